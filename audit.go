@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
+	"github.com/gorilla/mux"
+)
+
+// auditedEvent is whether the socket event is recorded in the audit log, every storyboard mutation is
+func auditedEvent(EventType string) bool {
+	return revisionedEvents[EventType] || EventType == "set_user_role"
+}
+
+// socketEventTarget gets the type and id of what a storyboard socket event changes,
+// the id is empty for events that add a new item
+func socketEventTarget(StoryboardID string, req *socketRequest) (string, string) {
+	var rs struct {
+		ID          string `json:"id"`
+		GoalID      string `json:"goalId"`
+		ColumnID    string `json:"columnId"`
+		CommentID   string `json:"commentId"`
+		ReleaseID   string `json:"releaseId"`
+		LabelID     string `json:"labelId"`
+		CriterionID string `json:"criterionId"`
+		LinkID      string `json:"linkId"`
+		RefID       string `json:"refId"`
+		UserID      string `json:"userId"`
+	}
+	json.Unmarshal([]byte(req.Value), &rs)
+
+	switch req.Type {
+	case "add_goal", "add_column", "add_story", "add_persona", "add_release", "add_label":
+		return strings.TrimPrefix(req.Type, "add_"), ""
+	case "delete_goal", "delete_column", "delete_persona", "delete_release", "delete_label":
+		return strings.TrimPrefix(req.Type, "delete_"), req.Value
+	case "revise_goal", "move_goal":
+		return "goal", rs.GoalID
+	case "revise_column":
+		return "column", rs.ID
+	case "move_column":
+		return "column", rs.ColumnID
+	case "update_persona":
+		return "persona", rs.ID
+	case "revise_release", "move_release":
+		return "release", rs.ReleaseID
+	case "revise_label":
+		return "label", rs.LabelID
+	case "update_story_criterion", "toggle_story_criterion", "move_story_criterion", "delete_story_criterion":
+		return "criterion", rs.CriterionID
+	case "remove_story_link":
+		return "link", rs.LinkID
+	case "remove_story_external_ref":
+		return "external_ref", rs.RefID
+	case "update_story_comment", "delete_story_comment":
+		return "comment", rs.CommentID
+	case "promote_owner":
+		return "user", req.Value
+	case "set_user_role":
+		return "user", rs.UserID
+	}
+
+	if StoryID := storyEventID(req); StoryID != "" {
+		return "story", StoryID
+	}
+
+	return "storyboard", StoryboardID
+}
+
+// goalTreeTarget finds a goal, column, story, criterion, link or external reference in the storyboards goals
+func goalTreeTarget(goals []*database.StoryboardGoal, TargetType string, TargetID string) interface{} {
+	switch TargetType {
+	case "goal":
+		if g := findGoal(goals, TargetID); g != nil {
+			return g
+		}
+	case "column":
+		if _, c := findColumn(goals, TargetID); c != nil {
+			return c
+		}
+	case "story":
+		if _, _, st := findStory(goals, TargetID); st != nil {
+			return st
+		}
+	case "criterion":
+		for _, g := range goals {
+			for _, c := range g.Columns {
+				for _, st := range c.Stories {
+					for _, cr := range st.Criteria {
+						if cr.CriterionID == TargetID {
+							return cr
+						}
+					}
+				}
+			}
+		}
+	case "link":
+		if l := findLink(goals, TargetID); l != nil {
+			return l
+		}
+	case "external_ref":
+		if ref := findExternalRef(goals, TargetID); ref != nil {
+			return ref
+		}
+	}
+
+	return nil
+}
+
+// auditTargetState gets the current state of a storyboard goal, column, story, comment, criterion, link,
+// external reference, persona, release, label or user
+func (s *server) auditTargetState(StoryboardID string, TargetType string, TargetID string) interface{} {
+	if TargetID == "" {
+		return nil
+	}
+
+	switch TargetType {
+	case "goal", "column", "story", "link", "external_ref":
+		return goalTreeTarget(s.database.GetStoryboardGoals(StoryboardID), TargetType, TargetID)
+	case "comment":
+		if c, err := s.database.GetStoryComment(StoryboardID, TargetID); err == nil {
+			return c
+		}
+	case "persona":
+		for _, p := range s.database.GetStoryboardPersonas(StoryboardID) {
+			if p.PersonaID == TargetID {
+				return p
+			}
+		}
+	case "release":
+		for _, r := range s.database.GetStoryboardReleases(StoryboardID) {
+			if r.ReleaseID == TargetID {
+				return r
+			}
+		}
+	case "criterion":
+		if c, err := s.database.GetStoryCriterion(StoryboardID, TargetID); err == nil {
+			return c
+		}
+	case "label":
+		for _, l := range s.database.GetStoryboardLabels(StoryboardID) {
+			if l.LabelID == TargetID {
+				return l
+			}
+		}
+	case "user":
+		for _, u := range s.database.GetStoryboardUsers(StoryboardID) {
+			if u.UserID == TargetID {
+				return u
+			}
+		}
+	}
+
+	return nil
+}
+
+// socketEventAudit creates the audit entry for a storyboard socket event before it is applied,
+// the after value is the requested change
+func (s *server) socketEventAudit(StoryboardID string, UserID string, req *socketRequest) *database.AuditEntry {
+	TargetType, TargetID := socketEventTarget(StoryboardID, req)
+
+	e := &database.AuditEntry{
+		ActorID:      UserID,
+		Action:       req.Type,
+		TargetType:   TargetType,
+		TargetID:     TargetID,
+		StoryboardID: StoryboardID,
+		Before:       s.auditTargetState(StoryboardID, TargetType, TargetID),
+	}
+	if !strings.HasPrefix(req.Type, "delete_") && req.Value != "" {
+		e.After = json.RawMessage(req.Value)
+	}
+
+	return e
+}
+
+// storyboardAudit creates the audit entry for a REST storyboard change before it is made,
+// the after value is set once the change is
+func (s *server) storyboardAudit(StoryboardID string, Action string, TargetType string, TargetID string) *database.AuditEntry {
+	return &database.AuditEntry{
+		Action:       Action,
+		TargetType:   TargetType,
+		TargetID:     TargetID,
+		StoryboardID: StoryboardID,
+		Before:       s.auditTargetState(StoryboardID, TargetType, TargetID),
+	}
+}
+
+// audit records a change made by the requesting user,
+// scoped to the organization, department and team in the route
+func (s *server) audit(r *http.Request, e *database.AuditEntry) {
+	vars := mux.Vars(r)
+	if UserID, ok := r.Context().Value(contextKeyUserID).(string); ok {
+		e.ActorID = UserID
+	}
+	if e.OrganizationID == "" {
+		e.OrganizationID = vars["orgId"]
+	}
+	if e.DepartmentID == "" {
+		e.DepartmentID = vars["departmentId"]
+	}
+	if e.TeamID == "" {
+		e.TeamID = vars["teamId"]
+	}
+
+	s.database.RecordAudit(e)
+}
+
+// auditFilter gets the audit log filter from the requests query
+func auditFilter(r *http.Request) *database.AuditFilter {
+	q := r.URL.Query()
+
+	return &database.AuditFilter{
+		ActorID:        q.Get("actorId"),
+		Action:         q.Get("action"),
+		TargetType:     q.Get("targetType"),
+		TargetID:       q.Get("targetId"),
+		StoryboardID:   q.Get("storyboardId"),
+		OrganizationID: q.Get("organizationId"),
+		TeamID:         q.Get("teamId"),
+		Since:          q.Get("since"),
+		Until:          q.Get("until"),
+	}
+}
+
+// handleGetAuditLog handles querying the whole audit log (ADMIN only)
+func (s *server) handleGetAuditLog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		Limit, _ := strconv.Atoi(vars["limit"])
+		Offset, _ := strconv.Atoi(vars["offset"])
+
+		Entries := s.database.GetAuditLog(auditFilter(r), Limit, Offset)
+
+		s.respondWithJSON(w, http.StatusOK, Entries)
+	}
+}
+
+// handleGetOrganizationAuditLog handles querying the audit log of an organization,
+// its departments and teams (organization ADMIN only)
+func (s *server) handleGetOrganizationAuditLog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		Limit, _ := strconv.Atoi(vars["limit"])
+		Offset, _ := strconv.Atoi(vars["offset"])
+
+		Filter := auditFilter(r)
+		Filter.OrganizationID = vars["orgId"]
+
+		Entries := s.database.GetAuditLog(Filter, Limit, Offset)
+
+		s.respondWithJSON(w, http.StatusOK, Entries)
+	}
+}
@@ -2,11 +2,13 @@ package main
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
 )
@@ -37,6 +39,9 @@ type connection struct {
 
 	// Buffered channel of outbound messages.
 	send chan []byte
+
+	// The socket protocol version negotiated at connect.
+	protocol int
 }
 
 // SocketEvent is the event structure used for socket messages
@@ -44,6 +49,11 @@ type SocketEvent struct {
 	EventType  string `json:"type"`
 	EventValue string `json:"value"`
 	EventUser  string `json:"userId"`
+	Revision   int    `json:"revision,omitempty"`
+	Seq        int64  `json:"seq,omitempty"`
+	Epoch      string `json:"epoch,omitempty"`
+	// Focus is the focus of each story in the storyboard, only sent with init
+	Focus map[string]storyFocus `json:"focus,omitempty"`
 }
 
 // CreateSocketEvent makes a SocketEvent struct and turns it into json []byte
@@ -62,17 +72,25 @@ func CreateSocketEvent(EventType string, EventValue string, EventUser string) []
 // readPump pumps messages from the websocket connection to the hub.
 func (s subscription) readPump(srv *server) {
 	var forceClosed bool
+	// the story this connection has focused (if any) and whether it requested the lock
+	var focusedStory string
+	var focusLocked bool
 	c := s.conn
 	defer func() {
 		StoryboardID := s.arena
 		UserID := s.userID
 
-		Users := srv.database.RetreatUser(StoryboardID, UserID)
+		// focus and any lock held expire with the connection
+		if focusedStory != "" {
+			focus := h.changeFocus(focusChange{arena: StoryboardID, storyID: focusedStory, userID: UserID, lock: focusLocked})
+			h.broadcast <- storyFocusMessage(StoryboardID, "story_blur", UserID, focus)
+		}
+
+		Users := srv.database.RetreatUser(StoryboardID, UserID, s.sessionID)
 		updatedUsers, _ := json.Marshal(Users)
 
 		retreatEvent := CreateSocketEvent("user_retreated", string(updatedUsers), UserID)
-		m := message{retreatEvent, StoryboardID}
-		h.broadcast <- m
+		h.broadcast <- message{data: retreatEvent, arena: StoryboardID}
 
 		h.unregister <- s
 		if forceClosed {
@@ -97,237 +115,351 @@ func (s subscription) readPump(srv *server) {
 			break
 		}
 
-		var badEvent bool
-		keyVal := make(map[string]string)
-		json.Unmarshal(msg, &keyVal) // check for errors
+		var eventErr error
+		var m message
+		var req socketRequest
 		userID := s.userID
 		storyboardID := s.arena
 
-		switch keyVal["type"] {
+		if err := json.Unmarshal(msg, &req); err != nil {
+			h.broadcast <- errorReplyMessage(storyboardID, c, &req, errInvalidEventValue)
+			continue
+		}
+
+		// story edits are rejected while another user holds the stories lock
+		if lockedStoryEvents[req.Type] {
+			if storiesLockedBy(srv.storyEventStories(storyboardID, userID, &req), userID) != "" {
+				h.broadcast <- errorReplyMessage(storyboardID, c, &req, errStoryLocked)
+				continue
+			}
+		}
+
+		// mutations based on a stale storyboard revision are rejected back to the sender only
+		var unlock func()
+		if revisionedEvents[req.Type] {
+			var err error
+			unlock, err = srv.lockStoryboard(storyboardID)
+			if err != nil {
+				h.broadcast <- errorReplyMessage(storyboardID, c, &req, err)
+				continue
+			}
+			current, err := srv.database.GetStoryboardRevision(storyboardID)
+			if err == nil && req.Revision != nil && *req.Revision != current {
+				unlock()
+				h.broadcast <- revisionConflictMessage(storyboardID, c, &req, current)
+				continue
+			}
+		}
+
+		// users mentioned by a comment are notified once it's broadcast
+		var mentions []*database.StoryMention
+		// other storyboards with stories linked to, unlinked from or unblocked by the event are sent their changes
+		// once it's broadcast
+		var linkedStoryboardIDs []string
+
+		// the audit entry captures the targets state before the event is applied
+		var audit *database.AuditEntry
+		if auditedEvent(req.Type) {
+			audit = srv.socketEventAudit(storyboardID, userID, &req)
+		}
+
+		switch req.Type {
 		case "add_goal":
-			goals, err := srv.database.CreateStoryboardGoal(storyboardID, userID, keyVal["value"])
+			goals, err := srv.database.CreateStoryboardGoal(storyboardID, userID, req.Value)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("goal_added", string(updatedGoals), "")
+			m = goalAddedMessage(storyboardID, goals)
 		case "revise_goal":
 			goalObj := make(map[string]string)
-			json.Unmarshal([]byte(keyVal["value"]), &goalObj)
+			if err := json.Unmarshal([]byte(req.Value), &goalObj); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 			GoalID := goalObj["goalId"]
 			GoalName := goalObj["name"]
 
 			goals, err := srv.database.ReviseGoalName(storyboardID, userID, GoalID, GoalName)
 			if err != nil {
-				badEvent = true
+				eventErr = err
+				break
+			}
+			m = goalRevisedMessage(storyboardID, goals, GoalID)
+		case "move_goal":
+			var rs struct {
+				GoalID      string `json:"goalId"`
+				PlaceBefore string `json:"placeBefore"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			goals, err := srv.database.MoveStoryboardGoal(storyboardID, userID, rs.GoalID, rs.PlaceBefore)
+			if err != nil {
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("goal_revised", string(updatedGoals), "")
+			m = goalMovedMessage(storyboardID, goals, rs.GoalID)
 		case "delete_goal":
-			goals, err := srv.database.DeleteStoryboardGoal(storyboardID, userID, keyVal["value"])
+			goals, err := srv.database.DeleteStoryboardGoal(storyboardID, userID, req.Value)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("goal_deleted", string(updatedGoals), "")
+			m = goalDeletedMessage(storyboardID, goals, req.Value)
 		case "add_column":
 			goalObj := make(map[string]string)
-			json.Unmarshal([]byte(keyVal["value"]), &goalObj)
+			if err := json.Unmarshal([]byte(req.Value), &goalObj); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 			GoalID := goalObj["goalId"]
 
 			goals, err := srv.database.CreateStoryboardColumn(storyboardID, GoalID, userID)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("column_added", string(updatedGoals), "")
+			m = columnAddedMessage(storyboardID, goals, GoalID)
 		case "revise_column":
 			var rs struct {
 				ColumnID string `json:"id"`
 				Name     string `json:"name"`
 			}
-			json.Unmarshal([]byte(keyVal["value"]), &rs)
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 
 			goals, err := srv.database.ReviseStoryboardColumn(storyboardID, userID, rs.ColumnID, rs.Name)
 			if err != nil {
-				badEvent = true
+				eventErr = err
+				break
+			}
+			m = columnUpdatedMessage(storyboardID, goals, rs.ColumnID)
+		case "move_column":
+			var rs struct {
+				ColumnID    string `json:"columnId"`
+				GoalID      string `json:"goalId"`
+				PlaceBefore string `json:"placeBefore"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			FromGoalID := srv.columnGoalID(storyboardID, rs.ColumnID)
+			goals, err := srv.database.MoveStoryboardColumn(storyboardID, userID, rs.ColumnID, rs.GoalID, rs.PlaceBefore)
+			if err != nil {
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("column_updated", string(updatedGoals), "")
+			m = columnMovedMessage(storyboardID, goals, rs.ColumnID, FromGoalID)
 		case "delete_column":
-			goals, err := srv.database.DeleteStoryboardColumn(storyboardID, userID, keyVal["value"])
+			goals, err := srv.database.DeleteStoryboardColumn(storyboardID, userID, req.Value)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_deleted", string(updatedGoals), "")
+			m = columnDeletedMessage(storyboardID, goals, req.Value)
 		case "add_story":
 			goalObj := make(map[string]string)
-			json.Unmarshal([]byte(keyVal["value"]), &goalObj)
+			if err := json.Unmarshal([]byte(req.Value), &goalObj); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 			GoalID := goalObj["goalId"]
 			ColumnID := goalObj["columnId"]
 
 			goals, err := srv.database.CreateStoryboardStory(storyboardID, GoalID, ColumnID, userID)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_added", string(updatedGoals), "")
+			m = storyAddedMessage(storyboardID, goals, ColumnID)
 		case "update_story_name":
 			goalObj := make(map[string]string)
-			json.Unmarshal([]byte(keyVal["value"]), &goalObj)
+			if err := json.Unmarshal([]byte(req.Value), &goalObj); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 			StoryID := goalObj["storyId"]
 			StoryName := goalObj["name"]
 
 			goals, err := srv.database.ReviseStoryName(storyboardID, userID, StoryID, StoryName)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_updated", string(updatedGoals), "")
+			m = storyUpdatedMessage(storyboardID, goals, StoryID)
 		case "update_story_content":
 			goalObj := make(map[string]string)
-			json.Unmarshal([]byte(keyVal["value"]), &goalObj)
+			if err := json.Unmarshal([]byte(req.Value), &goalObj); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 			StoryID := goalObj["storyId"]
 			StoryContent := goalObj["content"]
 
 			goals, err := srv.database.ReviseStoryContent(storyboardID, userID, StoryID, StoryContent)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_updated", string(updatedGoals), "")
+			m = storyUpdatedMessage(storyboardID, goals, StoryID)
 		case "update_story_color":
 			goalObj := make(map[string]string)
-			json.Unmarshal([]byte(keyVal["value"]), &goalObj)
+			if err := json.Unmarshal([]byte(req.Value), &goalObj); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 			StoryID := goalObj["storyId"]
 			StoryColor := goalObj["color"]
 
 			goals, err := srv.database.ReviseStoryColor(storyboardID, userID, StoryID, StoryColor)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_updated", string(updatedGoals), "")
+			m = storyUpdatedMessage(storyboardID, goals, StoryID)
 		case "update_story_points":
 			var rs struct {
 				StoryID string `json:"storyId"`
 				Points  int    `json:"points"`
 			}
-			json.Unmarshal([]byte(keyVal["value"]), &rs)
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 
 			goals, err := srv.database.ReviseStoryPoints(storyboardID, userID, rs.StoryID, rs.Points)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_updated", string(updatedGoals), "")
+			m = storyUpdatedMessage(storyboardID, goals, rs.StoryID)
 		case "update_story_closed":
 			var rs struct {
 				StoryID string `json:"storyId"`
 				Closed  bool   `json:"closed"`
 			}
-			json.Unmarshal([]byte(keyVal["value"]), &rs)
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 
 			goals, err := srv.database.ReviseStoryClosed(storyboardID, userID, rs.StoryID, rs.Closed)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_updated", string(updatedGoals), "")
+			m = storyClosedMessage(storyboardID, goals, rs.StoryID)
+			linkedStoryboardIDs = blockedStoryboards(goals, rs.StoryID)
 		case "move_story":
 			goalObj := make(map[string]string)
-			json.Unmarshal([]byte(keyVal["value"]), &goalObj)
+			if err := json.Unmarshal([]byte(req.Value), &goalObj); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 			StoryID := goalObj["storyId"]
 			GoalID := goalObj["goalId"]
 			ColumnID := goalObj["columnId"]
 			PlaceBefore := goalObj["placeBefore"]
 
+			From, _ := srv.database.GetStoryPosition(storyboardID, StoryID)
 			goals, err := srv.database.MoveStoryboardStory(storyboardID, userID, StoryID, GoalID, ColumnID, PlaceBefore)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_moved", string(updatedGoals), "")
+			m = storyMovedMessage(storyboardID, goals, StoryID, From)
 		case "delete_story":
-			goals, err := srv.database.DeleteStoryboardStory(storyboardID, userID, keyVal["value"])
+			goals, err := srv.database.DeleteStoryboardStory(storyboardID, userID, req.Value)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_deleted", string(updatedGoals), "")
+			m = storyDeletedMessage(storyboardID, goals, req.Value)
 		case "add_story_comment":
 			var rs struct {
-				StoryID string `json:"storyId"`
-				Comment string `json:"comment"`
-			}
-			json.Unmarshal([]byte(keyVal["value"]), &rs)
-
-			goals, err := srv.database.AddStoryComment(storyboardID, userID, rs.StoryID, rs.Comment)
-			if err != nil {
-				badEvent = true
-				break
-			}
-			updatedGoals, _ := json.Marshal(goals)
-			msg = CreateSocketEvent("story_updated", string(updatedGoals), "")
-		// case "update_story_comment":
-		// 	var rs struct {
-		// 		StoryID   string `json:"storyId"`
-		// 		CommendID string `json:"commentId"`
-		// 		Comment   string `json:"comment"`
-		// 	}
-		// 	json.Unmarshal([]byte(keyVal["value"]), &rs)
-
-		// 	goals, err := srv.database.UpdateStoryComment(storyboardID, userID, rs.CommendID, rs.Comment)
-		// 	if err != nil {
-		// 		badEvent = true
-		// 		break
-		// 	}
-		// 	updatedGoals, _ := json.Marshal(goals)
-		// 	msg = CreateSocketEvent("story_updated", string(updatedGoals), "")
-		// case "delete_story_comment":
-		// 	var rs struct {
-		// 		StoryID   string `json:"storyId"`
-		// 		CommendID string `json:"commentId"`
-		// 	}
-		// 	json.Unmarshal([]byte(keyVal["value"]), &rs)
-
-		// 	goals, err := srv.database.DeleteStoryComment(storyboardID, userID, rs.CommendID)
-		// 	if err != nil {
-		// 		badEvent = true
-		// 		break
-		// 	}
-		// 	updatedGoals, _ := json.Marshal(goals)
-		// 	msg = CreateSocketEvent("story_updated", string(updatedGoals), "")
+				StoryID  string `json:"storyId"`
+				ParentID string `json:"parentId"`
+				Comment  string `json:"comment"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			goals, Mentioned, err := srv.database.AddStoryComment(storyboardID, userID, rs.StoryID, rs.Comment, rs.ParentID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			mentions = Mentioned
+			m = storyUpdatedMessage(storyboardID, goals, rs.StoryID)
+		case "update_story_comment":
+			var rs struct {
+				CommentID string `json:"commentId"`
+				Comment   string `json:"comment"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			Comment, err := srv.database.GetStoryComment(storyboardID, rs.CommentID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			goals, Mentioned, err := srv.database.UpdateStoryComment(storyboardID, userID, rs.CommentID, rs.Comment)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			mentions = Mentioned
+			m = storyUpdatedMessage(storyboardID, goals, Comment.StoryID)
+		case "delete_story_comment":
+			var rs struct {
+				CommentID string `json:"commentId"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			Comment, err := srv.database.GetStoryComment(storyboardID, rs.CommentID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			goals, err := srv.database.DeleteStoryComment(storyboardID, userID, rs.CommentID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyUpdatedMessage(storyboardID, goals, Comment.StoryID)
 		case "add_persona":
 			var rs struct {
 				Name        string `json:"name"`
 				Role        string `json:"role"`
 				Description string `json:"description"`
 			}
-			json.Unmarshal([]byte(keyVal["value"]), &rs)
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 
 			personas, err := srv.database.AddPersona(storyboardID, userID, rs.Name, rs.Role, rs.Description)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
 			updatedPersonas, _ := json.Marshal(personas)
-			msg = CreateSocketEvent("personas_updated", string(updatedPersonas), "")
+			m = message{data: CreateSocketEvent("personas_updated", string(updatedPersonas), ""), arena: storyboardID}
 		case "update_persona":
 			var rs struct {
 				PersonaID   string `json:"id"`
@@ -335,67 +467,474 @@ func (s subscription) readPump(srv *server) {
 				Role        string `json:"role"`
 				Description string `json:"description"`
 			}
-			json.Unmarshal([]byte(keyVal["value"]), &rs)
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
 
 			personas, err := srv.database.UpdatePersona(storyboardID, userID, rs.PersonaID, rs.Name, rs.Role, rs.Description)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
 			updatedPersonas, _ := json.Marshal(personas)
-			msg = CreateSocketEvent("personas_updated", string(updatedPersonas), "")
+			m = message{data: CreateSocketEvent("personas_updated", string(updatedPersonas), ""), arena: storyboardID}
 		case "delete_persona":
-			personas, err := srv.database.DeletePersona(storyboardID, userID, keyVal["value"])
+			personas, err := srv.database.DeletePersona(storyboardID, userID, req.Value)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
 			updatedPersonas, _ := json.Marshal(personas)
-			msg = CreateSocketEvent("personas_updated", string(updatedPersonas), "")
+			m = message{data: CreateSocketEvent("personas_updated", string(updatedPersonas), ""), arena: storyboardID}
 		case "promote_owner":
-			storyboard, err := srv.database.SetStoryboardOwner(storyboardID, userID, keyVal["value"])
+			storyboard, err := srv.database.SetStoryboardOwner(storyboardID, userID, req.Value)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
 
 			updatedStoryboard, _ := json.Marshal(storyboard)
-			msg = CreateSocketEvent("storyboard_updated", string(updatedStoryboard), "")
+			m = message{data: CreateSocketEvent("storyboard_updated", string(updatedStoryboard), ""), arena: storyboardID}
+		case "add_release":
+			var rs struct {
+				Name       string `json:"name"`
+				TargetDate string `json:"targetDate"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			releases, err := srv.database.AddRelease(storyboardID, userID, rs.Name, rs.TargetDate)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = releasesMessage(storyboardID, releases)
+		case "revise_release":
+			var rs struct {
+				ReleaseID  string `json:"releaseId"`
+				Name       string `json:"name"`
+				TargetDate string `json:"targetDate"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			releases, err := srv.database.ReviseRelease(storyboardID, userID, rs.ReleaseID, rs.Name, rs.TargetDate)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = releasesMessage(storyboardID, releases)
+		case "move_release":
+			var rs struct {
+				ReleaseID   string `json:"releaseId"`
+				PlaceBefore string `json:"placeBefore"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			releases, err := srv.database.MoveRelease(storyboardID, userID, rs.ReleaseID, rs.PlaceBefore)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = releasesMessage(storyboardID, releases)
+		case "delete_release":
+			releases, err := srv.database.DeleteRelease(storyboardID, userID, req.Value)
+			if err != nil {
+				eventErr = err
+				break
+			}
+
+			// stories in the deleted release are unassigned, so the goal tree changes too
+			storyboard, err := srv.database.GetStoryboard(storyboardID)
+			if err != nil {
+				m = releasesMessage(storyboardID, releases)
+				break
+			}
+			updatedStoryboard, _ := json.Marshal(storyboard)
+			m = message{data: CreateSocketEvent("storyboard_updated", string(updatedStoryboard), ""), arena: storyboardID}
+		case "assign_story", "unassign_story":
+			var rs struct {
+				StoryID string `json:"storyId"`
+				UserID  string `json:"userId"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			assign := srv.database.AddStoryAssignee
+			if req.Type == "unassign_story" {
+				assign = srv.database.RemoveStoryAssignee
+			}
+			goals, err := assign(storyboardID, userID, rs.StoryID, rs.UserID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyUpdatedMessage(storyboardID, goals, rs.StoryID)
+		case "update_story_release":
+			var rs struct {
+				StoryID   string `json:"storyId"`
+				ReleaseID string `json:"releaseId"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			if _, err := srv.database.ReviseStoryRelease(storyboardID, userID, rs.StoryID, rs.ReleaseID); err != nil {
+				eventErr = err
+				break
+			}
+
+			storyboard, err := srv.database.GetStoryboard(storyboardID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyReleaseMessage(storyboardID, storyboard, rs.StoryID, rs.ReleaseID)
+		case "add_label":
+			var rs struct {
+				Name  string `json:"name"`
+				Color string `json:"color"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil || rs.Name == "" {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			labels, err := srv.database.AddLabel(storyboardID, userID, rs.Name, rs.Color)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = labelsMessage(storyboardID, labels)
+		case "revise_label", "delete_label":
+			var err error
+			if req.Type == "delete_label" {
+				_, err = srv.database.DeleteLabel(storyboardID, userID, req.Value)
+			} else {
+				var rs struct {
+					LabelID string `json:"labelId"`
+					Name    string `json:"name"`
+					Color   string `json:"color"`
+				}
+				if err := json.Unmarshal([]byte(req.Value), &rs); err != nil || rs.Name == "" {
+					eventErr = errInvalidEventValue
+					break
+				}
+				_, err = srv.database.ReviseLabel(storyboardID, userID, rs.LabelID, rs.Name, rs.Color)
+			}
+			if err != nil {
+				eventErr = err
+				break
+			}
+
+			// the labels on stories change too, so the whole storyboard is sent
+			storyboard, err := srv.database.GetStoryboard(storyboardID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyboardUpdatedMessage(storyboardID, storyboard)
+		case "add_story_label", "remove_story_label":
+			var rs struct {
+				StoryID string `json:"storyId"`
+				LabelID string `json:"labelId"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			label := srv.database.AddStoryLabel
+			if req.Type == "remove_story_label" {
+				label = srv.database.RemoveStoryLabel
+			}
+			goals, err := label(storyboardID, userID, rs.StoryID, rs.LabelID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyUpdatedMessage(storyboardID, goals, rs.StoryID)
+		case "add_story_criterion":
+			var rs struct {
+				StoryID string `json:"storyId"`
+				Text    string `json:"text"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil || rs.Text == "" {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			goals, err := srv.database.AddStoryCriterion(storyboardID, userID, rs.StoryID, rs.Text)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyUpdatedMessage(storyboardID, goals, rs.StoryID)
+		case "update_story_criterion", "toggle_story_criterion", "move_story_criterion", "delete_story_criterion":
+			var rs struct {
+				CriterionID string `json:"criterionId"`
+				Text        string `json:"text"`
+				Done        bool   `json:"done"`
+				PlaceBefore string `json:"placeBefore"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil || (req.Type == "update_story_criterion" && rs.Text == "") {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			Criterion, err := srv.database.GetStoryCriterion(storyboardID, rs.CriterionID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+
+			var goals []*database.StoryboardGoal
+			switch req.Type {
+			case "update_story_criterion":
+				goals, err = srv.database.ReviseStoryCriterion(storyboardID, userID, rs.CriterionID, rs.Text)
+			case "toggle_story_criterion":
+				goals, err = srv.database.ToggleStoryCriterion(storyboardID, userID, rs.CriterionID, rs.Done)
+			case "move_story_criterion":
+				goals, err = srv.database.MoveStoryCriterion(storyboardID, userID, rs.CriterionID, rs.PlaceBefore)
+			default:
+				goals, err = srv.database.DeleteStoryCriterion(storyboardID, userID, rs.CriterionID)
+			}
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyUpdatedMessage(storyboardID, goals, Criterion.StoryID)
+		case "add_story_link", "remove_story_link":
+			var rs struct {
+				StoryID       string `json:"storyId"`
+				TargetStoryID string `json:"targetStoryId"`
+				Type          string `json:"type"`
+				LinkID        string `json:"linkId"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil ||
+				(req.Type == "add_story_link" && !database.ValidStoryLinkType(rs.Type)) {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			var goals []*database.StoryboardGoal
+			var LinkedStoryboardID string
+			var err error
+			if req.Type == "add_story_link" {
+				goals, LinkedStoryboardID, err = srv.database.AddStoryLink(storyboardID, userID, rs.StoryID, rs.TargetStoryID, rs.Type)
+			} else {
+				goals, LinkedStoryboardID, err = srv.database.RemoveStoryLink(storyboardID, userID, rs.LinkID)
+			}
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyLinksMessage(storyboardID, goals)
+			linkedStoryboardIDs = []string{LinkedStoryboardID}
+		case "add_story_external_ref":
+			var rs struct {
+				StoryID string `json:"storyId"`
+				System  string `json:"system"`
+				Key     string `json:"key"`
+				URL     string `json:"url"`
+				Status  string `json:"status"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil || !database.ValidExternalRef(rs.System, rs.Key, rs.URL) {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			goals, err := srv.database.AddStoryExternalRef(storyboardID, userID, rs.StoryID, rs.System, rs.Key, rs.URL, rs.Status)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyUpdatedMessage(storyboardID, goals, rs.StoryID)
+		case "remove_story_external_ref":
+			var rs struct {
+				RefID string `json:"refId"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			goals, StoryID, err := srv.database.RemoveStoryExternalRef(storyboardID, userID, rs.RefID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyUpdatedMessage(storyboardID, goals, StoryID)
+		case "set_user_role":
+			var rs struct {
+				UserID string `json:"userId"`
+				Role   string `json:"role"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			users, err := srv.database.SetStoryboardUserRole(storyboardID, userID, rs.UserID, rs.Role)
+			if err != nil {
+				eventErr = err
+				break
+			}
+
+			updatedUsers, _ := json.Marshal(users)
+			m = message{data: CreateSocketEvent("users_updated", string(updatedUsers), ""), arena: storyboardID}
+		case "undo", "redo":
+			step := srv.database.UndoOperation
+			if req.Type == "redo" {
+				step = srv.database.RedoOperation
+			}
+			if _, err := step(storyboardID, userID); err != nil {
+				eventErr = err
+				break
+			}
+
+			// undoing or redoing can restore a whole goal and change release totals
+			storyboard, err := srv.database.GetStoryboard(storyboardID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyboardUpdatedMessage(storyboardID, storyboard)
+		case "story_focus":
+			var rs struct {
+				StoryID string `json:"storyId"`
+				Lock    bool   `json:"lock"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil || rs.StoryID == "" {
+				eventErr = errInvalidEventValue
+				break
+			}
+			if rs.Lock {
+				if err := srv.database.ConfirmEditor(storyboardID, userID); err != nil {
+					eventErr = errors.New("Incorrect permissions")
+					break
+				}
+			}
+
+			// a connection focuses one story at a time
+			if focusedStory != "" {
+				focus := h.changeFocus(focusChange{arena: storyboardID, storyID: focusedStory, userID: userID, lock: focusLocked})
+				h.broadcast <- storyFocusMessage(storyboardID, "story_blur", userID, focus)
+			}
+
+			focus := h.changeFocus(focusChange{arena: storyboardID, storyID: rs.StoryID, userID: userID, focused: true, lock: rs.Lock})
+			focusedStory = rs.StoryID
+			focusLocked = rs.Lock
+			m = storyFocusMessage(storyboardID, "story_focus", userID, focus)
+		case "story_blur":
+			if focusedStory == "" || focusedStory != req.Value {
+				eventErr = errors.New("invalid story, not focused")
+				break
+			}
+
+			focus := h.changeFocus(focusChange{arena: storyboardID, storyID: focusedStory, userID: userID, lock: focusLocked})
+			focusedStory = ""
+			focusLocked = false
+			m = storyFocusMessage(storyboardID, "story_blur", userID, focus)
 		case "revise_color_legend":
-			storyboard, err := srv.database.ReviseColorLegend(storyboardID, userID, keyVal["value"])
+			storyboard, err := srv.database.ReviseColorLegend(storyboardID, userID, req.Value)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
 
 			updatedStoryboard, _ := json.Marshal(storyboard)
-			msg = CreateSocketEvent("storyboard_updated", string(updatedStoryboard), "")
+			m = message{data: CreateSocketEvent("storyboard_updated", string(updatedStoryboard), ""), arena: storyboardID}
+		case "revise_criteria_rule":
+			var rs struct {
+				RequireDone bool `json:"requireDone"`
+			}
+			if err := json.Unmarshal([]byte(req.Value), &rs); err != nil {
+				eventErr = errInvalidEventValue
+				break
+			}
+
+			storyboard, err := srv.database.ReviseCriteriaRule(storyboardID, userID, rs.RequireDone)
+			if err != nil {
+				eventErr = err
+				break
+			}
+			m = storyboardUpdatedMessage(storyboardID, storyboard)
 		case "concede_storyboard":
 			err := srv.database.DeleteStoryboard(storyboardID, userID)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			msg = CreateSocketEvent("storyboard_conceded", "", "")
+			m = message{data: CreateSocketEvent("storyboard_conceded", "", ""), arena: storyboardID}
+		case "resync":
+			storyboard, err := srv.database.GetStoryboard(storyboardID)
+			if err != nil {
+				eventErr = err
+				break
+			}
+
+			fullStoryboard, _ := json.Marshal(storyboard)
+			m = message{data: CreateSocketEvent("storyboard_resync", string(fullStoryboard), ""), arena: storyboardID, conn: c}
 		case "abandon_storyboard":
-			_, err := srv.database.AbandonStoryboard(storyboardID, userID)
+			_, err := srv.database.AbandonStoryboard(storyboardID, userID, s.sessionID)
 			if err != nil {
-				badEvent = true
+				eventErr = err
 				break
 			}
-			badEvent = true // don't want this event to cause write panic
 			forceClosed = true
 		default:
+			// unknown events are refused rather than relayed, clients can't forge server events
+			eventErr = errInvalidEventValue
 		}
 
-		if !badEvent {
-			m := message{msg, s.arena}
-			h.broadcast <- m
+		var revision int
+		if unlock != nil && eventErr == nil {
+			if rev, err := srv.database.IncrementStoryboardRevision(storyboardID); err == nil {
+				revision = rev
+				m.setRevision(revision)
+			}
+		}
+		// the event is broadcast before the storyboard is unlocked, so a connection joining under the lock
+		// gets it either in its init or after
+		release := func() {
+			if unlock != nil {
+				unlock()
+			}
 		}
 
+		// abandon has no event to broadcast and its connection is about to close
 		if forceClosed {
+			release()
 			break
 		}
+
+		if eventErr != nil {
+			release()
+			h.broadcast <- errorReplyMessage(storyboardID, c, &req, eventErr)
+			continue
+		}
+
+		if audit != nil {
+			srv.database.RecordAudit(audit)
+		}
+
+		h.broadcast <- m
+		release()
+		h.broadcast <- ackReplyMessage(storyboardID, c, &req, revision)
+		notifyMentions(mentions)
+		srv.notifyLinkedStoryboards(storyboardID, linkedStoryboardIDs...)
 	}
 }
 
@@ -469,19 +1008,14 @@ func (s *server) serveWs() http.HandlerFunc {
 			}
 			return
 		}
-		storyboard, _ := json.Marshal(b)
 
 		// make sure user exists
 		_, userErr := s.database.GetStoryboardUser(storyboardID, userID)
 
 		if userErr != nil {
 			log.Println("error finding user : " + userErr.Error() + "\n")
-			cm := websocket.FormatCloseMessage(4003, "duplicate session")
-
-			if fmt.Sprint(userErr) == "User Not found" {
-				s.clearUserCookies(w)
-				cm = websocket.FormatCloseMessage(4001, "unauthorized")
-			}
+			s.clearUserCookies(w)
+			cm := websocket.FormatCloseMessage(4001, "unauthorized")
 
 			if err := ws.WriteMessage(websocket.CloseMessage, cm); err != nil {
 				log.Printf("unauthorized close error: %v", err)
@@ -492,19 +1026,63 @@ func (s *server) serveWs() http.HandlerFunc {
 			return
 		}
 
-		c := &connection{send: make(chan []byte, 256), ws: ws}
-		ss := subscription{c, storyboardID, userID}
-		h.register <- ss
+		// clients opt in to delta events with ?protocol=2, anything else gets the full goal tree
+		protocol := protocolFull
+		if v, err := strconv.Atoi(r.URL.Query().Get("protocol")); err == nil && v == protocolDelta {
+			protocol = protocolDelta
+		}
 
-		Users, _ := s.database.AddUserToStoryboard(ss.arena, userID)
+		c := &connection{send: make(chan []byte, 256), ws: ws, protocol: protocol}
+		ss := subscription{conn: c, arena: storyboardID, userID: userID, registered: make(chan registration, 1)}
+
+		// reconnecting clients send the last event they saw to replay only what they missed
+		if lastSeq, err := strconv.ParseInt(r.URL.Query().Get("lastSeq"), 10, 64); err == nil && lastSeq > 0 {
+			ss.resume = &resume{epoch: r.URL.Query().Get("epoch"), lastSeq: lastSeq}
+		}
+
+		// each connection gets its own session so the user stays active until their last one closes
+		Users, sessionID, sessionErr := s.database.AddUserToStoryboard(ss.arena, userID, clusterNodeID)
+		if sessionErr != nil {
+			cm := websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "unable to join storyboard")
+			if err := ws.WriteMessage(websocket.CloseMessage, cm); err != nil {
+				log.Printf("session close error: %v", err)
+			}
+			if err := ws.Close(); err != nil {
+				log.Printf("close error: %v", err)
+			}
+			return
+		}
+		ss.sessionID = sessionID
 		updatedUsers, _ := json.Marshal(Users)
 
-		initEvent := CreateSocketEvent("init", string(storyboard), userID)
-		_ = c.write(websocket.TextMessage, initEvent)
+		// registering and loading the storyboard under its lock keeps this instances mutations from being both
+		// in the init and queued after it, they're broadcast before the lock is released. Events relayed from
+		// other instances can still arrive after the init includes them, clients drop those by revision
+		unlock, lockErr := s.lockStoryboard(storyboardID)
+		h.register <- ss
+		reg := <-ss.registered
+
+		// the gap was replayed, otherwise fall back to a full init
+		if reg.replayed {
+			if lockErr == nil {
+				unlock()
+			}
+			resumedEvent, _ := json.Marshal(&SocketEvent{EventType: "resumed", EventUser: userID, Seq: reg.seq, Epoch: clusterNodeID})
+			_ = c.write(websocket.TextMessage, resumedEvent)
+		} else {
+			if current, err := s.database.GetStoryboard(storyboardID); err == nil {
+				b = current
+			}
+			if lockErr == nil {
+				unlock()
+			}
+			storyboard, _ := json.Marshal(b)
+			initEvent, _ := json.Marshal(&SocketEvent{EventType: "init", EventValue: string(storyboard), EventUser: userID, Revision: b.Revision, Seq: reg.seq, Epoch: clusterNodeID, Focus: h.focusMap(ss.arena)})
+			_ = c.write(websocket.TextMessage, initEvent)
+		}
 
 		joinedEvent := CreateSocketEvent("user_joined", string(updatedUsers), userID)
-		m := message{joinedEvent, ss.arena}
-		h.broadcast <- m
+		h.broadcast <- message{data: joinedEvent, arena: ss.arena}
 
 		go ss.writePump()
 		go ss.readPump(s)
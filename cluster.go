@@ -0,0 +1,154 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
+)
+
+// presenceInterval is how often this instance refreshes its cluster presence counts
+const presenceInterval = 15 * time.Second
+
+// clusterNodeID identifies this instance so it ignores the events it published itself
+var clusterNodeID = newNodeID()
+
+// clustered is set when socket events are fanned out to other instances
+var clustered bool
+
+// clusterEvent is a hub message published to other instances
+type clusterEvent struct {
+	Node  string          `json:"node"`
+	Arena string          `json:"arena"`
+	Data  json.RawMessage `json:"data"`
+	Delta json.RawMessage `json:"delta,omitempty"`
+}
+
+// newNodeID makes a random instance identifier
+func newNodeID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+// startCluster publishes the hubs broadcasts through postgres NOTIFY, relays events
+// published by other instances to local connections and keeps presence counts current
+func startCluster(d *database.Database) error {
+	h.publish = make(chan message, 256)
+
+	if err := d.ListenEvents(func(payload []byte) {
+		var e clusterEvent
+		if err := json.Unmarshal(payload, &e); err != nil {
+			log.Println(err)
+			return
+		}
+		if e.Node == clusterNodeID {
+			return
+		}
+
+		// keep focus and soft locks from other instances so edits are checked against them too
+		if fc, ok := remoteFocusChange(e.Node, e.Arena, e.Data); ok {
+			h.focusChanges <- fc
+		}
+
+		h.broadcast <- message{data: e.Data, delta: e.Delta, arena: e.Arena, remote: true}
+	}, func() {
+		resyncArenas(d)
+	}); err != nil {
+		return err
+	}
+
+	go func() {
+		for m := range h.publish {
+			payload, _ := json.Marshal(&clusterEvent{
+				Node:  clusterNodeID,
+				Arena: m.arena,
+				Data:  m.data,
+				Delta: m.delta,
+			})
+			d.PublishEvent(payload)
+		}
+	}()
+
+	// marked alive before serving any connection, so other instances don't expire its sessions
+	if err := d.SaveClusterPresence(clusterNodeID, map[string]int{}); err != nil {
+		return err
+	}
+
+	go func() {
+		for {
+			time.Sleep(presenceInterval)
+			d.SaveClusterPresence(clusterNodeID, h.connectionCounts())
+			expireNodeSessions(d)
+		}
+	}()
+
+	clustered = true
+
+	return nil
+}
+
+// expireNodeSessions ends the storyboard sessions of instances that stopped without ending them,
+// sending the storyboards their updated users, and releases the story focus and locks they held
+func expireNodeSessions(d *database.Database) {
+	StoryboardIDs, err := d.ExpireUserSessions("")
+	if err != nil {
+		return
+	}
+
+	for _, StoryboardID := range StoryboardIDs {
+		updatedUsers, _ := json.Marshal(d.GetStoryboardUsers(StoryboardID))
+		h.broadcast <- message{data: CreateSocketEvent("users_updated", string(updatedUsers), ""), arena: StoryboardID}
+	}
+
+	NodeIDs, err := d.GetClusterNodes()
+	if err != nil {
+		return
+	}
+	live := make(map[string]bool, len(NodeIDs))
+	for _, NodeID := range NodeIDs {
+		live[NodeID] = true
+	}
+
+	fe := focusExpiry{live: live, reply: make(chan []message, 1)}
+	h.focusExpiries <- fe
+	for _, m := range <-fe.reply {
+		h.broadcast <- m
+	}
+}
+
+// resyncArenas sends every local arena its whole storyboard, the events other instances published
+// while this one wasn't listening are lost and can't be replayed
+func resyncArenas(d *database.Database) {
+	for StoryboardID := range h.connectionCounts() {
+		storyboard, err := d.GetStoryboard(StoryboardID)
+		if err != nil {
+			continue
+		}
+
+		fullStoryboard, _ := json.Marshal(storyboard)
+		h.broadcast <- message{data: CreateSocketEvent("storyboard_resync", string(fullStoryboard), ""), arena: StoryboardID, remote: true}
+	}
+}
+
+// activePresence gets the active storyboard and storyboard user counts,
+// cluster wide when clustered otherwise for this instance
+func activePresence(d *database.Database) (int, int) {
+	if clustered {
+		if storyboards, users, err := d.GetClusterPresence(); err == nil {
+			return storyboards, users
+		}
+	}
+
+	var users int
+	counts := h.connectionCounts()
+	for _, c := range counts {
+		users = users + c
+	}
+
+	return len(counts), users
+}
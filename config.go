@@ -33,6 +33,13 @@ func InitConfig() {
 	viper.SetDefault("db.name", "exothermic")
 	viper.SetDefault("db.sslmode", "disable")
 
+	viper.SetDefault("cluster.enabled", false)
+
+	viper.SetDefault("snapshot.interval_hours", 24)
+	viper.SetDefault("snapshot.retention", 30)
+
+	viper.SetDefault("trash.retention_days", 30)
+
 	viper.SetDefault("smtp.host", "localhost")
 	viper.SetDefault("smtp.port", "25")
 	viper.SetDefault("smtp.secure", true)
@@ -77,6 +84,13 @@ func InitConfig() {
 	viper.BindEnv("db.name", "DB_NAME")
 	viper.BindEnv("db.sslmode", "DB_SSLMODE")
 
+	viper.BindEnv("cluster.enabled", "CLUSTER_ENABLED")
+
+	viper.BindEnv("snapshot.interval_hours", "SNAPSHOT_INTERVAL_HOURS")
+	viper.BindEnv("snapshot.retention", "SNAPSHOT_RETENTION")
+
+	viper.BindEnv("trash.retention_days", "TRASH_RETENTION_DAYS")
+
 	viper.BindEnv("smtp.host", "SMTP_HOST")
 	viper.BindEnv("smtp.port", "SMTP_PORT")
 	viper.BindEnv("smtp.secure", "SMTP_SECURE")
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
+)
+
+const (
+	// protocolFull is the original socket protocol, every board change
+	// broadcasts the entire goal tree
+	protocolFull = 1
+	// protocolDelta broadcasts only the changed goal, column or story,
+	// clients can request a full resync at any time
+	protocolDelta = 2
+)
+
+// goalDelta is the protocol v2 payload for goal changes
+type goalDelta struct {
+	GoalID    string `json:"id"`
+	GoalName  string `json:"name,omitempty"`
+	SortOrder int    `json:"sort_order,omitempty"`
+}
+
+// deletedDelta is the protocol v2 payload for a removed goal, column or story
+type deletedDelta struct {
+	ID string `json:"id"`
+}
+
+// columnDelta is the protocol v2 payload for column changes
+type columnDelta struct {
+	GoalID string                     `json:"goalId"`
+	Column *database.StoryboardColumn `json:"column"`
+}
+
+// storyDelta is the protocol v2 payload for story changes
+type storyDelta struct {
+	GoalID   string                    `json:"goalId"`
+	ColumnID string                    `json:"columnId"`
+	Story    *database.StoryboardStory `json:"story"`
+}
+
+// storyMoveDelta is the protocol v2 payload for a story changing position
+type storyMoveDelta struct {
+	StoryID string                  `json:"storyId"`
+	From    *database.StoryPosition `json:"from"`
+	To      *database.StoryPosition `json:"to"`
+}
+
+// goalMoveDelta is the protocol v2 payload for a goal changing position,
+// with the storyboards goals in their new order
+type goalMoveDelta struct {
+	GoalID  string   `json:"goalId"`
+	GoalIDs []string `json:"goalIds"`
+}
+
+// columnMoveDelta is the protocol v2 payload for a column changing position, its stories move with it,
+// with the destination goals columns in their new order
+type columnMoveDelta struct {
+	ColumnID   string   `json:"columnId"`
+	FromGoalID string   `json:"fromGoalId"`
+	GoalID     string   `json:"goalId"`
+	ColumnIDs  []string `json:"columnIds"`
+}
+
+// storyReleaseDelta is the protocol v2 payload for a story changing release,
+// carrying the releases with their recalculated point totals
+type storyReleaseDelta struct {
+	StoryID   string                        `json:"storyId"`
+	ReleaseID string                        `json:"releaseId"`
+	Releases  []*database.StoryboardRelease `json:"releases"`
+}
+
+// createDeltaEvent makes a protocol v2 SocketEvent with the payload marshalled as its value
+func createDeltaEvent(EventType string, payload interface{}) []byte {
+	value, _ := json.Marshal(payload)
+
+	return CreateSocketEvent(EventType, string(value), "")
+}
+
+// goalsMessage creates a message carrying the full goal tree, used for both protocols
+func goalsMessage(EventType string, StoryboardID string, goals []*database.StoryboardGoal) message {
+	updatedGoals, _ := json.Marshal(goals)
+
+	return message{
+		data:  CreateSocketEvent(EventType, string(updatedGoals), ""),
+		arena: StoryboardID,
+	}
+}
+
+// goalAddedMessage creates the goal_added message, the newest goal is the last by sort order
+func goalAddedMessage(StoryboardID string, goals []*database.StoryboardGoal) message {
+	m := goalsMessage("goal_added", StoryboardID, goals)
+	if len(goals) > 0 {
+		g := goals[len(goals)-1]
+		m.delta = createDeltaEvent("goal_added", g)
+	}
+
+	return m
+}
+
+// goalRevisedMessage creates the goal_revised message
+func goalRevisedMessage(StoryboardID string, goals []*database.StoryboardGoal, GoalID string) message {
+	m := goalsMessage("goal_revised", StoryboardID, goals)
+	if g := findGoal(goals, GoalID); g != nil {
+		m.delta = createDeltaEvent("goal_revised", &goalDelta{
+			GoalID:    g.GoalID,
+			GoalName:  g.GoalName,
+			SortOrder: g.SortOrder,
+		})
+	}
+
+	return m
+}
+
+// goalDeletedMessage creates the goal_deleted message
+func goalDeletedMessage(StoryboardID string, goals []*database.StoryboardGoal, GoalID string) message {
+	m := goalsMessage("goal_deleted", StoryboardID, goals)
+	m.delta = createDeltaEvent("goal_deleted", &deletedDelta{ID: GoalID})
+
+	return m
+}
+
+// columnAddedMessage creates the column_added message, the newest column is the last in its goal
+func columnAddedMessage(StoryboardID string, goals []*database.StoryboardGoal, GoalID string) message {
+	m := goalsMessage("column_added", StoryboardID, goals)
+	if g := findGoal(goals, GoalID); g != nil && len(g.Columns) > 0 {
+		m.delta = createDeltaEvent("column_added", &columnDelta{
+			GoalID: GoalID,
+			Column: g.Columns[len(g.Columns)-1],
+		})
+	}
+
+	return m
+}
+
+// columnUpdatedMessage creates the column_updated message
+func columnUpdatedMessage(StoryboardID string, goals []*database.StoryboardGoal, ColumnID string) message {
+	m := goalsMessage("column_updated", StoryboardID, goals)
+	if g, c := findColumn(goals, ColumnID); c != nil {
+		m.delta = createDeltaEvent("column_updated", &columnDelta{
+			GoalID: g.GoalID,
+			Column: &database.StoryboardColumn{
+				ColumnID:   c.ColumnID,
+				ColumnName: c.ColumnName,
+				SortOrder:  c.SortOrder,
+			},
+		})
+	}
+
+	return m
+}
+
+// goalMovedMessage creates the goal_moved message with the new goal order as its delta
+func goalMovedMessage(StoryboardID string, goals []*database.StoryboardGoal, GoalID string) message {
+	m := goalsMessage("goal_moved", StoryboardID, goals)
+	GoalIDs := make([]string, 0, len(goals))
+	for _, g := range goals {
+		GoalIDs = append(GoalIDs, g.GoalID)
+	}
+	m.delta = createDeltaEvent("goal_moved", &goalMoveDelta{GoalID: GoalID, GoalIDs: GoalIDs})
+
+	return m
+}
+
+// columnMovedMessage creates the column_moved message with the columns old goal and its new goals column order as its delta
+func columnMovedMessage(StoryboardID string, goals []*database.StoryboardGoal, ColumnID string, FromGoalID string) message {
+	m := goalsMessage("column_moved", StoryboardID, goals)
+	if g, c := findColumn(goals, ColumnID); c != nil {
+		ColumnIDs := make([]string, 0, len(g.Columns))
+		for _, gc := range g.Columns {
+			ColumnIDs = append(ColumnIDs, gc.ColumnID)
+		}
+		m.delta = createDeltaEvent("column_moved", &columnMoveDelta{
+			ColumnID:   ColumnID,
+			FromGoalID: FromGoalID,
+			GoalID:     g.GoalID,
+			ColumnIDs:  ColumnIDs,
+		})
+	}
+
+	return m
+}
+
+// columnDeletedMessage creates the column deleted message, protocol v1 has always called this story_deleted
+func columnDeletedMessage(StoryboardID string, goals []*database.StoryboardGoal, ColumnID string) message {
+	m := goalsMessage("story_deleted", StoryboardID, goals)
+	m.delta = createDeltaEvent("column_deleted", &deletedDelta{ID: ColumnID})
+
+	return m
+}
+
+// storyAddedMessage creates the story_added message, the newest story is the last in its column
+func storyAddedMessage(StoryboardID string, goals []*database.StoryboardGoal, ColumnID string) message {
+	m := goalsMessage("story_added", StoryboardID, goals)
+	if g, c := findColumn(goals, ColumnID); c != nil && len(c.Stories) > 0 {
+		m.delta = createDeltaEvent("story_added", &storyDelta{
+			GoalID:   g.GoalID,
+			ColumnID: c.ColumnID,
+			Story:    c.Stories[len(c.Stories)-1],
+		})
+	}
+
+	return m
+}
+
+// storyUpdatedMessage creates the story_updated message with only the changed story as its delta
+func storyUpdatedMessage(StoryboardID string, goals []*database.StoryboardGoal, StoryID string) message {
+	m := goalsMessage("story_updated", StoryboardID, goals)
+	if g, c, st := findStory(goals, StoryID); st != nil {
+		m.delta = createDeltaEvent("story_updated", &storyDelta{
+			GoalID:   g.GoalID,
+			ColumnID: c.ColumnID,
+			Story:    st,
+		})
+	}
+
+	return m
+}
+
+// storyMovedMessage creates the story_moved message with the stories old and new position as its delta
+func storyMovedMessage(StoryboardID string, goals []*database.StoryboardGoal, StoryID string, From *database.StoryPosition) message {
+	m := goalsMessage("story_moved", StoryboardID, goals)
+	if g, c, st := findStory(goals, StoryID); st != nil {
+		m.delta = createDeltaEvent("story_moved", &storyMoveDelta{
+			StoryID: StoryID,
+			From:    From,
+			To: &database.StoryPosition{
+				GoalID:    g.GoalID,
+				ColumnID:  c.ColumnID,
+				SortOrder: st.SortOrder,
+			},
+		})
+	}
+
+	return m
+}
+
+// storyDeletedMessage creates the story_deleted message
+func storyDeletedMessage(StoryboardID string, goals []*database.StoryboardGoal, StoryID string) message {
+	m := goalsMessage("story_deleted", StoryboardID, goals)
+	m.delta = createDeltaEvent("story_deleted", &deletedDelta{ID: StoryID})
+
+	return m
+}
+
+// storyLinksMessage creates the story_links_updated message carrying the full goal tree for both protocols,
+// a link changes both linked stories and whether stories they block are blocked
+func storyLinksMessage(StoryboardID string, goals []*database.StoryboardGoal) message {
+	return goalsMessage("story_links_updated", StoryboardID, goals)
+}
+
+// releasesMessage creates the releases_updated message, releases are small enough to always send in full
+func releasesMessage(StoryboardID string, releases []*database.StoryboardRelease) message {
+	updatedReleases, _ := json.Marshal(releases)
+
+	return message{
+		data:  CreateSocketEvent("releases_updated", string(updatedReleases), ""),
+		arena: StoryboardID,
+	}
+}
+
+// labelsMessage creates the labels_updated message for a new label, the labels are always sent in full
+func labelsMessage(StoryboardID string, labels []*database.StoryboardLabel) message {
+	updatedLabels, _ := json.Marshal(labels)
+
+	return message{
+		data:  CreateSocketEvent("labels_updated", string(updatedLabels), ""),
+		arena: StoryboardID,
+	}
+}
+
+// storyReleaseMessage creates the message for a story moving between releases,
+// protocol v1 receives the full storyboard as both the goals and release totals change
+func storyReleaseMessage(StoryboardID string, storyboard *database.Storyboard, StoryID string, ReleaseID string) message {
+	updatedStoryboard, _ := json.Marshal(storyboard)
+
+	return message{
+		data: CreateSocketEvent("storyboard_updated", string(updatedStoryboard), ""),
+		delta: createDeltaEvent("story_release_updated", &storyReleaseDelta{
+			StoryID:   StoryID,
+			ReleaseID: ReleaseID,
+			Releases:  storyboard.Releases,
+		}),
+		arena: StoryboardID,
+	}
+}
+
+// storyboardUpdatedMessage creates the storyboard_updated message carrying the full storyboard
+// for both protocols, used when a change can touch any part of the board
+func storyboardUpdatedMessage(StoryboardID string, storyboard *database.Storyboard) message {
+	updatedStoryboard, _ := json.Marshal(storyboard)
+
+	return message{
+		data:  CreateSocketEvent("storyboard_updated", string(updatedStoryboard), ""),
+		arena: StoryboardID,
+	}
+}
+
+// findGoal finds a goal in the goal tree by ID
+func findGoal(goals []*database.StoryboardGoal, GoalID string) *database.StoryboardGoal {
+	for _, g := range goals {
+		if g.GoalID == GoalID {
+			return g
+		}
+	}
+
+	return nil
+}
+
+// findColumn finds a column (and its goal) in the goal tree by ID
+func findColumn(goals []*database.StoryboardGoal, ColumnID string) (*database.StoryboardGoal, *database.StoryboardColumn) {
+	for _, g := range goals {
+		for _, c := range g.Columns {
+			if c.ColumnID == ColumnID {
+				return g, c
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// findStory finds a story (and its goal and column) in the goal tree by ID
+func findStory(goals []*database.StoryboardGoal, StoryID string) (*database.StoryboardGoal, *database.StoryboardColumn, *database.StoryboardStory) {
+	for _, g := range goals {
+		for _, c := range g.Columns {
+			for _, st := range c.Stories {
+				if st.StoryID == StoryID {
+					return g, c, st
+				}
+			}
+		}
+	}
+
+	return nil, nil, nil
+}
+
+// findLink finds a link to or from one of the stories in the goal tree by ID
+func findLink(goals []*database.StoryboardGoal, LinkID string) *database.StoryLink {
+	for _, g := range goals {
+		for _, c := range g.Columns {
+			for _, st := range c.Stories {
+				for _, l := range st.Links {
+					if l.LinkID == LinkID {
+						return l
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// findExternalRef finds an external reference on one of the stories in the goal tree by ID
+func findExternalRef(goals []*database.StoryboardGoal, RefID string) *database.StoryExternalRef {
+	for _, g := range goals {
+		for _, c := range g.Columns {
+			for _, st := range c.Stories {
+				for _, ref := range st.ExternalRefs {
+					if ref.RefID == RefID {
+						return ref
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
+)
+
+// errStoryLocked is returned for edits to a story another user holds the lock on
+var errStoryLocked = errors.New("Story locked by another user")
+
+// lockedStoryEvents are the socket events that edit a stories content,
+// rejected while another user holds the stories lock
+var lockedStoryEvents = map[string]bool{
+	"update_story_name":         true,
+	"update_story_content":      true,
+	"update_story_color":        true,
+	"update_story_points":       true,
+	"update_story_closed":       true,
+	"move_story":                true,
+	"delete_story":              true,
+	"update_story_release":      true,
+	"assign_story":              true,
+	"unassign_story":            true,
+	"add_story_label":           true,
+	"remove_story_label":        true,
+	"add_story_criterion":       true,
+	"update_story_criterion":    true,
+	"toggle_story_criterion":    true,
+	"move_story_criterion":      true,
+	"delete_story_criterion":    true,
+	"add_story_link":            true,
+	"remove_story_link":         true,
+	"add_story_external_ref":    true,
+	"remove_story_external_ref": true,
+	"undo":                      true,
+	"redo":                      true,
+}
+
+// storyFocus is who has a story open and who (if anyone) holds its soft lock
+type storyFocus struct {
+	StoryID string `json:"storyId"`
+	// UserID is the user that focused or blurred the story, only set in events
+	UserID   string   `json:"userId,omitempty"`
+	Users    []string `json:"users"`
+	LockedBy string   `json:"lockedBy,omitempty"`
+}
+
+// arenaStoryFocus is the hubs focus state for a story
+type arenaStoryFocus struct {
+	// users is the number of connections each user has focused on the story
+	users map[string]int
+	// nodes is the part of users relayed from each other instance, cleared when the instance stops
+	nodes    map[string]map[string]int
+	lockedBy string
+	// lockedNode is the other instance the lock was taken on, empty when taken on this one
+	lockedNode string
+}
+
+// focusChange is a connection focusing or blurring a story
+type focusChange struct {
+	arena   string
+	storyID string
+	userID  string
+	focused bool
+	// lock requests the lock when focusing, or releases the users lock when blurring
+	lock bool
+	// node is the instance a change relayed from another instance was made on, empty for this one
+	node string
+	// reply receives the stories focus after the change, nil for changes from other instances
+	reply chan storyFocus
+}
+
+// focusQuery requests an arenas current focus map
+type focusQuery struct {
+	arena string
+	reply chan map[string]storyFocus
+}
+
+// focusExpiry clears the focus and locks relayed from instances that are no longer alive
+type focusExpiry struct {
+	live map[string]bool
+	// reply receives the story_blur messages for the stories whose focus changed
+	reply chan []message
+}
+
+// changeFocus applies a focus change through the hub, returning the stories focus after it
+func (h *hub) changeFocus(fc focusChange) storyFocus {
+	fc.reply = make(chan storyFocus, 1)
+	h.focusChanges <- fc
+
+	return <-fc.reply
+}
+
+// focusMap gets the focus of each story in the arena
+func (h *hub) focusMap(arena string) map[string]storyFocus {
+	fq := focusQuery{arena: arena, reply: make(chan map[string]storyFocus, 1)}
+	h.focusQueries <- fq
+
+	return <-fq.reply
+}
+
+// storyLockedBy gets the user holding the stories lock, empty when unlocked
+func (h *hub) storyLockedBy(arena string, StoryID string) string {
+	if StoryID == "" {
+		return ""
+	}
+
+	return h.focusMap(arena)[StoryID].LockedBy
+}
+
+// applyFocus updates the arenas focus state, called from the hubs run loop
+func (h *hub) applyFocus(fc focusChange) storyFocus {
+	stories := h.focus[fc.arena]
+	if stories == nil {
+		stories = make(map[string]*arenaStoryFocus)
+		h.focus[fc.arena] = stories
+	}
+	f := stories[fc.storyID]
+	if f == nil {
+		f = &arenaStoryFocus{users: make(map[string]int)}
+		stories[fc.storyID] = f
+	}
+
+	if fc.focused {
+		f.users[fc.userID]++
+		if fc.node != "" {
+			if f.nodes == nil {
+				f.nodes = make(map[string]map[string]int)
+			}
+			if f.nodes[fc.node] == nil {
+				f.nodes[fc.node] = make(map[string]int)
+			}
+			f.nodes[fc.node][fc.userID]++
+		}
+		if fc.lock && f.lockedBy == "" {
+			f.lockedBy = fc.userID
+			f.lockedNode = fc.node
+		}
+	} else {
+		if f.users[fc.userID] > 1 {
+			f.users[fc.userID]--
+		} else {
+			delete(f.users, fc.userID)
+		}
+		if users := f.nodes[fc.node]; users != nil {
+			if users[fc.userID] > 1 {
+				users[fc.userID]--
+			} else {
+				delete(users, fc.userID)
+			}
+			if len(users) == 0 {
+				delete(f.nodes, fc.node)
+			}
+		}
+		// the lock expires with the users last focus on the story
+		if f.lockedBy == fc.userID && (fc.lock || f.users[fc.userID] == 0) {
+			f.lockedBy = ""
+			f.lockedNode = ""
+		}
+	}
+
+	state := f.state(fc.storyID)
+
+	if len(f.users) == 0 {
+		delete(stories, fc.storyID)
+		if len(stories) == 0 {
+			delete(h.focus, fc.arena)
+		}
+	}
+
+	return state
+}
+
+// expireFocus drops the focus and locks relayed from instances not in live, called from the hubs run loop.
+// A stopped instance never sends the blurs for its connections, so its locks would otherwise stay forever
+func (h *hub) expireFocus(live map[string]bool) []message {
+	var blurs []message
+	for arena, stories := range h.focus {
+		for StoryID, f := range stories {
+			changed := false
+			for node, users := range f.nodes {
+				if live[node] {
+					continue
+				}
+				for UserID, count := range users {
+					if f.users[UserID] > count {
+						f.users[UserID] -= count
+					} else {
+						delete(f.users, UserID)
+					}
+				}
+				delete(f.nodes, node)
+				if f.lockedNode == node {
+					f.lockedBy = ""
+					f.lockedNode = ""
+				}
+				changed = true
+			}
+			if !changed {
+				continue
+			}
+
+			// only delivered locally, every instance expires the stopped instances focus itself
+			m := storyFocusMessage(arena, "story_blur", "", f.state(StoryID))
+			m.remote = true
+			blurs = append(blurs, m)
+
+			if len(f.users) == 0 {
+				delete(stories, StoryID)
+			}
+		}
+		if len(stories) == 0 {
+			delete(h.focus, arena)
+		}
+	}
+
+	return blurs
+}
+
+// arenaFocus gets the focus of each story in the arena, called from the hubs run loop
+func (h *hub) arenaFocus(arena string) map[string]storyFocus {
+	focus := make(map[string]storyFocus, len(h.focus[arena]))
+	for StoryID, f := range h.focus[arena] {
+		focus[StoryID] = f.state(StoryID)
+	}
+
+	return focus
+}
+
+// state gets the stories focus
+func (f *arenaStoryFocus) state(StoryID string) storyFocus {
+	users := make([]string, 0, len(f.users))
+	for UserID := range f.users {
+		users = append(users, UserID)
+	}
+
+	return storyFocus{StoryID: StoryID, Users: users, LockedBy: f.lockedBy}
+}
+
+// storyFocusMessage creates the story_focus or story_blur event for the whole arena
+func storyFocusMessage(StoryboardID string, EventType string, UserID string, focus storyFocus) message {
+	focus.UserID = UserID
+	value, _ := json.Marshal(focus)
+
+	return message{data: CreateSocketEvent(EventType, string(value), UserID), arena: StoryboardID}
+}
+
+// remoteFocusChange gets the focus change from a story_focus or story_blur event
+// published by another instance (node), ok is false for any other event
+func remoteFocusChange(node string, arena string, data []byte) (focusChange, bool) {
+	var e SocketEvent
+	if err := json.Unmarshal(data, &e); err != nil {
+		return focusChange{}, false
+	}
+	if e.EventType != "story_focus" && e.EventType != "story_blur" {
+		return focusChange{}, false
+	}
+
+	var f storyFocus
+	if err := json.Unmarshal([]byte(e.EventValue), &f); err != nil || f.StoryID == "" {
+		return focusChange{}, false
+	}
+
+	fc := focusChange{arena: arena, storyID: f.StoryID, userID: f.UserID, focused: e.EventType == "story_focus", node: node}
+	if fc.focused {
+		fc.lock = f.LockedBy == f.UserID
+	} else {
+		fc.lock = f.LockedBy != f.UserID
+	}
+
+	return fc, true
+}
+
+// storyEventID gets the story a socket event edits
+func storyEventID(req *socketRequest) string {
+	if req.Type == "delete_story" {
+		return req.Value
+	}
+
+	var rs struct {
+		StoryID string `json:"storyId"`
+	}
+	json.Unmarshal([]byte(req.Value), &rs)
+
+	return rs.StoryID
+}
+
+// storyEventStories gets the stories a socket event edits keyed by story ID with the storyboard ID each is on,
+// stories identified by an acceptance criterion, link or external reference (or the users operation undone or redone)
+// are looked up, as is the story a link is added to
+func (s *server) storyEventStories(StoryboardID string, UserID string, req *socketRequest) map[string]string {
+	var rs struct {
+		CriterionID   string `json:"criterionId"`
+		LinkID        string `json:"linkId"`
+		RefID         string `json:"refId"`
+		StoryID       string `json:"storyId"`
+		TargetStoryID string `json:"targetStoryId"`
+	}
+	json.Unmarshal([]byte(req.Value), &rs)
+
+	switch req.Type {
+	case "update_story_criterion", "toggle_story_criterion", "move_story_criterion", "delete_story_criterion":
+		return s.criterionStories(StoryboardID, rs.CriterionID)
+	case "add_story_link":
+		return s.linkAddStories(StoryboardID, rs.StoryID, rs.TargetStoryID)
+	case "remove_story_link":
+		return s.linkStories(StoryboardID, rs.LinkID)
+	case "remove_story_external_ref":
+		return s.externalRefStories(StoryboardID, rs.RefID)
+	case "undo", "redo":
+		return s.operationStories(StoryboardID, UserID, req.Type == "undo")
+	}
+
+	return map[string]string{storyEventID(req): StoryboardID}
+}
+
+// operationStories gets the stories undoing (or redoing) the users next operation changes, for a story with the
+// story at the other end of a link it changes, for a goal or column every story under it
+func (s *server) operationStories(StoryboardID string, UserID string, Undo bool) map[string]string {
+	state, err := s.database.NextOperationState(StoryboardID, UserID, Undo)
+	if err != nil {
+		return nil
+	}
+
+	stories := make(map[string]string)
+	addColumn := func(c *database.StoryboardColumn) {
+		for _, st := range c.Stories {
+			stories[st.StoryID] = StoryboardID
+		}
+	}
+
+	switch state.Kind {
+	case "story":
+		stories[state.ID] = StoryboardID
+		if state.Story != nil && state.ItemID != "" {
+			for _, l := range state.Story.Links {
+				stories[l.StoryID] = l.StoryboardID
+			}
+		}
+	case "goal", "column":
+		// the stories the operation state restores and those under the goal or column now,
+		// which it trashes or moves along with it
+		if state.Goal != nil {
+			for _, c := range state.Goal.Columns {
+				addColumn(c)
+			}
+		}
+		if state.Column != nil {
+			addColumn(state.Column)
+		}
+
+		goals := s.database.GetStoryboardGoals(StoryboardID)
+		if state.Kind == "goal" {
+			if g := findGoal(goals, state.ID); g != nil {
+				for _, c := range g.Columns {
+					addColumn(c)
+				}
+			}
+		} else if _, c := findColumn(goals, state.ID); c != nil {
+			addColumn(c)
+		}
+	}
+
+	return stories
+}
+
+// criterionStories gets the story an acceptance criterion is on, none when not found
+func (s *server) criterionStories(StoryboardID string, CriterionID string) map[string]string {
+	c, err := s.database.GetStoryCriterion(StoryboardID, CriterionID)
+	if err != nil {
+		return nil
+	}
+
+	return map[string]string{c.StoryID: StoryboardID}
+}
+
+// linkStories gets the stories at each end of a link, none when not found
+func (s *server) linkStories(StoryboardID string, LinkID string) map[string]string {
+	stories, _ := s.database.GetStoryLinkStories(StoryboardID, LinkID)
+
+	return stories
+}
+
+// linkAddStories gets the story a link is added to and the story it links to (by its storyboard),
+// the story linked to is left out when not found
+func (s *server) linkAddStories(StoryboardID string, StoryID string, ToStoryID string) map[string]string {
+	stories := map[string]string{StoryID: StoryboardID}
+	if ToStoryboardID, err := s.database.GetLinkedStoryStoryboardID(ToStoryID); err == nil {
+		stories[ToStoryID] = ToStoryboardID
+	}
+
+	return stories
+}
+
+// externalRefStories gets the story an external reference is on, none when not found
+func (s *server) externalRefStories(StoryboardID string, RefID string) map[string]string {
+	StoryID, err := s.database.GetStoryExternalRefStoryID(StoryboardID, RefID)
+	if err != nil {
+		return nil
+	}
+
+	return map[string]string{StoryID: StoryboardID}
+}
+
+// storiesLockedBy gets another user holding the lock of any of the stories (by their storyboard), empty when none is
+func storiesLockedBy(Stories map[string]string, UserID string) string {
+	for StoryID, StoryboardID := range Stories {
+		if LockedBy := h.storyLockedBy(StoryboardID, StoryID); LockedBy != "" && LockedBy != UserID {
+			return LockedBy
+		}
+	}
+
+	return ""
+}
+
+// storiesLocked writes a locked status when another user holds the lock of any of the stories (by their storyboard)
+func (s *server) storiesLocked(w http.ResponseWriter, Stories map[string]string, UserID string) bool {
+	if storiesLockedBy(Stories, UserID) != "" {
+		w.WriteHeader(http.StatusLocked)
+		return true
+	}
+
+	return false
+}
+
+// storyLocked writes a locked status when another user holds the stories lock
+func (s *server) storyLocked(w http.ResponseWriter, StoryboardID string, StoryID string, UserID string) bool {
+	return s.storiesLocked(w, map[string]string{StoryID: StoryboardID}, UserID)
+}
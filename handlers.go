@@ -3,12 +3,15 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"html/template"
 	"io/fs"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
 
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
 	"github.com/gorilla/mux"
 	"github.com/spf13/viper"
 	"gopkg.in/go-playground/validator.v9"
@@ -256,7 +259,6 @@ func (s *server) handleIndex(FSS fs.FS) http.HandlerFunc {
 func (s *server) handleStoryboardCreate() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID := r.Context().Value(contextKeyUserID).(string)
-		vars := mux.Vars(r)
 
 		body, bodyErr := ioutil.ReadAll(r.Body) // check for errors
 		if bodyErr != nil {
@@ -267,9 +269,37 @@ func (s *server) handleStoryboardCreate() http.HandlerFunc {
 
 		var keyVal struct {
 			StoryboardName string `json:"storyboardName"`
+			TemplateID     string `json:"templateId"`
+			IncludeStories bool   `json:"includeStories"`
 		}
 		json.Unmarshal(body, &keyVal) // check for errors
 
+		// if storyboard created from a template
+		if keyVal.TemplateID != "" {
+			if err := s.database.ConfirmTemplateAccess(keyVal.TemplateID, userID); err != nil {
+				http.NotFound(w, r)
+				return
+			}
+
+			newStoryboard, err := s.database.CloneStoryboard(keyVal.TemplateID, userID, storyboardTeamID(r), keyVal.StoryboardName, keyVal.IncludeStories)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			s.audit(r, &database.AuditEntry{
+				Action:       "create_storyboard",
+				TargetType:   "storyboard",
+				TargetID:     newStoryboard.StoryboardID,
+				StoryboardID: newStoryboard.StoryboardID,
+				TeamID:       storyboardTeamID(r),
+				After:        map[string]interface{}{"name": newStoryboard.StoryboardName, "template_id": keyVal.TemplateID, "include_stories": keyVal.IncludeStories},
+			})
+
+			s.respondWithJSON(w, http.StatusOK, newStoryboard)
+			return
+		}
+
 		newStoryboard, err := s.database.CreateStoryboard(userID, keyVal.StoryboardName)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
@@ -277,33 +307,66 @@ func (s *server) handleStoryboardCreate() http.HandlerFunc {
 		}
 
 		// if storyboard created with team association
-		TeamID, ok := vars["teamId"]
-		if ok {
-			OrgRole := r.Context().Value(contextKeyOrgRole)
-			DepartmentRole := r.Context().Value(contextKeyDepartmentRole)
-			TeamRole := r.Context().Value(contextKeyTeamRole).(string)
-			var isAdmin bool
-			if DepartmentRole != nil && DepartmentRole.(string) == "ADMIN" {
-				isAdmin = true
-			}
-			if OrgRole != nil && OrgRole.(string) == "ADMIN" {
-				isAdmin = true
-			}
-
-			if isAdmin == true || TeamRole != "" {
-				err := s.database.TeamAddStoryboard(TeamID, newStoryboard.StoryboardID)
+		if TeamID := storyboardTeamID(r); TeamID != "" {
+			err := s.database.TeamAddStoryboard(TeamID, newStoryboard.StoryboardID)
 
-				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					return
-				}
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
 			}
 		}
 
+		s.audit(r, &database.AuditEntry{
+			Action:       "create_storyboard",
+			TargetType:   "storyboard",
+			TargetID:     newStoryboard.StoryboardID,
+			StoryboardID: newStoryboard.StoryboardID,
+			TeamID:       storyboardTeamID(r),
+			After:        map[string]string{"name": newStoryboard.StoryboardName},
+		})
+
 		s.respondWithJSON(w, http.StatusOK, newStoryboard)
 	}
 }
 
+// handleStoryboardTemplatesGet looks up the storyboard templates available to the user
+func (s *server) handleStoryboardTemplatesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(contextKeyUserID).(string)
+
+		templates := s.database.GetStoryboardTemplatesByUser(userID)
+
+		s.respondWithJSON(w, http.StatusOK, templates)
+	}
+}
+
+// storyboardTeamID gets the team a new storyboard should be associated with (if any),
+// the user must be on the team or an admin of its organization or department
+func storyboardTeamID(r *http.Request) string {
+	vars := mux.Vars(r)
+	TeamID, ok := vars["teamId"]
+	if !ok {
+		return ""
+	}
+
+	OrgRole := r.Context().Value(contextKeyOrgRole)
+	DepartmentRole := r.Context().Value(contextKeyDepartmentRole)
+	TeamRole := r.Context().Value(contextKeyTeamRole).(string)
+	var isAdmin bool
+	if DepartmentRole != nil && DepartmentRole.(string) == "ADMIN" {
+		isAdmin = true
+	}
+	if OrgRole != nil && OrgRole.(string) == "ADMIN" {
+		isAdmin = true
+	}
+
+	if isAdmin == true || TeamRole != "" {
+		return TeamID
+	}
+
+	return ""
+}
+
 // handleStoryboardGet looks up storyboard or returns notfound status
 func (s *server) handleStoryboardGet() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -317,6 +380,7 @@ func (s *server) handleStoryboardGet() http.HandlerFunc {
 			return
 		}
 
+		w.Header().Set("ETag", fmt.Sprintf(`"%d"`, storyboard.Revision))
 		s.respondWithJSON(w, http.StatusOK, storyboard)
 	}
 }
@@ -336,3 +400,73 @@ func (s *server) handleStoryboardsGet() http.HandlerFunc {
 		s.respondWithJSON(w, http.StatusOK, storyboards)
 	}
 }
+
+// handleUserAssignedStories looks up the open stories assigned to the user across their storyboards,
+// optionally only those with every label in the comma separated labels query (by name)
+func (s *server) handleUserAssignedStories() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		UserID := vars["id"]
+		UserCookieID := r.Context().Value(contextKeyUserID).(string)
+		if UserID != UserCookieID {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		assigned, err := s.database.GetUserAssignedStories(UserID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		filter := labelFilter(r)
+		stories := make([]*database.AssignedStory, 0)
+		for _, st := range assigned {
+			if database.HasLabels(st.Labels, filter) {
+				stories = append(stories, st)
+			}
+		}
+
+		s.respondWithJSON(w, http.StatusOK, stories)
+	}
+}
+
+// handleUserExternalRefStories handles looking up the stories referencing an external key (from any tracker
+// unless a system is given) on the storyboards the user can access
+func (s *server) handleUserExternalRefStories() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		UserID := vars["id"]
+		UserCookieID := r.Context().Value(contextKeyUserID).(string)
+		if UserID != UserCookieID {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		query := r.URL.Query()
+		Key := strings.TrimSpace(query.Get("key"))
+		if Key == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		stories, err := s.database.GetStoriesByExternalRef(UserID, query.Get("system"), Key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		s.respondWithJSON(w, http.StatusOK, stories)
+	}
+}
+
+// handleStoryboardsTrashGet looks up the storyboards in the users trash
+func (s *server) handleStoryboardsTrashGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID := r.Context().Value(contextKeyUserID).(string)
+
+		storyboards := s.database.GetDeletedStoryboards(userID)
+
+		s.respondWithJSON(w, http.StatusOK, storyboards)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
 	"github.com/gorilla/mux"
 	"github.com/spf13/viper"
 )
@@ -19,12 +20,9 @@ func (s *server) handleAppStats() http.HandlerFunc {
 			return
 		}
 
-		ActiveStoryboardUserCount := 0
-		for _, s := range h.arenas {
-			ActiveStoryboardUserCount = ActiveStoryboardUserCount + len(s)
-		}
+		ActiveStoryboardCount, ActiveStoryboardUserCount := activePresence(s.database)
 
-		AppStats.ActiveStoryboardCount = len(h.arenas)
+		AppStats.ActiveStoryboardCount = ActiveStoryboardCount
 		AppStats.ActiveStoryboardUserCount = ActiveStoryboardUserCount
 
 		s.respondWithJSON(w, http.StatusOK, AppStats)
@@ -67,6 +65,13 @@ func (s *server) handleUserCreate() http.HandlerFunc {
 			return
 		}
 
+		s.audit(r, &database.AuditEntry{
+			Action:     "create_user",
+			TargetType: "user",
+			TargetID:   newUser.UserID,
+			After:      map[string]string{"name": newUser.UserName, "email": newUser.UserEmail, "type": newUser.UserType},
+		})
+
 		s.email.SendWelcome(UserName, UserEmail, VerifyID)
 
 		s.respondWithJSON(w, http.StatusOK, newUser)
@@ -78,11 +83,18 @@ func (s *server) handleUserPromote() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		keyVal := s.getJSONRequestBody(r, w)
 
-		err := s.database.PromoteUser(keyVal["userId"].(string))
+		UserID := keyVal["userId"].(string)
+		e := &database.AuditEntry{Action: "promote_user", TargetType: "user", TargetID: UserID, After: map[string]string{"type": "ADMIN"}}
+		if User, err := s.database.GetUser(UserID); err == nil {
+			e.Before = map[string]string{"type": User.UserType}
+		}
+
+		err := s.database.PromoteUser(UserID)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, e)
 
 		return
 	}
@@ -93,11 +105,18 @@ func (s *server) handleUserDemote() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		keyVal := s.getJSONRequestBody(r, w)
 
-		err := s.database.DemoteUser(keyVal["userId"].(string))
+		UserID := keyVal["userId"].(string)
+		e := &database.AuditEntry{Action: "demote_user", TargetType: "user", TargetID: UserID, After: map[string]string{"type": "REGISTERED"}}
+		if User, err := s.database.GetUser(UserID); err == nil {
+			e.Before = map[string]string{"type": User.UserType}
+		}
+
+		err := s.database.DemoteUser(UserID)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, e)
 
 		return
 	}
@@ -113,6 +132,7 @@ func (s *server) handleCleanStoryboards() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{Action: "clean_storyboards", TargetType: "storyboard", After: map[string]int{"days_old": DaysOld}})
 
 		return
 	}
@@ -128,6 +148,7 @@ func (s *server) handleCleanGuests() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{Action: "clean_guests", TargetType: "user", After: map[string]int{"days_old": DaysOld}})
 
 		return
 	}
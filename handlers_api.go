@@ -3,10 +3,17 @@ package main
 import (
 	"log"
 	"net/http"
+	"strings"
 
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
 	"github.com/gorilla/mux"
 )
 
+// apiKeyPrefix gets the public prefix of an API key id, so the audit log never holds the rest of it
+func apiKeyPrefix(KeyID string) string {
+	return strings.SplitN(KeyID, ".", 2)[0]
+}
+
 // handleAPIKeyGenerate handles generating an API key for a user
 func (s *server) handleAPIKeyGenerate() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -27,6 +34,10 @@ func (s *server) handleAPIKeyGenerate() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "api_key_generate", TargetType: "api_key", TargetID: APIKey.Prefix,
+			After: map[string]interface{}{"name": APIKey.Name, "active": APIKey.Active},
+		})
 
 		s.respondWithJSON(w, http.StatusOK, APIKey)
 	}
@@ -76,6 +87,10 @@ func (s *server) handleUserAPIKeyUpdate() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "api_key_update", TargetType: "api_key", TargetID: apiKeyPrefix(APK),
+			After: map[string]bool{"active": active},
+		})
 
 		s.respondWithJSON(w, http.StatusOK, APIKeys)
 	}
@@ -100,6 +115,7 @@ func (s *server) handleUserAPIKeyDelete() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{Action: "api_key_delete", TargetType: "api_key", TargetID: apiKeyPrefix(APK)})
 
 		s.respondWithJSON(w, http.StatusOK, APIKeys)
 	}
@@ -77,6 +77,10 @@ func (s *server) handleCreateDepartment() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "create_department", TargetType: "department", TargetID: DepartmentID, DepartmentID: DepartmentID,
+			After: map[string]string{"name": OrgName},
+		})
 
 		var NewDepartment = &CreateDepartmentResponse{
 			DepartmentID: DepartmentID,
@@ -132,6 +136,10 @@ func (s *server) handleCreateDepartmentTeam() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "create_team", TargetType: "team", TargetID: TeamID, TeamID: TeamID,
+			After: map[string]string{"name": TeamName},
+		})
 
 		var NewTeam = &CreateTeamResponse{
 			TeamID: TeamID,
@@ -162,6 +170,10 @@ func (s *server) handleDepartmentAddUser() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "department_add_user", TargetType: "user", TargetID: User.UserID,
+			After: map[string]string{"role": Role},
+		})
 
 		return
 	}
@@ -176,11 +188,16 @@ func (s *server) handleDepartmentRemoveUser() http.HandlerFunc {
 		DepartmentID := vars["departmentId"]
 		UserID := keyVal["id"].(string)
 
+		_, Role, _ := s.database.DepartmentUserRole(UserID, vars["orgId"], DepartmentID)
 		err := s.database.DepartmentRemoveUser(DepartmentID, UserID)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "department_remove_user", TargetType: "user", TargetID: UserID,
+			Before: map[string]string{"role": Role},
+		})
 
 		return
 	}
@@ -215,6 +232,10 @@ func (s *server) handleDepartmentTeamAddUser() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "team_add_user", TargetType: "user", TargetID: User.UserID,
+			After: map[string]string{"role": Role},
+		})
 
 		return
 	}
@@ -63,6 +63,10 @@ func (s *server) handleCreateOrganization() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "create_organization", TargetType: "organization", TargetID: OrgId, OrganizationID: OrgId,
+			After: map[string]string{"name": OrgName},
+		})
 
 		var NewOrg = &CreateOrgResponse{
 			OrganizationID: OrgId,
@@ -118,6 +122,10 @@ func (s *server) handleCreateOrganizationTeam() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "create_team", TargetType: "team", TargetID: TeamID, TeamID: TeamID,
+			After: map[string]string{"name": TeamName},
+		})
 
 		var NewTeam = &CreateTeamResponse{
 			TeamID: TeamID,
@@ -148,6 +156,10 @@ func (s *server) handleOrganizationAddUser() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "organization_add_user", TargetType: "user", TargetID: User.UserID,
+			After: map[string]string{"role": Role},
+		})
 
 		return
 	}
@@ -162,11 +174,16 @@ func (s *server) handleOrganizationRemoveUser() http.HandlerFunc {
 		OrgID := vars["orgId"]
 		UserID := keyVal["id"].(string)
 
+		Role, _ := s.database.OrganizationUserRole(UserID, OrgID)
 		err := s.database.OrganizationRemoveUser(OrgID, UserID)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "organization_remove_user", TargetType: "user", TargetID: UserID,
+			Before: map[string]string{"role": Role},
+		})
 
 		return
 	}
@@ -236,7 +253,23 @@ func (s *server) handleOrganizationTeamAddUser() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "team_add_user", TargetType: "user", TargetID: User.UserID,
+			After: map[string]string{"role": Role},
+		})
 
 		return
 	}
 }
+
+// handleGetOrganizationStoryboardTemplates gets a list of the organizations storyboard templates
+func (s *server) handleGetOrganizationStoryboardTemplates() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		OrgID := vars["orgId"]
+
+		templates := s.database.OrganizationStoryboardTemplateList(OrgID)
+
+		s.respondWithJSON(w, http.StatusOK, templates)
+	}
+}
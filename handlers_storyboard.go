@@ -0,0 +1,2243 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// maxImportSize is the largest storyboard export document accepted for import
+const maxImportSize = 10 << 20
+
+/*
+	Storyboard Goal, Column, Story, and Persona Handlers
+*/
+
+// handleStoryboardGoalAdd handles adding a goal to a storyboard
+func (s *server) handleStoryboardGoalAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_goal", "goal", "")
+
+		goals, err := s.database.CreateStoryboardGoal(StoryboardID, UserID, rs.Name)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := goalAddedMessage(StoryboardID, goals)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = rs
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardGoalUpdate handles revising a storyboard goals name
+func (s *server) handleStoryboardGoalUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		GoalID := vars["goalId"]
+
+		var rs struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "revise_goal", "goal", GoalID)
+
+		goals, err := s.database.ReviseGoalName(StoryboardID, UserID, GoalID, rs.Name)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := goalRevisedMessage(StoryboardID, goals, GoalID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "goal", GoalID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardGoalMove handles moving a storyboard goal to before another goal (or last)
+func (s *server) handleStoryboardGoalMove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		GoalID := vars["goalId"]
+
+		var rs struct {
+			PlaceBefore string `json:"placeBefore"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "move_goal", "goal", GoalID)
+
+		goals, err := s.database.MoveStoryboardGoal(StoryboardID, UserID, GoalID, rs.PlaceBefore)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := goalMovedMessage(StoryboardID, goals, GoalID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "goal", GoalID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardGoalDelete handles deleting a storyboard goal
+func (s *server) handleStoryboardGoalDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		GoalID := vars["goalId"]
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "delete_goal", "goal", GoalID)
+
+		goals, err := s.database.DeleteStoryboardGoal(StoryboardID, UserID, GoalID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := goalDeletedMessage(StoryboardID, goals, GoalID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardColumnAdd handles adding a column to a storyboard goal
+func (s *server) handleStoryboardColumnAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			GoalID string `json:"goalId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_column", "column", "")
+
+		goals, err := s.database.CreateStoryboardColumn(StoryboardID, rs.GoalID, UserID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := columnAddedMessage(StoryboardID, goals, rs.GoalID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = rs
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardColumnUpdate handles revising a storyboard columns name
+func (s *server) handleStoryboardColumnUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		ColumnID := vars["columnId"]
+
+		var rs struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "revise_column", "column", ColumnID)
+
+		goals, err := s.database.ReviseStoryboardColumn(StoryboardID, UserID, ColumnID, rs.Name)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := columnUpdatedMessage(StoryboardID, goals, ColumnID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "column", ColumnID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardColumnMove handles moving a storyboard column with its stories
+// to before another column (or last) of a goal
+func (s *server) handleStoryboardColumnMove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		ColumnID := vars["columnId"]
+
+		var rs struct {
+			GoalID      string `json:"goalId"`
+			PlaceBefore string `json:"placeBefore"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "move_column", "column", ColumnID)
+
+		FromGoalID := s.columnGoalID(StoryboardID, ColumnID)
+		goals, err := s.database.MoveStoryboardColumn(StoryboardID, UserID, ColumnID, rs.GoalID, rs.PlaceBefore)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := columnMovedMessage(StoryboardID, goals, ColumnID, FromGoalID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "column", ColumnID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// columnGoalID gets the goal a storyboard column is currently in
+func (s *server) columnGoalID(StoryboardID string, ColumnID string) string {
+	if g, _ := findColumn(s.database.GetStoryboardGoals(StoryboardID), ColumnID); g != nil {
+		return g.GoalID
+	}
+
+	return ""
+}
+
+// handleStoryboardColumnDelete handles deleting a storyboard column
+func (s *server) handleStoryboardColumnDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		ColumnID := vars["columnId"]
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "delete_column", "column", ColumnID)
+
+		goals, err := s.database.DeleteStoryboardColumn(StoryboardID, UserID, ColumnID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := columnDeletedMessage(StoryboardID, goals, ColumnID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryAdd handles adding a story to a storyboard goal column
+func (s *server) handleStoryboardStoryAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			GoalID   string `json:"goalId"`
+			ColumnID string `json:"columnId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_story", "story", "")
+
+		goals, err := s.database.CreateStoryboardStory(StoryboardID, rs.GoalID, rs.ColumnID, UserID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyAddedMessage(StoryboardID, goals, rs.ColumnID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = rs
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryUpdate handles revising a storyboard story,
+// only the fields present in the request body are updated
+func (s *server) handleStoryboardStoryUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		var rs database.StoryRevision
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "revise_story", "story", StoryID)
+
+		goals, err := s.database.ReviseStory(StoryboardID, UserID, StoryID, &rs)
+		if err != nil {
+			w.WriteHeader(storyRevisionErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, StoryID)
+		if rs.Closed != nil {
+			m = storyClosedMessage(StoryboardID, goals, StoryID)
+		}
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+		if rs.Closed != nil {
+			s.notifyLinkedStoryboards(StoryboardID, blockedStoryboards(goals, StoryID)...)
+		}
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryMove handles moving a story to a goal column, placed before another story (or last)
+func (s *server) handleStoryboardStoryMove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		var rs struct {
+			GoalID      string `json:"goalId"`
+			ColumnID    string `json:"columnId"`
+			PlaceBefore string `json:"placeBefore"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "move_story", "story", StoryID)
+
+		From, _ := s.database.GetStoryPosition(StoryboardID, StoryID)
+		goals, err := s.database.MoveStoryboardStory(StoryboardID, UserID, StoryID, rs.GoalID, rs.ColumnID, rs.PlaceBefore)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyMovedMessage(StoryboardID, goals, StoryID, From)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryDelete handles deleting a storyboard story
+func (s *server) handleStoryboardStoryDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "delete_story", "story", StoryID)
+
+		goals, err := s.database.DeleteStoryboardStory(StoryboardID, UserID, StoryID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyDeletedMessage(StoryboardID, goals, StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryCommentAdd handles adding a comment (or a reply to a comment) to a storyboard story
+func (s *server) handleStoryboardStoryCommentAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		var rs struct {
+			ParentID string `json:"parentId"`
+			Comment  string `json:"comment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_story_comment", "story", StoryID)
+
+		goals, mentions, err := s.database.AddStoryComment(StoryboardID, UserID, StoryID, rs.Comment, rs.ParentID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+		notifyMentions(mentions)
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryCommentUpdate handles editing a storyboard story comment (author or storyboard owner only)
+func (s *server) handleStoryboardStoryCommentUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		CommentID := vars["commentId"]
+
+		var rs struct {
+			Comment string `json:"comment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "update_story_comment", "comment", CommentID)
+
+		Comment, err := s.database.GetStoryComment(StoryboardID, CommentID)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		goals, mentions, err := s.database.UpdateStoryComment(StoryboardID, UserID, CommentID, rs.Comment)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, Comment.StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+		notifyMentions(mentions)
+
+		e.After = s.auditTargetState(StoryboardID, "comment", CommentID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryCommentDelete handles deleting a storyboard story comment with its replies
+// (author or storyboard owner only)
+func (s *server) handleStoryboardStoryCommentDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		CommentID := vars["commentId"]
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "delete_story_comment", "comment", CommentID)
+
+		Comment, err := s.database.GetStoryComment(StoryboardID, CommentID)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		goals, err := s.database.DeleteStoryComment(StoryboardID, UserID, CommentID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, Comment.StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// storyboardErrorStatus gets the response status for a failed storyboard change from its error code
+func storyboardErrorStatus(err error) int {
+	switch eventErrorCode(err) {
+	case "forbidden":
+		return http.StatusForbidden
+	case "not_found":
+		return http.StatusNotFound
+	case "validation":
+		return http.StatusBadRequest
+	case "conflict":
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
+
+// storyRevisionErrorStatus gets the response status for a failed story revision,
+// closing a story with open acceptance criteria is a conflict
+func storyRevisionErrorStatus(err error) int {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23514" {
+		return http.StatusConflict
+	}
+
+	return storyboardErrorStatus(err)
+}
+
+// handleStoryboardPersonaAdd handles adding a persona to a storyboard
+func (s *server) handleStoryboardPersonaAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			Name        string `json:"name"`
+			Role        string `json:"role"`
+			Description string `json:"description"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_persona", "persona", "")
+
+		personas, err := s.database.AddPersona(StoryboardID, UserID, rs.Name, rs.Role, rs.Description)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		updatedPersonas, _ := json.Marshal(personas)
+		m := message{data: CreateSocketEvent("personas_updated", string(updatedPersonas), ""), arena: StoryboardID}
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = rs
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, personas)
+	}
+}
+
+// handleStoryboardPersonaUpdate handles updating a storyboard persona
+func (s *server) handleStoryboardPersonaUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		PersonaID := vars["personaId"]
+
+		var rs struct {
+			Name        string `json:"name"`
+			Role        string `json:"role"`
+			Description string `json:"description"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "update_persona", "persona", PersonaID)
+
+		personas, err := s.database.UpdatePersona(StoryboardID, UserID, PersonaID, rs.Name, rs.Role, rs.Description)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		updatedPersonas, _ := json.Marshal(personas)
+		m := message{data: CreateSocketEvent("personas_updated", string(updatedPersonas), ""), arena: StoryboardID}
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = s.auditTargetState(StoryboardID, "persona", PersonaID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, personas)
+	}
+}
+
+// handleStoryboardPersonaDelete handles deleting a storyboard persona
+func (s *server) handleStoryboardPersonaDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		PersonaID := vars["personaId"]
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "delete_persona", "persona", PersonaID)
+
+		personas, err := s.database.DeletePersona(StoryboardID, UserID, PersonaID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		updatedPersonas, _ := json.Marshal(personas)
+		m := message{data: CreateSocketEvent("personas_updated", string(updatedPersonas), ""), arena: StoryboardID}
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, personas)
+	}
+}
+
+// handleStoryboardUsersGet handles getting the storyboards users along with their roles
+func (s *server) handleStoryboardUsersGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		if _, err := s.database.GetStoryboardRevision(StoryboardID); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		users := s.database.GetStoryboardUsers(StoryboardID)
+
+		s.respondWithJSON(w, http.StatusOK, users)
+	}
+}
+
+// handleStoryboardUserRoleUpdate handles the owner setting a users EDITOR or VIEWER role
+func (s *server) handleStoryboardUserRoleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		MemberID := vars["userId"]
+
+		var rs struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil || (rs.Role != "EDITOR" && rs.Role != "VIEWER") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		e := s.storyboardAudit(StoryboardID, "set_user_role", "user", MemberID)
+
+		users, err := s.database.SetStoryboardUserRole(StoryboardID, UserID, MemberID, rs.Role)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		updatedUsers, _ := json.Marshal(users)
+		h.broadcast <- message{data: CreateSocketEvent("users_updated", string(updatedUsers), ""), arena: StoryboardID}
+
+		e.After = s.auditTargetState(StoryboardID, "user", MemberID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, users)
+	}
+}
+
+// handleStoryboardUserRoleDelete handles the owner reverting a user to their default role
+func (s *server) handleStoryboardUserRoleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		MemberID := vars["userId"]
+
+		e := s.storyboardAudit(StoryboardID, "set_user_role", "user", MemberID)
+
+		users, err := s.database.SetStoryboardUserRole(StoryboardID, UserID, MemberID, "")
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		updatedUsers, _ := json.Marshal(users)
+		h.broadcast <- message{data: CreateSocketEvent("users_updated", string(updatedUsers), ""), arena: StoryboardID}
+
+		e.After = s.auditTargetState(StoryboardID, "user", MemberID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, users)
+	}
+}
+
+// handleStoryboardReleasesGet handles getting the storyboards releases with their point totals
+func (s *server) handleStoryboardReleasesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		if _, err := s.database.GetStoryboardRevision(StoryboardID); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		releases := s.database.GetStoryboardReleases(StoryboardID)
+
+		s.respondWithJSON(w, http.StatusOK, releases)
+	}
+}
+
+// handleStoryboardReleaseAdd handles adding a release to a storyboard
+func (s *server) handleStoryboardReleaseAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			Name       string `json:"name"`
+			TargetDate string `json:"targetDate"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_release", "release", "")
+
+		releases, err := s.database.AddRelease(StoryboardID, UserID, rs.Name, rs.TargetDate)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := releasesMessage(StoryboardID, releases)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = rs
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, releases)
+	}
+}
+
+// handleStoryboardReleaseUpdate handles revising a storyboard releases name and target date
+func (s *server) handleStoryboardReleaseUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		ReleaseID := vars["releaseId"]
+
+		var rs struct {
+			Name       string `json:"name"`
+			TargetDate string `json:"targetDate"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "revise_release", "release", ReleaseID)
+
+		releases, err := s.database.ReviseRelease(StoryboardID, UserID, ReleaseID, rs.Name, rs.TargetDate)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := releasesMessage(StoryboardID, releases)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = s.auditTargetState(StoryboardID, "release", ReleaseID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, releases)
+	}
+}
+
+// handleStoryboardReleaseMove handles reordering a release, placed before another release (or last)
+func (s *server) handleStoryboardReleaseMove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		ReleaseID := vars["releaseId"]
+
+		var rs struct {
+			PlaceBefore string `json:"placeBefore"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "move_release", "release", ReleaseID)
+
+		releases, err := s.database.MoveRelease(StoryboardID, UserID, ReleaseID, rs.PlaceBefore)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := releasesMessage(StoryboardID, releases)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = s.auditTargetState(StoryboardID, "release", ReleaseID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, releases)
+	}
+}
+
+// handleStoryboardReleaseDelete handles deleting a storyboard release, its stories become unassigned
+func (s *server) handleStoryboardReleaseDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		ReleaseID := vars["releaseId"]
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "delete_release", "release", ReleaseID)
+
+		releases, err := s.database.DeleteRelease(StoryboardID, UserID, ReleaseID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		storyboard, err := s.database.GetStoryboard(StoryboardID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		updatedStoryboard, _ := json.Marshal(storyboard)
+		m := message{data: CreateSocketEvent("storyboard_updated", string(updatedStoryboard), ""), arena: StoryboardID}
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, releases)
+	}
+}
+
+// handleStoryboardStoryReleaseUpdate handles moving a story into a release, an empty releaseId unassigns it
+func (s *server) handleStoryboardStoryReleaseUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		var rs struct {
+			ReleaseID string `json:"releaseId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "update_story_release", "story", StoryID)
+
+		if _, err := s.database.ReviseStoryRelease(StoryboardID, UserID, StoryID, rs.ReleaseID); err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		storyboard, err := s.database.GetStoryboard(StoryboardID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		m := storyReleaseMessage(StoryboardID, storyboard, StoryID, rs.ReleaseID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(storyboard.Goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, storyboard.Releases)
+	}
+}
+
+// handleStoryboardStoryAssigneeAdd handles assigning a storyboard user or team member to a story
+func (s *server) handleStoryboardStoryAssigneeAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		var rs struct {
+			UserID string `json:"userId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil || rs.UserID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "assign_story", "story", StoryID)
+
+		goals, err := s.database.AddStoryAssignee(StoryboardID, UserID, StoryID, rs.UserID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryAssigneeRemove handles unassigning a user from a story
+func (s *server) handleStoryboardStoryAssigneeRemove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+		AssigneeID := vars["userId"]
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "unassign_story", "story", StoryID)
+
+		goals, err := s.database.RemoveStoryAssignee(StoryboardID, UserID, StoryID, AssigneeID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardLabelsGet handles getting a storyboards labels
+func (s *server) handleStoryboardLabelsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		if _, err := s.database.GetStoryboardRevision(StoryboardID); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		labels := s.database.GetStoryboardLabels(StoryboardID)
+
+		s.respondWithJSON(w, http.StatusOK, labels)
+	}
+}
+
+// handleStoryboardLabelAdd handles adding a label to a storyboard
+func (s *server) handleStoryboardLabelAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil || rs.Name == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_label", "label", "")
+
+		labels, err := s.database.AddLabel(StoryboardID, UserID, rs.Name, rs.Color)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := labelsMessage(StoryboardID, labels)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = rs
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, labels)
+	}
+}
+
+// handleStoryboardLabelUpdate handles revising a storyboard labels name and color
+func (s *server) handleStoryboardLabelUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		LabelID := vars["labelId"]
+
+		var rs struct {
+			Name  string `json:"name"`
+			Color string `json:"color"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil || rs.Name == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "revise_label", "label", LabelID)
+
+		labels, err := s.database.ReviseLabel(StoryboardID, UserID, LabelID, rs.Name, rs.Color)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		storyboard, err := s.database.GetStoryboard(StoryboardID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		m := storyboardUpdatedMessage(StoryboardID, storyboard)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = s.auditTargetState(StoryboardID, "label", LabelID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, labels)
+	}
+}
+
+// handleStoryboardLabelDelete handles deleting a storyboard label, removing it from its stories
+func (s *server) handleStoryboardLabelDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		LabelID := vars["labelId"]
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "delete_label", "label", LabelID)
+
+		labels, err := s.database.DeleteLabel(StoryboardID, UserID, LabelID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		storyboard, err := s.database.GetStoryboard(StoryboardID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		m := storyboardUpdatedMessage(StoryboardID, storyboard)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, labels)
+	}
+}
+
+// handleStoryboardStoryLabelAdd handles adding one of the storyboards labels to a story
+func (s *server) handleStoryboardStoryLabelAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		var rs struct {
+			LabelID string `json:"labelId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil || rs.LabelID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_story_label", "story", StoryID)
+
+		goals, err := s.database.AddStoryLabel(StoryboardID, UserID, StoryID, rs.LabelID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryLabelRemove handles removing a label from a story
+func (s *server) handleStoryboardStoryLabelRemove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+		LabelID := vars["labelId"]
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "remove_story_label", "story", StoryID)
+
+		goals, err := s.database.RemoveStoryLabel(StoryboardID, UserID, StoryID, LabelID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoriesGet handles listing a storyboards stories in map order,
+// optionally only those with every label in the comma separated labels query (by ID or name)
+func (s *server) handleStoryboardStoriesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		if _, err := s.database.GetStoryboardRevision(StoryboardID); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		filter := labelFilter(r)
+		stories := make([]*database.StoryboardStory, 0)
+		for _, g := range s.database.GetStoryboardGoals(StoryboardID) {
+			for _, c := range g.Columns {
+				for _, st := range c.Stories {
+					if database.HasLabels(st.Labels, filter) {
+						stories = append(stories, st)
+					}
+				}
+			}
+		}
+
+		s.respondWithJSON(w, http.StatusOK, stories)
+	}
+}
+
+// labelFilter gets the labels a story listing is filtered by from the comma separated labels query
+func labelFilter(r *http.Request) []string {
+	var filter []string
+	for _, l := range strings.Split(r.URL.Query().Get("labels"), ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			filter = append(filter, l)
+		}
+	}
+
+	return filter
+}
+
+// handleStoryboardStoryCriterionAdd handles adding an acceptance criterion to a storys checklist
+func (s *server) handleStoryboardStoryCriterionAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		var rs struct {
+			Text string `json:"text"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil || rs.Text == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_story_criterion", "story", StoryID)
+
+		goals, err := s.database.AddStoryCriterion(StoryboardID, UserID, StoryID, rs.Text)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryCriterionUpdate handles revising an acceptance criterions text and/or marking it done or open
+func (s *server) handleStoryboardStoryCriterionUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		CriterionID := vars["criterionId"]
+
+		var rs struct {
+			Text *string `json:"text"`
+			Done *bool   `json:"done"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil || (rs.Text != nil && *rs.Text == "") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "update_story_criterion", "criterion", CriterionID)
+
+		Criterion, err := s.database.GetStoryCriterion(StoryboardID, CriterionID)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if s.storyLocked(w, StoryboardID, Criterion.StoryID, UserID) {
+			return
+		}
+
+		goals, err := s.database.UpdateStoryCriterion(StoryboardID, UserID, CriterionID, rs.Text, rs.Done)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, Criterion.StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "criterion", CriterionID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryCriterionMove handles moving an acceptance criterion before another in its checklist (or last)
+func (s *server) handleStoryboardStoryCriterionMove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		CriterionID := vars["criterionId"]
+
+		var rs struct {
+			PlaceBefore string `json:"placeBefore"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "move_story_criterion", "criterion", CriterionID)
+
+		Criterion, err := s.database.GetStoryCriterion(StoryboardID, CriterionID)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if s.storyLocked(w, StoryboardID, Criterion.StoryID, UserID) {
+			return
+		}
+		goals, err := s.database.MoveStoryCriterion(StoryboardID, UserID, CriterionID, rs.PlaceBefore)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, Criterion.StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "criterion", CriterionID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryCriterionDelete handles deleting an acceptance criterion
+func (s *server) handleStoryboardStoryCriterionDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		CriterionID := vars["criterionId"]
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "delete_story_criterion", "criterion", CriterionID)
+
+		Criterion, err := s.database.GetStoryCriterion(StoryboardID, CriterionID)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if s.storyLocked(w, StoryboardID, Criterion.StoryID, UserID) {
+			return
+		}
+		goals, err := s.database.DeleteStoryCriterion(StoryboardID, UserID, CriterionID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, Criterion.StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardCriteriaRuleUpdate handles setting whether stories require their acceptance criteria done to close (owner only)
+func (s *server) handleStoryboardCriteriaRuleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			RequireDone bool `json:"requireDone"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "revise_criteria_rule", "storyboard", StoryboardID)
+		if storyboard, err := s.database.GetStoryboard(StoryboardID); err == nil {
+			e.Before = map[string]bool{"require_criteria_done": storyboard.RequireCriteriaDone}
+		}
+
+		storyboard, err := s.database.ReviseCriteriaRule(StoryboardID, UserID, rs.RequireDone)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyboardUpdatedMessage(StoryboardID, storyboard)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = map[string]bool{"require_criteria_done": storyboard.RequireCriteriaDone}
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, storyboard)
+	}
+}
+
+// handleStoryboardStoryLinkAdd handles linking a story to a story on any storyboard the user can access
+func (s *server) handleStoryboardStoryLinkAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		var rs struct {
+			TargetStoryID string `json:"targetStoryId"`
+			Type          string `json:"type"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil || rs.TargetStoryID == "" || !database.ValidStoryLinkType(rs.Type) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.storiesLocked(w, s.linkAddStories(StoryboardID, StoryID, rs.TargetStoryID), UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_story_link", "story", StoryID)
+
+		goals, LinkedStoryboardID, err := s.database.AddStoryLink(StoryboardID, UserID, StoryID, rs.TargetStoryID, rs.Type)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyLinksMessage(StoryboardID, goals)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+		s.notifyLinkedStoryboards(StoryboardID, LinkedStoryboardID)
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryLinkRemove handles removing a link to or from one of the storyboards stories
+func (s *server) handleStoryboardStoryLinkRemove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		LinkID := vars["linkId"]
+
+		if s.storiesLocked(w, s.linkStories(StoryboardID, LinkID), UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "remove_story_link", "link", LinkID)
+
+		goals, LinkedStoryboardID, err := s.database.RemoveStoryLink(StoryboardID, UserID, LinkID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyLinksMessage(StoryboardID, goals)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+		s.notifyLinkedStoryboards(StoryboardID, LinkedStoryboardID)
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryExternalRefAdd handles adding a reference to an external tracker issue to a story,
+// re-adding a reference updates its URL and cached status
+func (s *server) handleStoryboardStoryExternalRefAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		StoryID := vars["storyId"]
+
+		var rs struct {
+			System string `json:"system"`
+			Key    string `json:"key"`
+			URL    string `json:"url"`
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil || !database.ValidExternalRef(rs.System, rs.Key, rs.URL) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if s.storyLocked(w, StoryboardID, StoryID, UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "add_story_external_ref", "story", StoryID)
+
+		goals, err := s.database.AddStoryExternalRef(StoryboardID, UserID, StoryID, rs.System, rs.Key, rs.URL, rs.Status)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(goals, "story", StoryID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardStoryExternalRefRemove handles removing an external reference from one of the storyboards stories
+func (s *server) handleStoryboardStoryExternalRefRemove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		RefID := vars["refId"]
+
+		if s.storiesLocked(w, s.externalRefStories(StoryboardID, RefID), UserID) {
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "remove_story_external_ref", "external_ref", RefID)
+
+		goals, StoryID, err := s.database.RemoveStoryExternalRef(StoryboardID, UserID, RefID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyUpdatedMessage(StoryboardID, goals, StoryID)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, goals)
+	}
+}
+
+// handleStoryboardDependenciesGet handles getting the dependency graph of a storyboards stories,
+// including the stories on other storyboards they're linked to
+func (s *server) handleStoryboardDependenciesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		if _, err := s.database.GetStoryboardRevision(StoryboardID); err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		graph := s.database.GetStoryboardDependencies(StoryboardID)
+
+		s.respondWithJSON(w, http.StatusOK, graph)
+	}
+}
+
+// handleStoryboardExport handles exporting a storyboard as a versioned JSON document
+func (s *server) handleStoryboardExport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		export, err := s.database.ExportStoryboard(StoryboardID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="storyboard-%s.json"`, StoryboardID))
+		s.respondWithJSON(w, http.StatusOK, export)
+	}
+}
+
+// handleStoryboardImport handles recreating an exported storyboard under the user (and optionally a team)
+func (s *server) handleStoryboardImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+
+		var export database.StoryboardExport
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxImportSize)).Decode(&export); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		storyboard, err := s.database.ImportStoryboard(UserID, storyboardTeamID(r), &export)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s.audit(r, &database.AuditEntry{
+			Action:       "import_storyboard",
+			TargetType:   "storyboard",
+			TargetID:     storyboard.StoryboardID,
+			StoryboardID: storyboard.StoryboardID,
+			TeamID:       storyboardTeamID(r),
+			After:        map[string]string{"name": storyboard.StoryboardName},
+		})
+
+		s.respondWithJSON(w, http.StatusOK, storyboard)
+	}
+}
+
+// handleStoryboardClone handles duplicating a storyboard the user edits (or a template available to them)
+// under the user, optionally with its stories
+func (s *server) handleStoryboardClone() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			Name           string `json:"name"`
+			IncludeStories bool   `json:"includeStories"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// only the storyboards editors or users the storyboard is a template for can clone it
+		if s.database.ConfirmEditor(StoryboardID, UserID) != nil && s.database.ConfirmTemplateAccess(StoryboardID, UserID) != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		storyboard, err := s.database.CloneStoryboard(StoryboardID, UserID, "", rs.Name, rs.IncludeStories)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		s.audit(r, &database.AuditEntry{
+			Action:       "clone_storyboard",
+			TargetType:   "storyboard",
+			TargetID:     storyboard.StoryboardID,
+			StoryboardID: storyboard.StoryboardID,
+			After:        map[string]interface{}{"name": storyboard.StoryboardName, "source_id": StoryboardID, "include_stories": rs.IncludeStories},
+		})
+
+		s.respondWithJSON(w, http.StatusOK, storyboard)
+	}
+}
+
+// handleStoryboardTemplateUpdate handles the owner marking a storyboard as a
+// USER, TEAM or ORGANIZATION template, an empty scope unmarks it
+func (s *server) handleStoryboardTemplateUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			Scope   string `json:"scope"`
+			ScopeID string `json:"scopeId"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		e := s.storyboardAudit(StoryboardID, "set_storyboard_template", "storyboard", StoryboardID)
+		e.Before = map[string]string{"scope": "", "scope_id": ""}
+		for _, t := range s.database.GetStoryboardTemplatesByUser(UserID) {
+			if t.StoryboardID == StoryboardID {
+				e.Before = map[string]string{"scope": t.Scope, "scope_id": t.ScopeID}
+			}
+		}
+
+		if err := s.database.SetStoryboardTemplate(StoryboardID, UserID, rs.Scope, rs.ScopeID); err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		e.After = map[string]string{"scope": rs.Scope, "scope_id": rs.ScopeID}
+		s.audit(r, e)
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// handleStoryboardSnapshotsGet handles listing a storyboards snapshots, newest first
+func (s *server) handleStoryboardSnapshotsGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		snapshots := s.database.GetStoryboardSnapshots(StoryboardID)
+
+		s.respondWithJSON(w, http.StatusOK, snapshots)
+	}
+}
+
+// handleStoryboardSnapshotCreate handles taking a named snapshot of the storyboard as it is now
+func (s *server) handleStoryboardSnapshotCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		var rs struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		snapshot, err := s.database.CreateStoryboardSnapshot(StoryboardID, UserID, rs.Name)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		s.audit(r, &database.AuditEntry{
+			Action:       "create_snapshot",
+			TargetType:   "snapshot",
+			TargetID:     snapshot.SnapshotID,
+			StoryboardID: StoryboardID,
+			After:        map[string]interface{}{"name": snapshot.Name, "revision": snapshot.Revision},
+		})
+
+		s.respondWithJSON(w, http.StatusOK, snapshot)
+	}
+}
+
+// handleStoryboardSnapshotGet handles getting a snapshot with its contents
+func (s *server) handleStoryboardSnapshotGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		SnapshotID := vars["snapshotId"]
+
+		snapshot, err := s.database.GetStoryboardSnapshot(StoryboardID, SnapshotID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		s.respondWithJSON(w, http.StatusOK, snapshot)
+	}
+}
+
+// handleStoryboardSnapshotsDiff handles comparing two snapshots,
+// without a to snapshot the from snapshot is compared with the live storyboard
+func (s *server) handleStoryboardSnapshotsDiff() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		FromID := r.URL.Query().Get("from")
+		ToID := r.URL.Query().Get("to")
+
+		from, err := s.database.GetStoryboardSnapshot(StoryboardID, FromID)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		var to *database.StoryboardExport
+		if ToID == "" {
+			to, err = s.database.ExportStoryboard(StoryboardID)
+		} else {
+			var snapshot *database.StoryboardSnapshot
+			snapshot, err = s.database.GetStoryboardSnapshot(StoryboardID, ToID)
+			if err == nil {
+				to = snapshot.Storyboard
+			}
+		}
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		s.respondWithJSON(w, http.StatusOK, database.DiffStoryboards(from.Storyboard, to))
+	}
+}
+
+// handleStoryboardSnapshotRestore handles restoring a snapshot into the live storyboard,
+// or into a new storyboard owned by the user when asNew is set
+func (s *server) handleStoryboardSnapshotRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		SnapshotID := vars["snapshotId"]
+
+		var rs struct {
+			AsNew bool   `json:"asNew"`
+			Name  string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&rs); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if rs.AsNew {
+			storyboard, err := s.database.RestoreStoryboardSnapshotAsNew(StoryboardID, UserID, SnapshotID, rs.Name)
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+
+			s.audit(r, &database.AuditEntry{
+				Action:       "restore_snapshot_as_new",
+				TargetType:   "snapshot",
+				TargetID:     SnapshotID,
+				StoryboardID: storyboard.StoryboardID,
+				After:        map[string]string{"name": storyboard.StoryboardName, "source_id": StoryboardID},
+			})
+
+			s.respondWithJSON(w, http.StatusOK, storyboard)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		// the storyboard as it was is kept as the before value, the snapshot itself holds the after
+		e := s.storyboardAudit(StoryboardID, "restore_snapshot", "snapshot", SnapshotID)
+		if export, err := s.database.ExportStoryboard(StoryboardID); err == nil {
+			e.Before = export
+		}
+
+		storyboard, err := s.database.RestoreStoryboardSnapshot(StoryboardID, UserID, SnapshotID)
+		if err != nil {
+			w.WriteHeader(storyboardErrorStatus(err))
+			return
+		}
+
+		m := storyboardUpdatedMessage(StoryboardID, storyboard)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, storyboard)
+	}
+}
+
+// trashKinds maps the trash route kinds to the storyboard item kinds
+var trashKinds = map[string]string{
+	"goals":   "goal",
+	"columns": "column",
+	"stories": "story",
+}
+
+// handleStoryboardTrashGet handles listing the goals, columns and stories in a storyboards trash
+func (s *server) handleStoryboardTrashGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		items := s.database.GetStoryboardTrash(StoryboardID)
+
+		s.respondWithJSON(w, http.StatusOK, items)
+	}
+}
+
+// handleStoryboardTrashRestore handles restoring a goal, column or story from the storyboards trash
+func (s *server) handleStoryboardTrashRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+		ItemID := vars["itemId"]
+
+		Kind, ok := trashKinds[vars["kind"]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		unlock, ok := s.lockRevision(w, r, StoryboardID)
+		if !ok {
+			return
+		}
+		defer unlock()
+
+		e := s.storyboardAudit(StoryboardID, "restore_trash_item", Kind, ItemID)
+
+		storyboard, err := s.database.RestoreStoryboardTrashItem(StoryboardID, UserID, Kind, ItemID)
+		if err != nil {
+			switch err.Error() {
+			case "Incorrect permissions":
+				w.WriteHeader(http.StatusForbidden)
+			case "Trash item not found":
+				http.NotFound(w, r)
+			default:
+				// the goal or column it belongs to is still in the trash
+				w.WriteHeader(http.StatusConflict)
+			}
+			return
+		}
+
+		m := storyboardUpdatedMessage(StoryboardID, storyboard)
+		s.commitRevision(w, StoryboardID, &m)
+		h.broadcast <- m
+
+		e.After = goalTreeTarget(storyboard.Goals, Kind, ItemID)
+		s.audit(r, e)
+
+		s.respondWithJSON(w, http.StatusOK, storyboard)
+	}
+}
+
+// handleStoryboardRestore handles restoring a storyboard from its owners trash
+func (s *server) handleStoryboardRestore() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		UserID := r.Context().Value(contextKeyUserID).(string)
+		vars := mux.Vars(r)
+		StoryboardID := vars["id"]
+
+		storyboard, err := s.database.RestoreStoryboard(StoryboardID, UserID)
+		if err != nil {
+			if err.Error() == "Incorrect permissions" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			http.NotFound(w, r)
+			return
+		}
+
+		s.audit(r, &database.AuditEntry{
+			Action:       "restore_storyboard",
+			TargetType:   "storyboard",
+			TargetID:     StoryboardID,
+			StoryboardID: StoryboardID,
+			After:        map[string]string{"name": storyboard.StoryboardName},
+		})
+
+		s.respondWithJSON(w, http.StatusOK, storyboard)
+	}
+}
@@ -77,6 +77,10 @@ func (s *server) handleCreateTeam() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "create_team", TargetType: "team", TargetID: TeamID, TeamID: TeamID,
+			After: map[string]string{"name": TeamName},
+		})
 
 		var NewTeam = &CreateTeamResponse{
 			TeamID: TeamID,
@@ -107,6 +111,10 @@ func (s *server) handleTeamAddUser() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "team_add_user", TargetType: "user", TargetID: User.UserID,
+			After: map[string]string{"role": Role},
+		})
 
 		return
 	}
@@ -121,11 +129,16 @@ func (s *server) handleTeamRemoveUser() http.HandlerFunc {
 		TeamID := vars["teamId"]
 		UserID := keyVal["id"].(string)
 
+		Role, _ := s.database.TeamUserRole(UserID, TeamID)
 		err := s.database.TeamRemoveUser(TeamID, UserID)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "team_remove_user", TargetType: "user", TargetID: UserID,
+			Before: map[string]string{"role": Role},
+		})
 
 		return
 	}
@@ -159,6 +172,9 @@ func (s *server) handleTeamRemoveStoryboard() http.HandlerFunc {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, &database.AuditEntry{
+			Action: "team_remove_storyboard", TargetType: "storyboard", TargetID: StoryboardID, StoryboardID: StoryboardID,
+		})
 
 		return
 	}
@@ -170,12 +186,32 @@ func (s *server) handleDeleteTeam() http.HandlerFunc {
 		keyVal := s.getJSONRequestBody(r, w)
 		TeamID := keyVal["id"].(string)
 
+		// the team is looked up first so its name is kept in the audit log
+		Team, _ := s.database.TeamGet(TeamID)
+		e := &database.AuditEntry{Action: "delete_team", TargetType: "team", TargetID: TeamID, TeamID: TeamID}
+		if Team != nil {
+			e.Before = Team
+		}
+
 		err := s.database.TeamDelete(TeamID)
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, e)
 
 		return
 	}
 }
+
+// handleGetTeamStoryboardTemplates gets a list of the teams storyboard templates
+func (s *server) handleGetTeamStoryboardTemplates() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		TeamID := vars["teamId"]
+
+		templates := s.database.TeamStoryboardTemplateList(TeamID)
+
+		s.respondWithJSON(w, http.StatusOK, templates)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
 	"github.com/anthonynsimon/bild/transform"
 	"github.com/gorilla/mux"
 	"github.com/ipsn/go-adorable"
@@ -93,6 +94,14 @@ func (s *server) handleUserProfileUpdate() http.HandlerFunc {
 			return
 		}
 
+		// mention emails are left as they are when not sent
+		if MentionEmails, ok := keyVal["mentionEmails"].(bool); ok {
+			if err := s.database.UpdateUserMentionEmails(UserID, MentionEmails); err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+
 		return
 	}
 }
@@ -126,12 +135,18 @@ func (s *server) handleUserDelete() http.HandlerFunc {
 			return
 		}
 
+		e := &database.AuditEntry{Action: "delete_user", TargetType: "user", TargetID: UserID}
+		if User, err := s.database.GetUser(UserID); err == nil {
+			e.Before = map[string]string{"name": User.UserName, "email": User.UserEmail, "type": User.UserType}
+		}
+
 		updateErr := s.database.DeleteUser(UserID)
 		if updateErr != nil {
 			log.Println("error attempting to delete user : " + updateErr.Error() + "\n")
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
+		s.audit(r, e)
 
 		s.clearUserCookies(w)
 
@@ -1,14 +1,32 @@
 package main
 
+import (
+	"log"
+	"time"
+)
+
 type message struct {
-	data  []byte
+	// data is the event sent to protocol v1 connections
+	data []byte
+	// delta is the event sent to protocol v2 connections in place of data, when set
+	delta []byte
 	arena string
+	// conn restricts delivery to a single connection in the arena, when set
+	conn *connection
+	// remote messages were published by another instance and are only delivered locally
+	remote bool
 }
 
 type subscription struct {
 	conn   *connection
 	arena  string
 	userID string
+	// sessionID identifies this connections storyboard session
+	sessionID string
+	// resume replays the events a reconnecting client missed, when set
+	resume *resume
+	// registered receives the hubs registration reply, when set
+	registered chan registration
 }
 
 // hub maintains the set of active connections and broadcasts messages to the
@@ -17,6 +35,9 @@ type hub struct {
 	// Registered connections.
 	arenas map[string]map[*connection]bool
 
+	// Recent events per arena for reconnecting clients to replay.
+	histories map[string]*arenaHistory
+
 	// Inbound messages from the connections.
 	broadcast chan message
 
@@ -25,19 +46,60 @@ type hub struct {
 
 	// Unregister requests from connections.
 	unregister chan subscription
+
+	// Requests for the number of connections per arena.
+	presence chan chan map[string]int
+
+	// Focused stories per arena.
+	focus map[string]map[string]*arenaStoryFocus
+
+	// Story focus and blur requests from the connections.
+	focusChanges chan focusChange
+
+	// Requests for an arenas focused stories.
+	focusQueries chan focusQuery
+
+	// Requests to drop the focus relayed from stopped instances.
+	focusExpiries chan focusExpiry
+
+	// Outbound messages to publish to other instances, nil unless clustered.
+	publish chan message
 }
 
 var h = hub{
-	broadcast:  make(chan message),
-	register:   make(chan subscription),
-	unregister: make(chan subscription),
-	arenas:     make(map[string]map[*connection]bool),
+	broadcast:     make(chan message),
+	register:      make(chan subscription),
+	unregister:    make(chan subscription),
+	presence:      make(chan chan map[string]int),
+	focusChanges:  make(chan focusChange),
+	focusQueries:  make(chan focusQuery),
+	focusExpiries: make(chan focusExpiry),
+	arenas:        make(map[string]map[*connection]bool),
+	histories:     make(map[string]*arenaHistory),
+	focus:         make(map[string]map[string]*arenaStoryFocus),
+}
+
+// connectionCounts gets the number of local connections in each arena
+func (h *hub) connectionCounts() map[string]int {
+	counts := make(chan map[string]int)
+	h.presence <- counts
+
+	return <-counts
 }
 
 func (h *hub) run() {
+	pruneTicker := time.NewTicker(time.Minute)
+	defer pruneTicker.Stop()
+
 	for {
 		select {
 		case s := <-h.register:
+			// replay before joining the arena so missed events arrive ahead of new ones
+			reg := h.replay(s)
+			if s.registered != nil {
+				s.registered <- reg
+			}
+
 			connections := h.arenas[s.arena]
 			if connections == nil {
 				connections = make(map[*connection]bool)
@@ -55,11 +117,47 @@ func (h *hub) run() {
 					}
 				}
 			}
+		case counts := <-h.presence:
+			arenaCounts := make(map[string]int, len(h.arenas))
+			for arena, connections := range h.arenas {
+				arenaCounts[arena] = len(connections)
+			}
+			counts <- arenaCounts
+		case fc := <-h.focusChanges:
+			state := h.applyFocus(fc)
+			if fc.reply != nil {
+				fc.reply <- state
+			}
+		case fq := <-h.focusQueries:
+			fq.reply <- h.arenaFocus(fq.arena)
+		case fe := <-h.focusExpiries:
+			fe.reply <- h.expireFocus(fe.live)
+		case <-pruneTicker.C:
+			h.pruneHistories()
 		case m := <-h.broadcast:
+			if m.conn == nil {
+				h.record(&m)
+			}
+
+			if h.publish != nil && !m.remote && m.conn == nil {
+				select {
+				case h.publish <- m:
+				default:
+					log.Println("cluster publish queue full, dropping event for arena ", m.arena)
+				}
+			}
+
 			connections := h.arenas[m.arena]
 			for c := range connections {
+				if m.conn != nil && m.conn != c {
+					continue
+				}
+				data := m.data
+				if m.delta != nil && c.protocol >= protocolDelta {
+					data = m.delta
+				}
 				select {
-				case c.send <- m.data:
+				case c.send <- data:
 				default:
 					close(c.send)
 					delete(connections, c)
@@ -0,0 +1,41 @@
+package main
+
+import "github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
+
+// notifyLinkedStoryboards sends other storyboards their goal tree after their stories were linked to,
+// unlinked from or unblocked by a story on the storyboard
+func (s *server) notifyLinkedStoryboards(StoryboardID string, LinkedStoryboardIDs ...string) {
+	sent := map[string]bool{StoryboardID: true, "": true}
+	for _, LinkedStoryboardID := range LinkedStoryboardIDs {
+		if sent[LinkedStoryboardID] {
+			continue
+		}
+		sent[LinkedStoryboardID] = true
+
+		h.broadcast <- storyLinksMessage(LinkedStoryboardID, s.database.GetStoryboardGoals(LinkedStoryboardID))
+	}
+}
+
+// blockedStoryboards gets the storyboards of the stories a story blocks
+func blockedStoryboards(goals []*database.StoryboardGoal, StoryID string) []string {
+	var StoryboardIDs []string
+	if _, _, st := findStory(goals, StoryID); st != nil {
+		for _, l := range st.Links {
+			if l.Type == "blocks" && l.Direction == "outward" {
+				StoryboardIDs = append(StoryboardIDs, l.StoryboardID)
+			}
+		}
+	}
+
+	return StoryboardIDs
+}
+
+// storyClosedMessage creates the message for a story being closed or reopened, when it blocks other stories
+// their blocked flags change too so the full goal tree is sent for both protocols
+func storyClosedMessage(StoryboardID string, goals []*database.StoryboardGoal, StoryID string) message {
+	if len(blockedStoryboards(goals, StoryID)) > 0 {
+		return storyLinksMessage(StoryboardID, goals)
+	}
+
+	return storyUpdatedMessage(StoryboardID, goals, StoryID)
+}
@@ -1,8 +1,9 @@
 package main
 
 import (
-	_ "embed"
+	"embed"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
@@ -15,8 +16,8 @@ import (
 	"github.com/spf13/viper"
 )
 
-//go:embed schema.sql
-var schemaSQL string
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
 var embedUseOS bool
 var (
 	version = "dev"
@@ -63,6 +64,19 @@ func main() {
 
 	InitConfig()
 
+	migrationFiles, _ := fs.Sub(migrationsFS, "migrations")
+	migrations, err := database.LoadMigrations(migrationFiles)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], migrations); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	cookieHashkey := viper.GetString("http.cookie_hashkey")
 	pathPrefix := viper.GetString("http.path_prefix")
 	router := mux.NewRouter()
@@ -89,10 +103,26 @@ func main() {
 		cookie: securecookie.New([]byte(cookieHashkey), nil),
 	}
 	s.email = email.New(s.config.AppDomain, s.config.PathPrefix)
-	s.database = database.New(s.config.AdminEmail, schemaSQL)
+	s.database = database.New(s.config.AdminEmail, clusterNodeID, migrations)
+
+	// the cluster must be started before the hub which then publishes to it
+	if viper.GetBool("cluster.enabled") {
+		if err := startCluster(s.database); err != nil {
+			log.Fatal(err)
+		}
+	}
 
 	go h.run()
 
+	if interval := viper.GetInt("snapshot.interval_hours"); interval > 0 {
+		go s.takeAutomaticSnapshots(interval, viper.GetInt("snapshot.retention"))
+	}
+
+	if retention := viper.GetInt("trash.retention_days"); retention > 0 {
+		go s.purgeTrash(retention)
+	}
+	go s.sendMentionEmails()
+
 	s.routes()
 
 	srv := &http.Server{
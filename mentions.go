@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
+)
+
+// mentionMessage creates the story_mentioned message for the mentioned user,
+// clients only show it to the user in the events userId
+func mentionMessage(mention *database.StoryMention) message {
+	value, _ := json.Marshal(mention)
+
+	return message{
+		data:  CreateSocketEvent("story_mentioned", string(value), mention.UserID),
+		arena: mention.StoryboardID,
+	}
+}
+
+// notifyMentions sends the story_mentioned event for mentions of users on the storyboard,
+// the rest are emailed by sendMentionEmails
+func notifyMentions(mentions []*database.StoryMention) {
+	for _, mention := range mentions {
+		if !mention.EmailQueued {
+			h.broadcast <- mentionMessage(mention)
+		}
+	}
+}
+
+// sendMentionEmails emails the story comment mentions queued for users that weren't on the storyboard,
+// checking once a minute
+func (s *server) sendMentionEmails() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for _, e := range s.database.ClaimMentionEmails() {
+			s.email.SendMention(e.UserName, e.UserEmail, e.AuthorName, e.StoryboardID, e.StoryboardName, e.StoryID, e.StoryName, e.Comment)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/StevenWeathers/exothermic-story-mapping/pkg/database"
+)
+
+// migrateUsage describes the migrate command
+const migrateUsage = "usage: exothermic migrate up|down [steps]|status"
+
+// runMigrate handles the migrate command, applying, rolling back or listing db migrations
+func runMigrate(args []string, migrations []*database.Migration) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	d := database.Connect()
+
+	switch args[0] {
+	case "up":
+		count, err := d.MigrateUp(migrations)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", count)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			var err error
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+
+		count, err := d.MigrateDown(migrations, steps)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %d migration(s)\n", count)
+	case "status":
+		statuses, err := d.MigrationStatuses(migrations)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tNAME\tAPPLIED")
+		for _, m := range statuses {
+			applied := "pending"
+			if m.AppliedDate != nil {
+				applied = m.AppliedDate.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(tw, "%04d\t%s\t%s\n", m.Version, m.Name, applied)
+		}
+		tw.Flush()
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	return nil
+}
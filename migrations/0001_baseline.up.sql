@@ -1,3 +1,5 @@
+-- Baseline schema, formerly schema.sql which was run on every boot.
+-- Kept idempotent so databases created before versioned migrations can adopt it.
 --
 -- Extensions
 --
@@ -66,6 +68,17 @@ CREATE TABLE IF NOT EXISTS storyboard_user (
     PRIMARY KEY (storyboard_id, user_id)
 );
 
+CREATE TABLE IF NOT EXISTS storyboard_release (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    storyboard_id UUID NOT NULL,
+    name VARCHAR(256) NOT NULL,
+    sort_order INTEGER NOT NULL,
+    target_date DATE,
+    created_date TIMESTAMP DEFAULT NOW(),
+    updated_date TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT sr_storyboard_id FOREIGN KEY(storyboard_id) REFERENCES storyboard(id) ON DELETE CASCADE
+);
+
 CREATE TABLE IF NOT EXISTS user_reset (
     reset_id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
     user_id UUID REFERENCES users NOT NULL,
@@ -232,6 +245,11 @@ ALTER TABLE storyboard_story ADD COLUMN IF NOT EXISTS points INTEGER;
 ALTER TABLE storyboard_story ADD COLUMN IF NOT EXISTS closed BOOL DEFAULT false;
 ALTER TABLE storyboard_story ALTER COLUMN color SET DEFAULT 'gray';
 ALTER TABLE storyboard ADD COLUMN IF NOT EXISTS color_legend JSONB DEFAULT '[{"color":"gray","legend":""},{"color":"red","legend":""},{"color":"orange","legend":""},{"color":"yellow","legend":""},{"color":"green","legend":""},{"color":"teal","legend":""},{"color":"blue","legend":""},{"color":"indigo","legend":""},{"color":"purple","legend":""},{"color":"pink","legend":""}]'::JSONB;
+ALTER TABLE storyboard ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE storyboard_user ADD COLUMN IF NOT EXISTS role VARCHAR(16);
+ALTER TABLE storyboard_story ADD COLUMN IF NOT EXISTS release_id UUID REFERENCES storyboard_release(id) ON DELETE SET NULL;
+ALTER TABLE storyboard ADD COLUMN IF NOT EXISTS template_scope VARCHAR(16);
+ALTER TABLE storyboard ADD COLUMN IF NOT EXISTS template_scope_id UUID;
 
 DO $$
 BEGIN
@@ -349,6 +367,24 @@ BEGIN
 END;
 $$;
 
+-- Set a Storyboard Users Role, a NULL role reverts to the default --
+CREATE OR REPLACE PROCEDURE set_storyboard_user_role(storyboardId UUID, userId UUID, userRole VARCHAR(16))
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO storyboard_user (storyboard_id, user_id, role) VALUES (storyboardId, userId, userRole)
+    ON CONFLICT (storyboard_id, user_id) DO UPDATE SET role = userRole;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Set Storyboard Template Scope, a NULL scope unmarks the template --
+CREATE OR REPLACE PROCEDURE set_storyboard_template(storyboardId UUID, templateScope VARCHAR(16), scopeId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE storyboard SET updated_date = NOW(), template_scope = templateScope, template_scope_id = scopeId WHERE id = storyboardId;
+END;
+$$;
+
 -- Revise Storyboard ColorLegend --
 CREATE OR REPLACE PROCEDURE revise_color_legend(storyboardId UUID, colorLegend JSONB)
 LANGUAGE plpgsql AS $$
@@ -513,31 +549,19 @@ $$;
 CREATE OR REPLACE PROCEDURE move_story(storyId UUID, goalId UUID, columnId UUID, placeBefore TEXT)
 LANGUAGE plpgsql AS $$
 DECLARE storyboardId UUID;
-DECLARE srcGoalId UUID;
 DECLARE srcColumnId UUID;
 DECLARE srcSortOrder INTEGER;
-DECLARE storyName VARCHAR(256);
-DECLARE storyColor VARCHAR(32);
-DECLARE storyContent TEXT;
-DECLARE createdDate TIMESTAMP;
 DECLARE targetSortOrder INTEGER;
 BEGIN
     -- Get Story current details
-    SELECT 
-        storyboard_id, goal_id, column_id, sort_order, name, color, content, created_date
+    SELECT
+        storyboard_id, column_id, sort_order
     INTO
-        storyboardId, srcGoalId, srcColumnId, srcSortOrder, storyName, storyColor, storyContent, createdDate
+        storyboardId, srcColumnId, srcSortOrder
     FROM storyboard_story WHERE id = storyId;
 
-    -- Get target sort order
-    IF placeBefore = '' THEN
-        SELECT coalesce(max(sort_order), 0) + 1 INTO targetSortOrder FROM storyboard_story WHERE column_id = columnId;
-    ELSE
-        SELECT sort_order INTO targetSortOrder FROM storyboard_story WHERE column_id = columnId AND id = placeBefore::UUID;
-    END IF;
-
-    -- Remove from source column
-    DELETE FROM storyboard_story WHERE id = storyId;
+    -- Remove from source column ordering, keeping the story (and its comments) intact
+    UPDATE storyboard_story SET sort_order = NULL WHERE id = storyId;
     -- Update sort order in src column
     UPDATE storyboard_story ss SET sort_order = (t.sort_order - 1)
     FROM (
@@ -548,6 +572,13 @@ BEGIN
     ) AS t
     WHERE ss.id = t.id;
 
+    -- Get target sort order
+    IF placeBefore = '' THEN
+        SELECT coalesce(max(sort_order), 0) + 1 INTO targetSortOrder FROM storyboard_story WHERE column_id = columnId;
+    ELSE
+        SELECT sort_order INTO targetSortOrder FROM storyboard_story WHERE column_id = columnId AND id = placeBefore::UUID;
+    END IF;
+
     -- Update sort order for any story that should come after newly moved story
     UPDATE storyboard_story ss SET sort_order = (t.sort_order + 1)
     FROM (
@@ -558,14 +589,10 @@ BEGIN
     ) AS t
     WHERE ss.id = t.id;
 
-    -- Finally, insert new story in its ordered place
-    INSERT INTO
-        storyboard_story (
-            storyboard_id, goal_id, column_id, sort_order, name, color, content, created_date
-        )
-    VALUES (
-        storyBoardId, goalId, columnId, targetSortOrder, storyName, storyColor, storyContent, createdDate
-    );
+    -- Finally, place the story in its ordered place
+    UPDATE storyboard_story
+    SET goal_id = goalId, column_id = columnId, sort_order = targetSortOrder, updated_date = NOW()
+    WHERE id = storyId;
 
     UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
 
@@ -661,6 +688,92 @@ BEGIN
 END;
 $$;
 
+-- Add a Release to Storyboard --
+CREATE OR REPLACE PROCEDURE release_add(storyboardId UUID, releaseName VARCHAR(256), targetDate DATE)
+LANGUAGE plpgsql AS $$
+DECLARE sortOrder INTEGER;
+BEGIN
+    SELECT coalesce(max(sort_order), 0) + 1 INTO sortOrder FROM storyboard_release WHERE storyboard_id = storyboardId;
+    INSERT INTO storyboard_release (storyboard_id, name, sort_order, target_date) VALUES (storyboardId, releaseName, sortOrder, targetDate);
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+
+    COMMIT;
+END;
+$$;
+
+-- Edit a Storyboard Release --
+CREATE OR REPLACE PROCEDURE release_edit(storyboardId UUID, releaseId UUID, releaseName VARCHAR(256), targetDate DATE)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE storyboard_release SET name = releaseName, target_date = targetDate, updated_date = NOW()
+    WHERE id = releaseId AND storyboard_id = storyboardId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+
+    COMMIT;
+END;
+$$;
+
+-- Move a Storyboard Release before another release (or last) --
+CREATE OR REPLACE PROCEDURE release_move(storyboardId UUID, releaseId UUID, placeBefore TEXT)
+LANGUAGE plpgsql AS $$
+DECLARE targetSortOrder INTEGER;
+BEGIN
+    IF placeBefore = '' THEN
+        SELECT coalesce(max(sort_order), 0) + 1 INTO targetSortOrder FROM storyboard_release WHERE storyboard_id = storyboardId;
+    ELSE
+        SELECT sort_order INTO targetSortOrder FROM storyboard_release WHERE storyboard_id = storyboardId AND id = placeBefore::UUID;
+        UPDATE storyboard_release SET sort_order = sort_order + 1
+        WHERE storyboard_id = storyboardId AND sort_order >= targetSortOrder AND id != releaseId;
+    END IF;
+
+    UPDATE storyboard_release SET sort_order = targetSortOrder, updated_date = NOW()
+    WHERE id = releaseId AND storyboard_id = storyboardId;
+
+    -- Close any gaps left behind
+    UPDATE storyboard_release sr SET sort_order = t.new_order
+    FROM (
+        SELECT id, row_number() OVER (ORDER BY sort_order) AS new_order
+        FROM storyboard_release WHERE storyboard_id = storyboardId
+    ) AS t
+    WHERE sr.id = t.id;
+
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+
+    COMMIT;
+END;
+$$;
+
+-- Delete a Storyboard Release, its stories become unassigned --
+CREATE OR REPLACE PROCEDURE release_delete(storyboardId UUID, releaseId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE sortOrder INTEGER;
+BEGIN
+    SELECT sort_order INTO sortOrder FROM storyboard_release WHERE id = releaseId AND storyboard_id = storyboardId;
+    DELETE FROM storyboard_release WHERE id = releaseId AND storyboard_id = storyboardId;
+    UPDATE storyboard_release SET sort_order = (sort_order - 1) WHERE storyboard_id = storyboardId AND sort_order > sortOrder;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+
+    COMMIT;
+END;
+$$;
+
+-- Assign a Storyboard Story to a Release, a NULL release unassigns it --
+CREATE OR REPLACE PROCEDURE update_story_release(storyboardId UUID, storyId UUID, releaseId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF releaseId IS NOT NULL AND NOT EXISTS (
+        SELECT 1 FROM storyboard_release WHERE id = releaseId AND storyboard_id = storyboardId
+    ) THEN
+        RAISE EXCEPTION 'Release not found';
+    END IF;
+
+    UPDATE storyboard_story SET release_id = releaseId, updated_date = NOW() WHERE id = storyId AND storyboard_id = storyboardId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+
+    COMMIT;
+END;
+$$;
+
 -- Reset User Password --
 CREATE OR REPLACE PROCEDURE reset_user_password(resetId UUID, userPassword TEXT)
 LANGUAGE plpgsql AS $$
@@ -848,6 +961,40 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+-- Get Storyboard Templates available to a User by ID
+DROP FUNCTION IF EXISTS get_storyboard_templates_by_user(uuid);
+CREATE FUNCTION get_storyboard_templates_by_user(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), owner_id UUID, template_scope VARCHAR(16), template_scope_id UUID
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name, b.owner_id, b.template_scope, b.template_scope_id
+		FROM storyboard b
+		WHERE (b.template_scope = 'USER' AND b.owner_id = userId)
+		OR (b.template_scope = 'TEAM' AND b.template_scope_id IN (
+		    SELECT tu.team_id FROM team_user tu WHERE tu.user_id = userId
+		))
+		OR (b.template_scope = 'ORGANIZATION' AND b.template_scope_id IN (
+		    SELECT ou.organization_id FROM organization_user ou WHERE ou.user_id = userId
+		))
+		ORDER BY b.name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Templates for a Team or Organization by ID
+DROP FUNCTION IF EXISTS get_storyboard_templates_by_scope(varchar, uuid);
+CREATE FUNCTION get_storyboard_templates_by_scope(templateScope VARCHAR(16), scopeId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), owner_id UUID, template_scope VARCHAR(16), template_scope_id UUID
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name, b.owner_id, b.template_scope, b.template_scope_id
+		FROM storyboard b
+		WHERE b.template_scope = templateScope AND b.template_scope_id = scopeId
+		ORDER BY b.name;
+END;
+$$ LANGUAGE plpgsql;
+
 -- Get a Storyboards Goals --
 DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
 CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
@@ -897,15 +1044,40 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+-- Get a Users Role on a Storyboard, team members default to EDITOR --
+CREATE OR REPLACE FUNCTION storyboard_user_role(storyboardId UUID, userId UUID) RETURNS VARCHAR(16) AS $$
+DECLARE userRole VARCHAR(16);
+BEGIN
+    IF EXISTS (SELECT 1 FROM storyboard WHERE id = storyboardId AND owner_id = userId) THEN
+        RETURN 'OWNER';
+    END IF;
+
+    SELECT su.role INTO userRole FROM storyboard_user su WHERE su.storyboard_id = storyboardId AND su.user_id = userId;
+    IF userRole IS NOT NULL THEN
+        RETURN userRole;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM team_storyboard tb
+        JOIN team_user tu ON tu.team_id = tb.team_id
+        WHERE tb.storyboard_id = storyboardId AND tu.user_id = userId
+    ) THEN
+        RETURN 'EDITOR';
+    END IF;
+
+    RETURN 'VIEWER';
+END;
+$$ LANGUAGE plpgsql;
+
 -- Get Storyboard Users
 DROP FUNCTION IF EXISTS get_storyboard_users(uuid);
 CREATE FUNCTION get_storyboard_users(storyboardId UUID) RETURNS table (
-    id UUID, name VARCHAR(256), active BOOL
+    id UUID, name VARCHAR(256), active BOOL, role VARCHAR(16)
 ) AS $$
 BEGIN
     RETURN QUERY
         SELECT
-			w.id, w.name, su.active
+			w.id, w.name, su.active, storyboard_user_role(storyboardId, w.id)
 		FROM storyboard_user su
 		LEFT JOIN users w ON su.user_id = w.id
 		WHERE su.storyboard_id = storyboardId
@@ -930,6 +1102,29 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+-- Get Storyboard Releases with their point totals
+DROP FUNCTION IF EXISTS get_storyboard_releases(uuid);
+CREATE FUNCTION get_storyboard_releases(storyboardId UUID) RETURNS table (
+    id UUID,
+    name VARCHAR(256),
+    sort_order INTEGER,
+    target_date TEXT,
+    points BIGINT,
+    story_count BIGINT
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+			sr.id, sr.name, sr.sort_order, coalesce(to_char(sr.target_date, 'YYYY-MM-DD'), ''),
+			coalesce(sum(ss.points), 0), count(ss.id)
+		FROM storyboard_release sr
+		LEFT JOIN storyboard_story ss ON ss.release_id = sr.id
+		WHERE sr.storyboard_id = storyboardId
+		GROUP BY sr.id
+		ORDER BY sr.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
 -- Get Storyboard User by id
 DROP FUNCTION IF EXISTS get_storyboard_user(uuid, uuid);
 CREATE FUNCTION get_storyboard_user(storyboardId UUID, userId UUID) RETURNS table (
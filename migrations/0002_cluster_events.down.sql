@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS cluster_presence;
+DROP TABLE IF EXISTS socket_event;
@@ -0,0 +1,15 @@
+-- Socket events too large for a NOTIFY payload, referenced by id in the notification
+CREATE TABLE IF NOT EXISTS socket_event (
+    id BIGSERIAL PRIMARY KEY,
+    payload TEXT NOT NULL,
+    created_date TIMESTAMP DEFAULT NOW()
+);
+
+-- Active connections per storyboard on each instance, refreshed by a heartbeat
+CREATE TABLE IF NOT EXISTS cluster_presence (
+    node_id VARCHAR(64) NOT NULL,
+    storyboard_id UUID NOT NULL,
+    connections INTEGER NOT NULL DEFAULT 0,
+    updated_date TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (node_id, storyboard_id)
+);
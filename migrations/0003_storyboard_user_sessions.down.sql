@@ -0,0 +1,25 @@
+DROP FUNCTION IF EXISTS get_storyboard_users(uuid);
+CREATE FUNCTION get_storyboard_users(storyboardId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), active BOOL, role VARCHAR(16)
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+			w.id, w.name, su.active, storyboard_user_role(storyboardId, w.id)
+		FROM storyboard_user su
+		LEFT JOIN users w ON su.user_id = w.id
+		WHERE su.storyboard_id = storyboardId
+		ORDER BY w.name;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE PROCEDURE deactivate_all_users()
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE storyboard_user SET active = false WHERE active = true;
+END;
+$$;
+
+DROP PROCEDURE IF EXISTS storyboard_user_session_remove(uuid, uuid, uuid, bool);
+DROP FUNCTION IF EXISTS storyboard_user_session_add(uuid, uuid);
+DROP TABLE IF EXISTS storyboard_user_session;
@@ -0,0 +1,74 @@
+-- Storyboard presence per socket connection, a user stays active until their last session closes
+CREATE TABLE IF NOT EXISTS storyboard_user_session (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    storyboard_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    created_date TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT sus_storyboard_user FOREIGN KEY(storyboard_id, user_id) REFERENCES storyboard_user(storyboard_id, user_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS sus_storyboard_user_idx ON storyboard_user_session (storyboard_id, user_id);
+
+-- Reset All Users to Inactive, used by server restart --
+CREATE OR REPLACE PROCEDURE deactivate_all_users()
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM storyboard_user_session;
+    UPDATE storyboard_user SET active = false WHERE active = true;
+END;
+$$;
+
+-- Add a session for the user to the storyboard, returning the session id --
+CREATE OR REPLACE FUNCTION storyboard_user_session_add(storyboardId UUID, userId UUID) RETURNS UUID AS $$
+DECLARE sessionId UUID;
+BEGIN
+    INSERT INTO storyboard_user (storyboard_id, user_id, active)
+        VALUES (storyboardId, userId, true)
+        ON CONFLICT (storyboard_id, user_id) DO UPDATE SET active = true, abandoned = false;
+
+    INSERT INTO storyboard_user_session (storyboard_id, user_id)
+        VALUES (storyboardId, userId)
+        RETURNING id INTO sessionId;
+
+    RETURN sessionId;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Remove a users storyboard session, the user stays active while other sessions remain --
+CREATE OR REPLACE PROCEDURE storyboard_user_session_remove(sessionId UUID, storyboardId UUID, userId UUID, isAbandoned BOOL)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM storyboard_user_session WHERE id = sessionId;
+
+    UPDATE storyboard_user su
+        SET active = EXISTS (
+                SELECT 1 FROM storyboard_user_session sus
+                WHERE sus.storyboard_id = storyboardId AND sus.user_id = userId
+            ),
+            abandoned = su.abandoned OR isAbandoned
+        WHERE su.storyboard_id = storyboardId AND su.user_id = userId;
+
+    UPDATE users SET last_active = NOW() WHERE id = userId;
+
+    COMMIT;
+END;
+$$;
+
+-- Get Storyboard Users
+DROP FUNCTION IF EXISTS get_storyboard_users(uuid);
+CREATE FUNCTION get_storyboard_users(storyboardId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), active BOOL, role VARCHAR(16), connections INTEGER
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+			w.id, w.name, su.active, storyboard_user_role(storyboardId, w.id),
+			(
+			    SELECT COUNT(*)::INTEGER FROM storyboard_user_session sus
+			    WHERE sus.storyboard_id = storyboardId AND sus.user_id = w.id
+			)
+		FROM storyboard_user su
+		LEFT JOIN users w ON su.user_id = w.id
+		WHERE su.storyboard_id = storyboardId
+		ORDER BY w.name;
+END;
+$$ LANGUAGE plpgsql;
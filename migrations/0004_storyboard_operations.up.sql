@@ -0,0 +1,14 @@
+-- Undoable storyboard mutations per user, states hold the affected goal, column or story before and after
+CREATE TABLE IF NOT EXISTS storyboard_operation (
+    id BIGSERIAL PRIMARY KEY,
+    storyboard_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    before_state JSONB NOT NULL,
+    after_state JSONB NOT NULL,
+    undone BOOL NOT NULL DEFAULT false,
+    created_date TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT so_storyboard_id FOREIGN KEY(storyboard_id) REFERENCES storyboard(id) ON DELETE CASCADE,
+    CONSTRAINT so_user_id FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS so_storyboard_user_idx ON storyboard_operation (storyboard_id, user_id, id);
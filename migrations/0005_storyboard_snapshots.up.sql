@@ -0,0 +1,14 @@
+-- Point in time copies of a storyboard, stored as a storyboard export document
+CREATE TABLE IF NOT EXISTS storyboard_snapshot (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    storyboard_id UUID NOT NULL,
+    name VARCHAR(256),
+    automatic BOOL NOT NULL DEFAULT false,
+    created_by UUID,
+    revision INTEGER NOT NULL DEFAULT 0,
+    snapshot JSONB NOT NULL,
+    created_date TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT ss_storyboard_id FOREIGN KEY(storyboard_id) REFERENCES storyboard(id) ON DELETE CASCADE,
+    CONSTRAINT ss_created_by FOREIGN KEY(created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS ss_storyboard_created_idx ON storyboard_snapshot (storyboard_id, created_date);
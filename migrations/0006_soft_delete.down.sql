@@ -0,0 +1,236 @@
+-- Purge everything in the trash before dropping the soft delete columns
+DELETE FROM storyboard WHERE deleted_date IS NOT NULL;
+DELETE FROM storyboard_story WHERE deleted_date IS NOT NULL;
+DELETE FROM storyboard_column WHERE deleted_date IS NOT NULL;
+DELETE FROM storyboard_goal WHERE deleted_date IS NOT NULL;
+
+DROP PROCEDURE IF EXISTS purge_deleted(integer);
+DROP PROCEDURE IF EXISTS restore_storyboard_story(uuid);
+DROP PROCEDURE IF EXISTS restore_storyboard_column(uuid);
+DROP PROCEDURE IF EXISTS restore_storyboard_goal(uuid);
+DROP PROCEDURE IF EXISTS restore_storyboard(uuid);
+DROP FUNCTION IF EXISTS get_deleted_storyboards_by_user(uuid);
+DROP PROCEDURE IF EXISTS delete_storyboard_story(uuid, uuid);
+DROP PROCEDURE IF EXISTS delete_storyboard_column(uuid, uuid);
+DROP PROCEDURE IF EXISTS delete_storyboard_goal(uuid, uuid);
+DROP PROCEDURE IF EXISTS delete_storyboard(uuid, uuid);
+
+-- Delete Storyboard --
+CREATE OR REPLACE PROCEDURE delete_storyboard(storyboardId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM storyboard WHERE id = storyboardId;
+
+    COMMIT;
+END;
+$$;
+
+-- Delete a Storyboard Goal --
+CREATE OR REPLACE PROCEDURE delete_storyboard_goal(goalId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE storyboardId UUID;
+DECLARE sortOrder INTEGER;
+BEGIN
+    SELECT sort_order, storyboard_id INTO sortOrder, storyboardId FROM storyboard_goal WHERE id = goalId;
+
+    DELETE FROM storyboard_story WHERE goal_id = goalId;
+    DELETE FROM storyboard_column WHERE goal_id = goalId;
+    DELETE FROM storyboard_goal WHERE id = goalId;
+    UPDATE storyboard_goal sg SET sort_order = (sg.sort_order - 1) WHERE sg.storyboard_id = storyBoardId AND sg.sort_order > sortOrder;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+    
+    COMMIT;
+END;
+$$;
+
+-- Delete a Storyboard Column --
+CREATE OR REPLACE PROCEDURE delete_storyboard_column(columnId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE goalId UUID;
+DECLARE sortOrder INTEGER;
+DECLARE storyboardId UUID;
+BEGIN
+    SELECT goal_id, sort_order INTO goalId, sortOrder FROM storyboard_column WHERE id = columnId;
+
+    DELETE FROM storyboard_story WHERE column_id = columnId;
+    DELETE FROM storyboard_column WHERE id = columnId RETURNING storyboard_id INTO storyboardId;
+    UPDATE storyboard_column sc SET sort_order = (sc.sort_order - 1) WHERE sc.goal_id = goalId AND sc.sort_order > sortOrder;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+    
+    COMMIT;
+END;
+$$;
+
+-- Delete a Storyboard Story --
+CREATE OR REPLACE PROCEDURE delete_storyboard_story(storyId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE columnId UUID;
+DECLARE sortOrder INTEGER;
+DECLARE storyboardId UUID;
+BEGIN
+    SELECT column_id, sort_order, storyboard_id INTO columnId, sortOrder, storyboardId FROM storyboard_story WHERE id = storyId;
+    DELETE FROM storyboard_story WHERE id = storyId;
+    UPDATE storyboard_story ss SET sort_order = (ss.sort_order - 1) WHERE ss.column_id = columnId AND ss.sort_order > sortOrder;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+    
+    COMMIT;
+END;
+$$;
+
+-- Get Storyboards by User ID
+DROP FUNCTION IF EXISTS get_storyboards_by_user(uuid);
+CREATE FUNCTION get_storyboards_by_user(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), owner_id UUID
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name, b.owner_id
+		FROM storyboard b
+		LEFT JOIN storyboard_user su ON b.id = su.storyboard_id WHERE su.user_id = userId AND su.abandoned = false
+		GROUP BY b.id ORDER BY b.created_date DESC;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Templates available to a User by ID
+DROP FUNCTION IF EXISTS get_storyboard_templates_by_user(uuid);
+CREATE FUNCTION get_storyboard_templates_by_user(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), owner_id UUID, template_scope VARCHAR(16), template_scope_id UUID
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name, b.owner_id, b.template_scope, b.template_scope_id
+		FROM storyboard b
+		WHERE (b.template_scope = 'USER' AND b.owner_id = userId)
+		OR (b.template_scope = 'TEAM' AND b.template_scope_id IN (
+		    SELECT tu.team_id FROM team_user tu WHERE tu.user_id = userId
+		))
+		OR (b.template_scope = 'ORGANIZATION' AND b.template_scope_id IN (
+		    SELECT ou.organization_id FROM organization_user ou WHERE ou.user_id = userId
+		))
+		ORDER BY b.name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Templates for a Team or Organization by ID
+DROP FUNCTION IF EXISTS get_storyboard_templates_by_scope(varchar, uuid);
+CREATE FUNCTION get_storyboard_templates_by_scope(templateScope VARCHAR(16), scopeId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), owner_id UUID, template_scope VARCHAR(16), template_scope_id UUID
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name, b.owner_id, b.template_scope, b.template_scope_id
+		FROM storyboard b
+		WHERE b.template_scope = templateScope AND b.template_scope_id = scopeId
+		ORDER BY b.name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get a Storyboards Goals --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns           
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments
+                FROM storyboard_story ss
+                LEFT JOIN story_comment stcm ON stcm.story_id = ss.id
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Releases with their point totals
+DROP FUNCTION IF EXISTS get_storyboard_releases(uuid);
+CREATE FUNCTION get_storyboard_releases(storyboardId UUID) RETURNS table (
+    id UUID,
+    name VARCHAR(256),
+    sort_order INTEGER,
+    target_date TEXT,
+    points BIGINT,
+    story_count BIGINT
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+			sr.id, sr.name, sr.sort_order, coalesce(to_char(sr.target_date, 'YYYY-MM-DD'), ''),
+			coalesce(sum(ss.points), 0), count(ss.id)
+		FROM storyboard_release sr
+		LEFT JOIN storyboard_story ss ON ss.release_id = sr.id
+		WHERE sr.storyboard_id = storyboardId
+		GROUP BY sr.id
+		ORDER BY sr.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION get_app_stats(
+    OUT unregistered_user_count INTEGER,
+    OUT registered_user_count INTEGER,
+    OUT storyboard_count INTEGER,
+    OUT organization_count INTEGER,
+    OUT department_count INTEGER,
+    OUT team_count INTEGER,
+    OUT apikey_count INTEGER
+) AS $$
+BEGIN
+    SELECT COUNT(*) INTO unregistered_user_count FROM users WHERE email IS NULL;
+    SELECT COUNT(*) INTO registered_user_count FROM users WHERE email IS NOT NULL;
+    SELECT COUNT(*) INTO storyboard_count FROM storyboard;
+    SELECT COUNT(*) INTO organization_count FROM organization;
+    SELECT COUNT(*) INTO department_count FROM organization_department;
+    SELECT COUNT(*) INTO team_count FROM team;
+    SELECT COUNT(*) INTO apikey_count FROM api_keys;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Team Storyboards --
+CREATE OR REPLACE FUNCTION team_storyboard_list(
+    IN teamId UUID,
+    IN l_limit INTEGER,
+    IN l_offset INTEGER
+) RETURNS table (
+    id UUID, name VARCHAR(256)
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name
+        FROM team_storyboard tb
+        LEFT JOIN storyboard b ON tb.storyboard_id = b.id
+        WHERE tb.team_id = teamId
+        ORDER BY tb.created_date
+		LIMIT l_limit
+		OFFSET l_offset;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS sst_deleted_date_idx;
+DROP INDEX IF EXISTS sc_deleted_date_idx;
+DROP INDEX IF EXISTS sg_deleted_date_idx;
+DROP INDEX IF EXISTS sb_deleted_date_idx;
+ALTER TABLE storyboard_story DROP COLUMN IF EXISTS deleted_sort_order, DROP COLUMN IF EXISTS deleted_by, DROP COLUMN IF EXISTS deleted_date;
+ALTER TABLE storyboard_column DROP COLUMN IF EXISTS deleted_sort_order, DROP COLUMN IF EXISTS deleted_by, DROP COLUMN IF EXISTS deleted_date;
+ALTER TABLE storyboard_goal DROP COLUMN IF EXISTS deleted_sort_order, DROP COLUMN IF EXISTS deleted_by, DROP COLUMN IF EXISTS deleted_date;
+ALTER TABLE storyboard DROP COLUMN IF EXISTS deleted_by, DROP COLUMN IF EXISTS deleted_date;
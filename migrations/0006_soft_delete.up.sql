@@ -0,0 +1,385 @@
+-- Deleted storyboards, goals, columns and stories stay in the trash until restored or purged,
+-- items deleted along with their parent share its deleted_date
+ALTER TABLE storyboard ADD COLUMN IF NOT EXISTS deleted_date TIMESTAMP;
+ALTER TABLE storyboard ADD COLUMN IF NOT EXISTS deleted_by UUID;
+ALTER TABLE storyboard ADD CONSTRAINT sb_deleted_by FOREIGN KEY(deleted_by) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE storyboard_goal ADD COLUMN IF NOT EXISTS deleted_date TIMESTAMP;
+ALTER TABLE storyboard_goal ADD COLUMN IF NOT EXISTS deleted_by UUID;
+ALTER TABLE storyboard_goal ADD COLUMN IF NOT EXISTS deleted_sort_order INTEGER;
+ALTER TABLE storyboard_goal ADD CONSTRAINT sg_deleted_by FOREIGN KEY(deleted_by) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE storyboard_column ADD COLUMN IF NOT EXISTS deleted_date TIMESTAMP;
+ALTER TABLE storyboard_column ADD COLUMN IF NOT EXISTS deleted_by UUID;
+ALTER TABLE storyboard_column ADD COLUMN IF NOT EXISTS deleted_sort_order INTEGER;
+ALTER TABLE storyboard_column ADD CONSTRAINT sc_deleted_by FOREIGN KEY(deleted_by) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE storyboard_story ADD COLUMN IF NOT EXISTS deleted_date TIMESTAMP;
+ALTER TABLE storyboard_story ADD COLUMN IF NOT EXISTS deleted_by UUID;
+ALTER TABLE storyboard_story ADD COLUMN IF NOT EXISTS deleted_sort_order INTEGER;
+ALTER TABLE storyboard_story ADD CONSTRAINT sst_deleted_by FOREIGN KEY(deleted_by) REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS sb_deleted_date_idx ON storyboard (deleted_date) WHERE deleted_date IS NOT NULL;
+CREATE INDEX IF NOT EXISTS sg_deleted_date_idx ON storyboard_goal (storyboard_id, deleted_date) WHERE deleted_date IS NOT NULL;
+CREATE INDEX IF NOT EXISTS sc_deleted_date_idx ON storyboard_column (storyboard_id, deleted_date) WHERE deleted_date IS NOT NULL;
+CREATE INDEX IF NOT EXISTS sst_deleted_date_idx ON storyboard_story (storyboard_id, deleted_date) WHERE deleted_date IS NOT NULL;
+
+-- Delete Storyboard, moving it to its owners trash --
+DROP PROCEDURE IF EXISTS delete_storyboard(uuid);
+CREATE PROCEDURE delete_storyboard(storyboardId UUID, userId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE storyboard SET deleted_date = NOW(), deleted_by = userId
+    WHERE id = storyboardId AND deleted_date IS NULL;
+END;
+$$;
+
+-- Restore a Storyboard from the trash --
+CREATE OR REPLACE PROCEDURE restore_storyboard(storyboardId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE storyboard SET deleted_date = NULL, deleted_by = NULL, updated_date = NOW()
+    WHERE id = storyboardId AND deleted_date IS NOT NULL;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Storyboard not found in trash';
+    END IF;
+END;
+$$;
+
+-- Delete a Storyboard Goal, moving it with its columns and stories to the trash --
+DROP PROCEDURE IF EXISTS delete_storyboard_goal(uuid);
+CREATE PROCEDURE delete_storyboard_goal(goalId UUID, userId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE storyboardId UUID;
+DECLARE sortOrder INTEGER;
+BEGIN
+    SELECT sort_order, storyboard_id INTO sortOrder, storyboardId FROM storyboard_goal
+    WHERE id = goalId AND deleted_date IS NULL;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    UPDATE storyboard_story SET deleted_date = NOW(), deleted_by = userId WHERE goal_id = goalId AND deleted_date IS NULL;
+    UPDATE storyboard_column SET deleted_date = NOW(), deleted_by = userId WHERE goal_id = goalId AND deleted_date IS NULL;
+    UPDATE storyboard_goal
+    SET deleted_date = NOW(), deleted_by = userId, deleted_sort_order = sort_order, sort_order = NULL
+    WHERE id = goalId;
+    UPDATE storyboard_goal sg SET sort_order = -(sg.sort_order - 1) WHERE sg.storyboard_id = storyboardId AND sg.sort_order > sortOrder;
+    UPDATE storyboard_goal sg SET sort_order = -sg.sort_order WHERE sg.storyboard_id = storyboardId AND sg.sort_order < 0;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Restore a Storyboard Goal with the columns and stories deleted along with it --
+CREATE OR REPLACE PROCEDURE restore_storyboard_goal(goalId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE storyboardId UUID;
+DECLARE deletedDate TIMESTAMP;
+DECLARE sortOrder INTEGER;
+DECLARE maxSortOrder INTEGER;
+BEGIN
+    SELECT storyboard_id, deleted_date, deleted_sort_order INTO storyboardId, deletedDate, sortOrder
+    FROM storyboard_goal WHERE id = goalId AND deleted_date IS NOT NULL;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Goal not found in trash';
+    END IF;
+
+    SELECT coalesce(MAX(sort_order), 0) + 1 INTO maxSortOrder FROM storyboard_goal WHERE storyboard_id = storyboardId;
+    sortOrder := LEAST(GREATEST(coalesce(sortOrder, maxSortOrder), 1), maxSortOrder);
+
+    UPDATE storyboard_goal sg SET sort_order = -(sg.sort_order + 1) WHERE sg.storyboard_id = storyboardId AND sg.sort_order >= sortOrder;
+    UPDATE storyboard_goal sg SET sort_order = -sg.sort_order WHERE sg.storyboard_id = storyboardId AND sg.sort_order < 0;
+    UPDATE storyboard_goal
+    SET deleted_date = NULL, deleted_by = NULL, deleted_sort_order = NULL, sort_order = sortOrder, updated_date = NOW()
+    WHERE id = goalId;
+    UPDATE storyboard_column SET deleted_date = NULL, deleted_by = NULL WHERE goal_id = goalId AND deleted_date = deletedDate;
+    UPDATE storyboard_story SET deleted_date = NULL, deleted_by = NULL WHERE goal_id = goalId AND deleted_date = deletedDate;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Delete a Storyboard Column, moving it with its stories to the trash --
+DROP PROCEDURE IF EXISTS delete_storyboard_column(uuid);
+CREATE PROCEDURE delete_storyboard_column(columnId UUID, userId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE goalId UUID;
+DECLARE sortOrder INTEGER;
+DECLARE storyboardId UUID;
+BEGIN
+    SELECT goal_id, sort_order, storyboard_id INTO goalId, sortOrder, storyboardId FROM storyboard_column
+    WHERE id = columnId AND deleted_date IS NULL;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    UPDATE storyboard_story SET deleted_date = NOW(), deleted_by = userId WHERE column_id = columnId AND deleted_date IS NULL;
+    UPDATE storyboard_column
+    SET deleted_date = NOW(), deleted_by = userId, deleted_sort_order = sort_order, sort_order = NULL
+    WHERE id = columnId;
+    UPDATE storyboard_column sc SET sort_order = -(sc.sort_order - 1) WHERE sc.goal_id = goalId AND sc.sort_order > sortOrder;
+    UPDATE storyboard_column sc SET sort_order = -sc.sort_order WHERE sc.goal_id = goalId AND sc.sort_order < 0;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Restore a Storyboard Column with the stories deleted along with it --
+CREATE OR REPLACE PROCEDURE restore_storyboard_column(columnId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE goalId UUID;
+DECLARE storyboardId UUID;
+DECLARE deletedDate TIMESTAMP;
+DECLARE sortOrder INTEGER;
+DECLARE maxSortOrder INTEGER;
+BEGIN
+    SELECT goal_id, storyboard_id, deleted_date, deleted_sort_order INTO goalId, storyboardId, deletedDate, sortOrder
+    FROM storyboard_column WHERE id = columnId AND deleted_date IS NOT NULL;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Column not found in trash';
+    END IF;
+    IF EXISTS (SELECT 1 FROM storyboard_goal WHERE id = goalId AND deleted_date IS NOT NULL) THEN
+        RAISE EXCEPTION 'Column goal is deleted, restore the goal first';
+    END IF;
+
+    SELECT coalesce(MAX(sort_order), 0) + 1 INTO maxSortOrder FROM storyboard_column WHERE goal_id = goalId;
+    sortOrder := LEAST(GREATEST(coalesce(sortOrder, maxSortOrder), 1), maxSortOrder);
+
+    UPDATE storyboard_column sc SET sort_order = -(sc.sort_order + 1) WHERE sc.goal_id = goalId AND sc.sort_order >= sortOrder;
+    UPDATE storyboard_column sc SET sort_order = -sc.sort_order WHERE sc.goal_id = goalId AND sc.sort_order < 0;
+    UPDATE storyboard_column
+    SET deleted_date = NULL, deleted_by = NULL, deleted_sort_order = NULL, sort_order = sortOrder, updated_date = NOW()
+    WHERE id = columnId;
+    UPDATE storyboard_story SET deleted_date = NULL, deleted_by = NULL WHERE column_id = columnId AND deleted_date = deletedDate;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Delete a Storyboard Story, moving it to the trash --
+DROP PROCEDURE IF EXISTS delete_storyboard_story(uuid);
+CREATE PROCEDURE delete_storyboard_story(storyId UUID, userId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE columnId UUID;
+DECLARE sortOrder INTEGER;
+DECLARE storyboardId UUID;
+BEGIN
+    SELECT column_id, sort_order, storyboard_id INTO columnId, sortOrder, storyboardId FROM storyboard_story
+    WHERE id = storyId AND deleted_date IS NULL;
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    UPDATE storyboard_story
+    SET deleted_date = NOW(), deleted_by = userId, deleted_sort_order = sort_order, sort_order = NULL
+    WHERE id = storyId;
+    UPDATE storyboard_story ss SET sort_order = -(ss.sort_order - 1) WHERE ss.column_id = columnId AND ss.sort_order > sortOrder;
+    UPDATE storyboard_story ss SET sort_order = -ss.sort_order WHERE ss.column_id = columnId AND ss.sort_order < 0;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Restore a Storyboard Story from the trash --
+CREATE OR REPLACE PROCEDURE restore_storyboard_story(storyId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE columnId UUID;
+DECLARE storyboardId UUID;
+DECLARE sortOrder INTEGER;
+DECLARE maxSortOrder INTEGER;
+BEGIN
+    SELECT column_id, storyboard_id, deleted_sort_order INTO columnId, storyboardId, sortOrder
+    FROM storyboard_story WHERE id = storyId AND deleted_date IS NOT NULL;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Story not found in trash';
+    END IF;
+    IF EXISTS (SELECT 1 FROM storyboard_column WHERE id = columnId AND deleted_date IS NOT NULL) THEN
+        RAISE EXCEPTION 'Story column is deleted, restore the column first';
+    END IF;
+
+    SELECT coalesce(MAX(sort_order), 0) + 1 INTO maxSortOrder FROM storyboard_story WHERE column_id = columnId;
+    sortOrder := LEAST(GREATEST(coalesce(sortOrder, maxSortOrder), 1), maxSortOrder);
+
+    UPDATE storyboard_story ss SET sort_order = -(ss.sort_order + 1) WHERE ss.column_id = columnId AND ss.sort_order >= sortOrder;
+    UPDATE storyboard_story ss SET sort_order = -ss.sort_order WHERE ss.column_id = columnId AND ss.sort_order < 0;
+    UPDATE storyboard_story
+    SET deleted_date = NULL, deleted_by = NULL, deleted_sort_order = NULL, sort_order = sortOrder, updated_date = NOW()
+    WHERE id = storyId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Purge storyboards, goals, columns and stories in the trash for more than X Days --
+CREATE OR REPLACE PROCEDURE purge_deleted(daysOld INTEGER)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM storyboard WHERE deleted_date < (NOW() - daysOld * interval '1 day');
+    DELETE FROM storyboard_story WHERE deleted_date < (NOW() - daysOld * interval '1 day');
+    DELETE FROM storyboard_column WHERE deleted_date < (NOW() - daysOld * interval '1 day');
+    DELETE FROM storyboard_goal WHERE deleted_date < (NOW() - daysOld * interval '1 day');
+END;
+$$;
+
+-- Get Storyboards by User ID
+DROP FUNCTION IF EXISTS get_storyboards_by_user(uuid);
+CREATE FUNCTION get_storyboards_by_user(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), owner_id UUID
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name, b.owner_id
+		FROM storyboard b
+		LEFT JOIN storyboard_user su ON b.id = su.storyboard_id WHERE su.user_id = userId AND su.abandoned = false
+		AND b.deleted_date IS NULL
+		GROUP BY b.id ORDER BY b.created_date DESC;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Templates available to a User by ID
+DROP FUNCTION IF EXISTS get_storyboard_templates_by_user(uuid);
+CREATE FUNCTION get_storyboard_templates_by_user(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), owner_id UUID, template_scope VARCHAR(16), template_scope_id UUID
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name, b.owner_id, b.template_scope, b.template_scope_id
+		FROM storyboard b
+		WHERE b.deleted_date IS NULL AND (
+		    (b.template_scope = 'USER' AND b.owner_id = userId)
+		    OR (b.template_scope = 'TEAM' AND b.template_scope_id IN (
+		        SELECT tu.team_id FROM team_user tu WHERE tu.user_id = userId
+		    ))
+		    OR (b.template_scope = 'ORGANIZATION' AND b.template_scope_id IN (
+		        SELECT ou.organization_id FROM organization_user ou WHERE ou.user_id = userId
+		    ))
+		)
+		ORDER BY b.name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Templates for a Team or Organization by ID
+DROP FUNCTION IF EXISTS get_storyboard_templates_by_scope(varchar, uuid);
+CREATE FUNCTION get_storyboard_templates_by_scope(templateScope VARCHAR(16), scopeId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), owner_id UUID, template_scope VARCHAR(16), template_scope_id UUID
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name, b.owner_id, b.template_scope, b.template_scope_id
+		FROM storyboard b
+		WHERE b.template_scope = templateScope AND b.template_scope_id = scopeId AND b.deleted_date IS NULL
+		ORDER BY b.name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Deleted Storyboards owned by a User --
+CREATE OR REPLACE FUNCTION get_deleted_storyboards_by_user(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), deleted_date TIMESTAMP
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name, b.deleted_date
+		FROM storyboard b
+		WHERE b.owner_id = userId AND b.deleted_date IS NOT NULL
+		ORDER BY b.deleted_date DESC;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get a Storyboards Goals --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments
+                FROM storyboard_story ss
+                LEFT JOIN story_comment stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Releases with their point totals
+DROP FUNCTION IF EXISTS get_storyboard_releases(uuid);
+CREATE FUNCTION get_storyboard_releases(storyboardId UUID) RETURNS table (
+    id UUID,
+    name VARCHAR(256),
+    sort_order INTEGER,
+    target_date TEXT,
+    points BIGINT,
+    story_count BIGINT
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+			sr.id, sr.name, sr.sort_order, coalesce(to_char(sr.target_date, 'YYYY-MM-DD'), ''),
+			coalesce(sum(ss.points), 0), count(ss.id)
+		FROM storyboard_release sr
+		LEFT JOIN storyboard_story ss ON ss.release_id = sr.id AND ss.deleted_date IS NULL
+		WHERE sr.storyboard_id = storyboardId
+		GROUP BY sr.id
+		ORDER BY sr.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Application Stats e.g. total user and storyboard counts
+CREATE OR REPLACE FUNCTION get_app_stats(
+    OUT unregistered_user_count INTEGER,
+    OUT registered_user_count INTEGER,
+    OUT storyboard_count INTEGER,
+    OUT organization_count INTEGER,
+    OUT department_count INTEGER,
+    OUT team_count INTEGER,
+    OUT apikey_count INTEGER
+) AS $$
+BEGIN
+    SELECT COUNT(*) INTO unregistered_user_count FROM users WHERE email IS NULL;
+    SELECT COUNT(*) INTO registered_user_count FROM users WHERE email IS NOT NULL;
+    SELECT COUNT(*) INTO storyboard_count FROM storyboard WHERE deleted_date IS NULL;
+    SELECT COUNT(*) INTO organization_count FROM organization;
+    SELECT COUNT(*) INTO department_count FROM organization_department;
+    SELECT COUNT(*) INTO team_count FROM team;
+    SELECT COUNT(*) INTO apikey_count FROM api_keys;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Team Storyboards --
+CREATE OR REPLACE FUNCTION team_storyboard_list(
+    IN teamId UUID,
+    IN l_limit INTEGER,
+    IN l_offset INTEGER
+) RETURNS table (
+    id UUID, name VARCHAR(256)
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT b.id, b.name
+        FROM team_storyboard tb
+        LEFT JOIN storyboard b ON tb.storyboard_id = b.id
+        WHERE tb.team_id = teamId AND b.deleted_date IS NULL
+        ORDER BY tb.created_date
+		LIMIT l_limit
+		OFFSET l_offset;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,4 @@
+DROP PROCEDURE IF EXISTS audit_log_add(uuid, varchar, varchar, text, uuid, uuid, uuid, uuid, jsonb, jsonb);
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+DROP TABLE IF EXISTS audit_log;
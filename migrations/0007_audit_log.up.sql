@@ -0,0 +1,64 @@
+-- Append-only record of who changed what on storyboards, teams, departments, organizations and users,
+-- ids are kept without foreign keys so entries outlive what they refer to
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id UUID,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32) NOT NULL,
+    target_id TEXT,
+    storyboard_id UUID,
+    organization_id UUID,
+    department_id UUID,
+    team_id UUID,
+    before_value JSONB,
+    after_value JSONB,
+    created_date TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX IF NOT EXISTS al_created_idx ON audit_log (created_date);
+CREATE INDEX IF NOT EXISTS al_actor_idx ON audit_log (actor_id, id);
+CREATE INDEX IF NOT EXISTS al_target_idx ON audit_log (target_type, target_id, id);
+CREATE INDEX IF NOT EXISTS al_storyboard_idx ON audit_log (storyboard_id, id);
+CREATE INDEX IF NOT EXISTS al_organization_idx ON audit_log (organization_id, id);
+CREATE INDEX IF NOT EXISTS al_team_idx ON audit_log (team_id, id);
+
+-- Reject any change to recorded audit entries --
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_append_only ON audit_log;
+CREATE TRIGGER audit_log_append_only BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE PROCEDURE audit_log_append_only();
+
+-- Add an Audit Log entry, resolving the organization of departments and organization or department teams --
+CREATE OR REPLACE PROCEDURE audit_log_add(
+    actorId UUID,
+    auditAction VARCHAR(64),
+    targetType VARCHAR(32),
+    targetId TEXT,
+    storyboardId UUID,
+    organizationId UUID,
+    departmentId UUID,
+    teamId UUID,
+    beforeValue JSONB,
+    afterValue JSONB
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF departmentId IS NULL AND teamId IS NOT NULL THEN
+        SELECT dt.department_id INTO departmentId FROM department_team dt WHERE dt.team_id = teamId;
+    END IF;
+    IF organizationId IS NULL AND departmentId IS NOT NULL THEN
+        SELECT od.organization_id INTO organizationId FROM organization_department od WHERE od.id = departmentId;
+    END IF;
+    IF organizationId IS NULL AND teamId IS NOT NULL THEN
+        SELECT ot.organization_id INTO organizationId FROM organization_team ot WHERE ot.team_id = teamId;
+    END IF;
+
+    INSERT INTO audit_log
+        (actor_id, action, target_type, target_id, storyboard_id, organization_id, department_id, team_id, before_value, after_value)
+        VALUES (actorId, auditAction, targetType, targetId, storyboardId, organizationId, departmentId, teamId, beforeValue, afterValue);
+END;
+$$;
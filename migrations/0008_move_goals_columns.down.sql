@@ -0,0 +1,2 @@
+DROP PROCEDURE IF EXISTS move_storyboard_column(uuid, uuid, uuid, text);
+DROP PROCEDURE IF EXISTS move_storyboard_goal(uuid, uuid, text);
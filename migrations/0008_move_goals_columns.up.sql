@@ -0,0 +1,85 @@
+-- Move a Storyboard Goal to before another goal (or last) --
+CREATE OR REPLACE PROCEDURE move_storyboard_goal(storyboardId UUID, goalId UUID, placeBefore TEXT)
+LANGUAGE plpgsql AS $$
+DECLARE srcSortOrder INTEGER;
+DECLARE targetSortOrder INTEGER;
+BEGIN
+    SELECT sort_order INTO srcSortOrder FROM storyboard_goal
+    WHERE id = goalId AND storyboard_id = storyboardId AND deleted_date IS NULL;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Goal not found';
+    END IF;
+    IF placeBefore = goalId::TEXT THEN
+        RETURN;
+    END IF;
+
+    -- Remove from the storyboards ordering and close the gap
+    UPDATE storyboard_goal SET sort_order = NULL WHERE id = goalId;
+    UPDATE storyboard_goal sg SET sort_order = -(sg.sort_order - 1) WHERE sg.storyboard_id = storyboardId AND sg.sort_order > srcSortOrder;
+    UPDATE storyboard_goal sg SET sort_order = -sg.sort_order WHERE sg.storyboard_id = storyboardId AND sg.sort_order < 0;
+
+    -- Get target sort order
+    IF placeBefore = '' THEN
+        SELECT coalesce(max(sort_order), 0) + 1 INTO targetSortOrder FROM storyboard_goal WHERE storyboard_id = storyboardId;
+    ELSE
+        SELECT sort_order INTO targetSortOrder FROM storyboard_goal
+        WHERE storyboard_id = storyboardId AND id = placeBefore::UUID AND sort_order IS NOT NULL;
+        IF NOT FOUND THEN
+            RAISE EXCEPTION 'Goal to place before not found';
+        END IF;
+    END IF;
+
+    -- Make room for the goal and place it
+    UPDATE storyboard_goal sg SET sort_order = -(sg.sort_order + 1) WHERE sg.storyboard_id = storyboardId AND sg.sort_order >= targetSortOrder;
+    UPDATE storyboard_goal sg SET sort_order = -sg.sort_order WHERE sg.storyboard_id = storyboardId AND sg.sort_order < 0;
+    UPDATE storyboard_goal SET sort_order = targetSortOrder, updated_date = NOW() WHERE id = goalId;
+
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Move a Storyboard Column to before another column (or last) of a goal, its stories move with it --
+CREATE OR REPLACE PROCEDURE move_storyboard_column(storyboardId UUID, columnId UUID, goalId UUID, placeBefore TEXT)
+LANGUAGE plpgsql AS $$
+DECLARE srcGoalId UUID;
+DECLARE srcSortOrder INTEGER;
+DECLARE targetSortOrder INTEGER;
+BEGIN
+    SELECT goal_id, sort_order INTO srcGoalId, srcSortOrder FROM storyboard_column
+    WHERE id = columnId AND storyboard_id = storyboardId AND deleted_date IS NULL;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Column not found';
+    END IF;
+    PERFORM 1 FROM storyboard_goal WHERE id = goalId AND storyboard_id = storyboardId AND deleted_date IS NULL;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Goal not found';
+    END IF;
+    IF placeBefore = columnId::TEXT THEN
+        RETURN;
+    END IF;
+
+    -- Remove from the source goals ordering and close the gap
+    UPDATE storyboard_column SET sort_order = NULL WHERE id = columnId;
+    UPDATE storyboard_column sc SET sort_order = -(sc.sort_order - 1) WHERE sc.goal_id = srcGoalId AND sc.sort_order > srcSortOrder;
+    UPDATE storyboard_column sc SET sort_order = -sc.sort_order WHERE sc.goal_id = srcGoalId AND sc.sort_order < 0;
+
+    -- Get target sort order
+    IF placeBefore = '' THEN
+        SELECT coalesce(max(sort_order), 0) + 1 INTO targetSortOrder FROM storyboard_column WHERE goal_id = goalId;
+    ELSE
+        SELECT sort_order INTO targetSortOrder FROM storyboard_column
+        WHERE goal_id = goalId AND id = placeBefore::UUID AND sort_order IS NOT NULL;
+        IF NOT FOUND THEN
+            RAISE EXCEPTION 'Column to place before not found';
+        END IF;
+    END IF;
+
+    -- Make room for the column and place it, carrying its stories (including those in the trash) to the goal
+    UPDATE storyboard_column sc SET sort_order = -(sc.sort_order + 1) WHERE sc.goal_id = goalId AND sc.sort_order >= targetSortOrder;
+    UPDATE storyboard_column sc SET sort_order = -sc.sort_order WHERE sc.goal_id = goalId AND sc.sort_order < 0;
+    UPDATE storyboard_column SET goal_id = goalId, sort_order = targetSortOrder, updated_date = NOW() WHERE id = columnId;
+    UPDATE storyboard_story SET goal_id = goalId WHERE column_id = columnId;
+
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
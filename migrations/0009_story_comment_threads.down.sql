@@ -0,0 +1,56 @@
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments
+                FROM storyboard_story ss
+                LEFT JOIN story_comment stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP PROCEDURE IF EXISTS story_comment_delete(uuid, uuid);
+DROP PROCEDURE IF EXISTS story_comment_edit(uuid, uuid, text);
+DROP PROCEDURE IF EXISTS story_comment_add(uuid, uuid, uuid, text, uuid);
+CREATE PROCEDURE story_comment_add(storyboardId UUID, storyId UUID, userId UUID, comment TEXT)
+LANGUAGE plpgsql AS $$
+BEGIN
+    INSERT INTO story_comment (storyboard_id, story_id, user_id, comment) VALUES (storyboardId, storyId, userId, comment);
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+
+    COMMIT;
+END;
+$$;
+
+DROP INDEX IF EXISTS story_comment_parent_id_idx;
+ALTER TABLE story_comment DROP CONSTRAINT IF EXISTS stc_parent_id;
+ALTER TABLE story_comment DROP COLUMN IF EXISTS edited;
+ALTER TABLE story_comment DROP COLUMN IF EXISTS parent_id;
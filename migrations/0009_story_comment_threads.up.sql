@@ -0,0 +1,93 @@
+-- Story comments can be edited and deleted by their author (or the storyboard owner) and replied to,
+-- replies are deleted along with the comment they reply to
+ALTER TABLE story_comment ADD COLUMN IF NOT EXISTS parent_id UUID;
+ALTER TABLE story_comment ADD COLUMN IF NOT EXISTS edited BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE story_comment ADD CONSTRAINT stc_parent_id FOREIGN KEY(parent_id) REFERENCES story_comment(id) ON DELETE CASCADE;
+CREATE INDEX IF NOT EXISTS story_comment_parent_id_idx ON story_comment (parent_id);
+
+-- Add a comment (or a reply to a comment) to Storyboard Story, replies to a reply join its thread --
+DROP PROCEDURE IF EXISTS story_comment_add(uuid, uuid, uuid, text);
+CREATE PROCEDURE story_comment_add(storyboardId UUID, storyId UUID, userId UUID, comment TEXT, parentId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF parentId IS NOT NULL THEN
+        SELECT coalesce(parent_id, id) INTO parentId FROM story_comment WHERE id = parentId AND story_id = storyId;
+        IF NOT FOUND THEN
+            RAISE EXCEPTION 'Parent comment not found';
+        END IF;
+    END IF;
+
+    INSERT INTO story_comment (storyboard_id, story_id, user_id, comment, parent_id)
+    VALUES (storyboardId, storyId, userId, comment, parentId);
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Edit a comment on a Storyboard Story --
+CREATE OR REPLACE PROCEDURE story_comment_edit(storyboardId UUID, commentId UUID, updatedComment TEXT)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE story_comment SET comment = updatedComment, edited = true, updated_date = NOW()
+    WHERE id = commentId AND storyboard_id = storyboardId;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Comment not found';
+    END IF;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Delete a comment on a Storyboard Story along with its replies --
+CREATE OR REPLACE PROCEDURE story_comment_delete(storyboardId UUID, commentId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM story_comment WHERE id = commentId AND storyboard_id = storyboardId;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Comment not found';
+    END IF;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Get a Storyboards Goals, story comments include their authors name --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
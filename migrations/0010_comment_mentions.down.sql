@@ -0,0 +1,29 @@
+DROP FUNCTION IF EXISTS get_user(UUID);
+CREATE FUNCTION get_user(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(64), email VARCHAR(320), type VARCHAR(128), verified BOOL, avatar VARCHAR(128), country VARCHAR(2), locale VARCHAR(2), company VARCHAR(256), jobTitle VARCHAR(128)
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT u.id, u.name, coalesce(u.email, ''), u.type, u.verified, u.avatar, u.country, u.locale, u.company, u.job_title FROM users u WHERE u.id = userId;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP PROCEDURE IF EXISTS story_comment_add(uuid, uuid, uuid, text, uuid, uuid);
+CREATE PROCEDURE story_comment_add(storyboardId UUID, storyId UUID, userId UUID, comment TEXT, parentId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF parentId IS NOT NULL THEN
+        SELECT coalesce(parent_id, id) INTO parentId FROM story_comment WHERE id = parentId AND story_id = storyId;
+        IF NOT FOUND THEN
+            RAISE EXCEPTION 'Parent comment not found';
+        END IF;
+    END IF;
+
+    INSERT INTO story_comment (storyboard_id, story_id, user_id, comment, parent_id)
+    VALUES (storyboardId, storyId, userId, comment, parentId);
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+DROP TABLE IF EXISTS story_comment_mention;
+ALTER TABLE users DROP COLUMN IF EXISTS mention_emails;
@@ -0,0 +1,51 @@
+-- Users mentioned in story comments, mentions of users not on the storyboard at the time are queued for email --
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mention_emails BOOL NOT NULL DEFAULT true;
+
+CREATE TABLE IF NOT EXISTS story_comment_mention (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    comment_id UUID NOT NULL,
+    storyboard_id UUID NOT NULL,
+    story_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    email_queued BOOL NOT NULL DEFAULT false,
+    emailed_date TIMESTAMP,
+    created_date TIMESTAMP DEFAULT NOW(),
+    UNIQUE(comment_id, user_id),
+    CONSTRAINT scm_comment_id FOREIGN KEY(comment_id) REFERENCES story_comment(id) ON DELETE CASCADE,
+    CONSTRAINT scm_storyboard_id FOREIGN KEY(storyboard_id) REFERENCES storyboard(id) ON DELETE CASCADE,
+    CONSTRAINT scm_story_id FOREIGN KEY(story_id) REFERENCES storyboard_story(id) ON DELETE CASCADE,
+    CONSTRAINT scm_user_id FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS story_comment_mention_user_id_idx ON story_comment_mention (user_id);
+CREATE INDEX IF NOT EXISTS story_comment_mention_email_idx ON story_comment_mention (created_date)
+    WHERE email_queued = true AND emailed_date IS NULL;
+
+-- Add a comment (or a reply to a comment) to Storyboard Story, replies to a reply join its thread --
+DROP PROCEDURE IF EXISTS story_comment_add(uuid, uuid, uuid, text, uuid);
+CREATE PROCEDURE story_comment_add(storyboardId UUID, storyId UUID, userId UUID, comment TEXT, parentId UUID, INOUT commentId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF parentId IS NOT NULL THEN
+        SELECT coalesce(parent_id, id) INTO parentId FROM story_comment WHERE id = parentId AND story_id = storyId;
+        IF NOT FOUND THEN
+            RAISE EXCEPTION 'Parent comment not found';
+        END IF;
+    END IF;
+
+    INSERT INTO story_comment (storyboard_id, story_id, user_id, comment, parent_id)
+    VALUES (storyboardId, storyId, userId, comment, parentId)
+    RETURNING id INTO commentId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Get User by ID --
+DROP FUNCTION IF EXISTS get_user(UUID);
+CREATE FUNCTION get_user(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(64), email VARCHAR(320), type VARCHAR(128), verified BOOL, avatar VARCHAR(128), country VARCHAR(2), locale VARCHAR(2), company VARCHAR(256), jobTitle VARCHAR(128), mentionEmails BOOL
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT u.id, u.name, coalesce(u.email, ''), u.type, u.verified, u.avatar, u.country, u.locale, u.company, u.job_title, u.mention_emails FROM users u WHERE u.id = userId;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,48 @@
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS get_user_assigned_stories(uuid);
+DROP PROCEDURE IF EXISTS story_assignee_remove(uuid, uuid, uuid);
+DROP PROCEDURE IF EXISTS story_assignee_add(uuid, uuid, uuid);
+DROP FUNCTION IF EXISTS storyboard_user_assignable(uuid, uuid);
+DROP TABLE IF EXISTS storyboard_story_assignee;
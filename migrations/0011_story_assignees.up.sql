@@ -0,0 +1,126 @@
+-- Users assigned to a Storyboard Story, assignees are drawn from the storyboards users and the members of its teams --
+CREATE TABLE IF NOT EXISTS storyboard_story_assignee (
+    story_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    storyboard_id UUID NOT NULL,
+    created_date TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (story_id, user_id),
+    CONSTRAINT ssa_story_id FOREIGN KEY(story_id) REFERENCES storyboard_story(id) ON DELETE CASCADE,
+    CONSTRAINT ssa_user_id FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT ssa_storyboard_id FOREIGN KEY(storyboard_id) REFERENCES storyboard(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS storyboard_story_assignee_user_id_idx ON storyboard_story_assignee (user_id);
+
+-- Check whether a User can be assigned stories on a Storyboard --
+CREATE OR REPLACE FUNCTION storyboard_user_assignable(storyboardId UUID, userId UUID) RETURNS BOOL AS $$
+BEGIN
+    RETURN EXISTS (SELECT 1 FROM storyboard WHERE id = storyboardId AND owner_id = userId)
+        OR EXISTS (SELECT 1 FROM storyboard_user WHERE storyboard_id = storyboardId AND user_id = userId)
+        OR EXISTS (
+            SELECT 1 FROM team_storyboard tb
+            JOIN team_user tu ON tu.team_id = tb.team_id
+            WHERE tb.storyboard_id = storyboardId AND tu.user_id = userId
+        );
+END;
+$$ LANGUAGE plpgsql;
+
+-- Assign a User to a Storyboard Story --
+CREATE OR REPLACE PROCEDURE story_assignee_add(storyboardId UUID, storyId UUID, userId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM storyboard_story WHERE id = storyId AND storyboard_id = storyboardId AND deleted_date IS NULL
+    ) THEN
+        RAISE EXCEPTION 'Story not found';
+    END IF;
+    IF NOT storyboard_user_assignable(storyboardId, userId) THEN
+        RAISE EXCEPTION 'User can not be assigned';
+    END IF;
+
+    INSERT INTO storyboard_story_assignee (story_id, user_id, storyboard_id)
+    VALUES (storyId, userId, storyboardId)
+    ON CONFLICT DO NOTHING;
+    UPDATE storyboard_story SET updated_date = NOW() WHERE id = storyId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Unassign a User from a Storyboard Story --
+CREATE OR REPLACE PROCEDURE story_assignee_remove(storyboardId UUID, storyId UUID, userId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM storyboard_story_assignee WHERE story_id = storyId AND user_id = userId AND storyboard_id = storyboardId;
+    UPDATE storyboard_story SET updated_date = NOW() WHERE id = storyId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Get Open Stories assigned to a User across the Storyboards they can access --
+CREATE OR REPLACE FUNCTION get_user_assigned_stories(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), color VARCHAR(32), points INTEGER, storyboard_id UUID, storyboard_name VARCHAR(256),
+    goal_id UUID, goal_name VARCHAR(256), column_id UUID, column_name VARCHAR(256)
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT ss.id, coalesce(ss.name, ''), ss.color, coalesce(ss.points, 0), b.id, coalesce(b.name, ''), sg.id, coalesce(sg.name, ''), sc.id, coalesce(sc.name, '')
+        FROM storyboard_story_assignee ssa
+        JOIN storyboard_story ss ON ss.id = ssa.story_id
+        JOIN storyboard_column sc ON sc.id = ss.column_id
+        JOIN storyboard_goal sg ON sg.id = ss.goal_id
+        JOIN storyboard b ON b.id = ss.storyboard_id
+        WHERE ssa.user_id = userId AND ss.closed = false
+        AND ss.deleted_date IS NULL AND sc.deleted_date IS NULL AND sg.deleted_date IS NULL AND b.deleted_date IS NULL
+        AND storyboard_user_assignable(b.id, userId)
+        ORDER BY b.name, sg.sort_order, sc.sort_order, ss.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Goals, stories include their comments and assignees --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', u.id, 'name', u.name) ORDER BY u.name), '[]')
+                        FROM storyboard_story_assignee ssa
+                        JOIN users u ON u.id = ssa.user_id
+                        WHERE ssa.story_id = ss.id
+                    ) AS assignees
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,79 @@
+-- Get Storyboard Goals, stories include their comments and assignees --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', u.id, 'name', u.name) ORDER BY u.name), '[]')
+                        FROM storyboard_story_assignee ssa
+                        JOIN users u ON u.id = ssa.user_id
+                        WHERE ssa.story_id = ss.id
+                    ) AS assignees
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Open Stories assigned to a User across the Storyboards they can access --
+DROP FUNCTION IF EXISTS get_user_assigned_stories(uuid);
+CREATE FUNCTION get_user_assigned_stories(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), color VARCHAR(32), points INTEGER, storyboard_id UUID, storyboard_name VARCHAR(256),
+    goal_id UUID, goal_name VARCHAR(256), column_id UUID, column_name VARCHAR(256)
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT ss.id, coalesce(ss.name, ''), ss.color, coalesce(ss.points, 0), b.id, coalesce(b.name, ''), sg.id, coalesce(sg.name, ''), sc.id, coalesce(sc.name, '')
+        FROM storyboard_story_assignee ssa
+        JOIN storyboard_story ss ON ss.id = ssa.story_id
+        JOIN storyboard_column sc ON sc.id = ss.column_id
+        JOIN storyboard_goal sg ON sg.id = ss.goal_id
+        JOIN storyboard b ON b.id = ss.storyboard_id
+        WHERE ssa.user_id = userId AND ss.closed = false
+        AND ss.deleted_date IS NULL AND sc.deleted_date IS NULL AND sg.deleted_date IS NULL AND b.deleted_date IS NULL
+        AND storyboard_user_assignable(b.id, userId)
+        ORDER BY b.name, sg.sort_order, sc.sort_order, ss.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS get_storyboard_labels(uuid);
+DROP PROCEDURE IF EXISTS story_label_remove(uuid, uuid, uuid);
+DROP PROCEDURE IF EXISTS story_label_add(uuid, uuid, uuid);
+DROP PROCEDURE IF EXISTS label_delete(uuid, uuid);
+DROP PROCEDURE IF EXISTS label_edit(uuid, uuid, varchar, varchar);
+DROP PROCEDURE IF EXISTS label_add(uuid, varchar, varchar);
+DROP TABLE IF EXISTS storyboard_story_label;
+DROP TABLE IF EXISTS storyboard_label;
@@ -0,0 +1,188 @@
+-- Labels defined per Storyboard, a story can have any number of its storyboards labels --
+CREATE TABLE IF NOT EXISTS storyboard_label (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    storyboard_id UUID NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    color VARCHAR(32) NOT NULL DEFAULT 'gray',
+    created_date TIMESTAMP DEFAULT NOW(),
+    updated_date TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT sl_storyboard_id FOREIGN KEY(storyboard_id) REFERENCES storyboard(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS storyboard_label_name_idx ON storyboard_label (storyboard_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS storyboard_story_label (
+    story_id UUID NOT NULL,
+    label_id UUID NOT NULL,
+    created_date TIMESTAMP DEFAULT NOW(),
+    PRIMARY KEY (story_id, label_id),
+    CONSTRAINT ssl_story_id FOREIGN KEY(story_id) REFERENCES storyboard_story(id) ON DELETE CASCADE,
+    CONSTRAINT ssl_label_id FOREIGN KEY(label_id) REFERENCES storyboard_label(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS storyboard_story_label_label_id_idx ON storyboard_story_label (label_id);
+
+-- Add a Label to a Storyboard --
+CREATE OR REPLACE PROCEDURE label_add(storyboardId UUID, labelName VARCHAR(64), labelColor VARCHAR(32))
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM storyboard_label WHERE storyboard_id = storyboardId AND lower(name) = lower(labelName)) THEN
+        RAISE EXCEPTION 'Label already exists';
+    END IF;
+
+    INSERT INTO storyboard_label (storyboard_id, name, color) VALUES (storyboardId, labelName, labelColor);
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Revise a Storyboard Labels name and color --
+CREATE OR REPLACE PROCEDURE label_edit(storyboardId UUID, labelId UUID, labelName VARCHAR(64), labelColor VARCHAR(32))
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM storyboard_label WHERE storyboard_id = storyboardId AND lower(name) = lower(labelName) AND id != labelId
+    ) THEN
+        RAISE EXCEPTION 'Label already exists';
+    END IF;
+
+    UPDATE storyboard_label SET name = labelName, color = labelColor, updated_date = NOW()
+    WHERE id = labelId AND storyboard_id = storyboardId;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Label not found';
+    END IF;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Delete a Storyboard Label, removing it from its stories --
+CREATE OR REPLACE PROCEDURE label_delete(storyboardId UUID, labelId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM storyboard_label WHERE id = labelId AND storyboard_id = storyboardId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Add a Storyboard Label to a Story --
+CREATE OR REPLACE PROCEDURE story_label_add(storyboardId UUID, storyId UUID, labelId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM storyboard_story WHERE id = storyId AND storyboard_id = storyboardId AND deleted_date IS NULL
+    ) THEN
+        RAISE EXCEPTION 'Story not found';
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM storyboard_label WHERE id = labelId AND storyboard_id = storyboardId) THEN
+        RAISE EXCEPTION 'Label not found';
+    END IF;
+
+    INSERT INTO storyboard_story_label (story_id, label_id) VALUES (storyId, labelId) ON CONFLICT DO NOTHING;
+    UPDATE storyboard_story SET updated_date = NOW() WHERE id = storyId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Remove a Label from a Story --
+CREATE OR REPLACE PROCEDURE story_label_remove(storyboardId UUID, storyId UUID, labelId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM storyboard_story_label ssl USING storyboard_label sl
+    WHERE ssl.story_id = storyId AND ssl.label_id = labelId AND sl.id = ssl.label_id AND sl.storyboard_id = storyboardId;
+    UPDATE storyboard_story SET updated_date = NOW() WHERE id = storyId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Get Storyboard Labels --
+CREATE OR REPLACE FUNCTION get_storyboard_labels(storyboardId UUID) RETURNS table (
+    id UUID, name VARCHAR(64), color VARCHAR(32)
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT sl.id, sl.name, sl.color
+        FROM storyboard_label sl
+        WHERE sl.storyboard_id = storyboardId
+        ORDER BY sl.name;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Open Stories assigned to a User across the Storyboards they can access, with their labels --
+DROP FUNCTION IF EXISTS get_user_assigned_stories(uuid);
+CREATE FUNCTION get_user_assigned_stories(userId UUID) RETURNS table (
+    id UUID, name VARCHAR(256), color VARCHAR(32), points INTEGER, storyboard_id UUID, storyboard_name VARCHAR(256),
+    goal_id UUID, goal_name VARCHAR(256), column_id UUID, column_name VARCHAR(256), labels JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT ss.id, coalesce(ss.name, ''), ss.color, coalesce(ss.points, 0), b.id, coalesce(b.name, ''), sg.id, coalesce(sg.name, ''), sc.id, coalesce(sc.name, ''),
+            (
+                SELECT COALESCE(json_agg(json_build_object('id', sl.id, 'name', sl.name, 'color', sl.color) ORDER BY sl.name), '[]')
+                FROM storyboard_story_label ssl
+                JOIN storyboard_label sl ON sl.id = ssl.label_id
+                WHERE ssl.story_id = ss.id
+            )
+        FROM storyboard_story_assignee ssa
+        JOIN storyboard_story ss ON ss.id = ssa.story_id
+        JOIN storyboard_column sc ON sc.id = ss.column_id
+        JOIN storyboard_goal sg ON sg.id = ss.goal_id
+        JOIN storyboard b ON b.id = ss.storyboard_id
+        WHERE ssa.user_id = userId AND ss.closed = false
+        AND ss.deleted_date IS NULL AND sc.deleted_date IS NULL AND sg.deleted_date IS NULL AND b.deleted_date IS NULL
+        AND storyboard_user_assignable(b.id, userId)
+        ORDER BY b.name, sg.sort_order, sc.sort_order, ss.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Goals, stories include their comments, assignees and labels --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', u.id, 'name', u.name) ORDER BY u.name), '[]')
+                        FROM storyboard_story_assignee ssa
+                        JOIN users u ON u.id = ssa.user_id
+                        WHERE ssa.story_id = ss.id
+                    ) AS assignees,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', sl.id, 'name', sl.name, 'color', sl.color) ORDER BY sl.name), '[]')
+                        FROM storyboard_story_label ssl
+                        JOIN storyboard_label sl ON sl.id = ssl.label_id
+                        WHERE ssl.story_id = ss.id
+                    ) AS labels
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,73 @@
+-- Get Storyboard Goals, stories include their comments, assignees and labels --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', u.id, 'name', u.name) ORDER BY u.name), '[]')
+                        FROM storyboard_story_assignee ssa
+                        JOIN users u ON u.id = ssa.user_id
+                        WHERE ssa.story_id = ss.id
+                    ) AS assignees,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', sl.id, 'name', sl.name, 'color', sl.color) ORDER BY sl.name), '[]')
+                        FROM storyboard_story_label ssl
+                        JOIN storyboard_label sl ON sl.id = ssl.label_id
+                        WHERE ssl.story_id = ss.id
+                    ) AS labels
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE PROCEDURE update_story_closed(storyId UUID, isClosed BOOL)
+LANGUAGE plpgsql AS $$
+DECLARE storyboardId UUID;
+BEGIN
+    UPDATE storyboard_story SET closed = isClosed, updated_date = NOW() WHERE id = storyId RETURNING storyboard_id INTO storyboardId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+DROP PROCEDURE IF EXISTS story_criterion_delete(uuid, uuid);
+DROP PROCEDURE IF EXISTS story_criterion_move(uuid, uuid, text);
+DROP PROCEDURE IF EXISTS story_criterion_toggle(uuid, uuid, bool);
+DROP PROCEDURE IF EXISTS story_criterion_edit(uuid, uuid, text);
+DROP PROCEDURE IF EXISTS story_criterion_add(uuid, uuid, text);
+DROP PROCEDURE IF EXISTS revise_criteria_rule(uuid, bool);
+ALTER TABLE storyboard DROP COLUMN IF EXISTS require_criteria_done;
+DROP TABLE IF EXISTS story_criterion;
@@ -0,0 +1,194 @@
+-- Acceptance criteria checklist items on a Storyboard Story, kept in sort order --
+CREATE TABLE IF NOT EXISTS story_criterion (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    storyboard_id UUID NOT NULL,
+    story_id UUID NOT NULL,
+    text TEXT NOT NULL,
+    done BOOL NOT NULL DEFAULT false,
+    sort_order INTEGER NOT NULL,
+    created_date TIMESTAMP DEFAULT NOW(),
+    updated_date TIMESTAMP DEFAULT NOW(),
+    CONSTRAINT scr_storyboard_id FOREIGN KEY(storyboard_id) REFERENCES storyboard(id) ON DELETE CASCADE,
+    CONSTRAINT scr_story_id FOREIGN KEY(story_id) REFERENCES storyboard_story(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS story_criterion_story_id_idx ON story_criterion (story_id, sort_order);
+
+-- When enabled a story can't be closed while any of its acceptance criteria are open --
+ALTER TABLE storyboard ADD COLUMN IF NOT EXISTS require_criteria_done BOOL NOT NULL DEFAULT false;
+
+-- Set whether a Storyboards stories require their acceptance criteria done to close --
+CREATE OR REPLACE PROCEDURE revise_criteria_rule(storyboardId UUID, requireDone BOOL)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE storyboard SET require_criteria_done = requireDone, updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Add an acceptance criterion to the end of a Storyboard Storys checklist --
+CREATE OR REPLACE PROCEDURE story_criterion_add(storyboardId UUID, storyId UUID, criterionText TEXT)
+LANGUAGE plpgsql AS $$
+DECLARE sortOrder INTEGER;
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM storyboard_story WHERE id = storyId AND storyboard_id = storyboardId AND deleted_date IS NULL
+    ) THEN
+        RAISE EXCEPTION 'Story not found';
+    END IF;
+
+    SELECT coalesce(max(sort_order), 0) + 1 INTO sortOrder FROM story_criterion WHERE story_id = storyId;
+    INSERT INTO story_criterion (storyboard_id, story_id, text, sort_order) VALUES (storyboardId, storyId, criterionText, sortOrder);
+    UPDATE storyboard_story SET updated_date = NOW() WHERE id = storyId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Revise an acceptance criterions text --
+CREATE OR REPLACE PROCEDURE story_criterion_edit(storyboardId UUID, criterionId UUID, criterionText TEXT)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE story_criterion SET text = criterionText, updated_date = NOW() WHERE id = criterionId AND storyboard_id = storyboardId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Mark an acceptance criterion done or open --
+CREATE OR REPLACE PROCEDURE story_criterion_toggle(storyboardId UUID, criterionId UUID, isDone BOOL)
+LANGUAGE plpgsql AS $$
+BEGIN
+    UPDATE story_criterion SET done = isDone, updated_date = NOW() WHERE id = criterionId AND storyboard_id = storyboardId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Move an acceptance criterion before another in its checklist (or last) --
+CREATE OR REPLACE PROCEDURE story_criterion_move(storyboardId UUID, criterionId UUID, placeBefore TEXT)
+LANGUAGE plpgsql AS $$
+DECLARE storyId UUID;
+DECLARE targetSortOrder INTEGER;
+BEGIN
+    SELECT story_id INTO storyId FROM story_criterion WHERE id = criterionId AND storyboard_id = storyboardId;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Criterion not found';
+    END IF;
+
+    IF placeBefore = '' THEN
+        SELECT coalesce(max(sort_order), 0) + 1 INTO targetSortOrder FROM story_criterion WHERE story_id = storyId;
+    ELSE
+        SELECT sort_order INTO targetSortOrder FROM story_criterion WHERE story_id = storyId AND id = placeBefore::UUID;
+        IF NOT FOUND THEN
+            RAISE EXCEPTION 'Criterion to place before not found';
+        END IF;
+        UPDATE story_criterion SET sort_order = sort_order + 1
+        WHERE story_id = storyId AND sort_order >= targetSortOrder AND id != criterionId;
+    END IF;
+
+    UPDATE story_criterion SET sort_order = targetSortOrder, updated_date = NOW() WHERE id = criterionId;
+
+    -- Close any gaps left behind
+    UPDATE story_criterion scr SET sort_order = t.new_order
+    FROM (
+        SELECT id, row_number() OVER (ORDER BY sort_order) AS new_order
+        FROM story_criterion WHERE story_id = storyId
+    ) t
+    WHERE scr.id = t.id AND scr.sort_order != t.new_order;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Delete an acceptance criterion --
+CREATE OR REPLACE PROCEDURE story_criterion_delete(storyboardId UUID, criterionId UUID)
+LANGUAGE plpgsql AS $$
+DECLARE storyId UUID;
+DECLARE sortOrder INTEGER;
+BEGIN
+    DELETE FROM story_criterion WHERE id = criterionId AND storyboard_id = storyboardId
+    RETURNING story_id, sort_order INTO storyId, sortOrder;
+    UPDATE story_criterion SET sort_order = (sort_order - 1) WHERE story_id = storyId AND sort_order > sortOrder;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Update a Storys closed status, closing is refused while acceptance criteria are open on storyboards requiring them done --
+CREATE OR REPLACE PROCEDURE update_story_closed(storyId UUID, isClosed BOOL)
+LANGUAGE plpgsql AS $$
+DECLARE storyboardId UUID;
+BEGIN
+    IF isClosed AND EXISTS (
+        SELECT 1 FROM storyboard_story ss
+        JOIN storyboard b ON b.id = ss.storyboard_id
+        JOIN story_criterion scr ON scr.story_id = ss.id
+        WHERE ss.id = storyId AND b.require_criteria_done AND NOT scr.done
+    ) THEN
+        RAISE EXCEPTION 'Story has open acceptance criteria' USING ERRCODE = 'check_violation';
+    END IF;
+
+    UPDATE storyboard_story SET closed = isClosed, updated_date = NOW() WHERE id = storyId RETURNING storyboard_id INTO storyboardId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Get Storyboard Goals, stories include their comments, assignees, labels and acceptance criteria --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', u.id, 'name', u.name) ORDER BY u.name), '[]')
+                        FROM storyboard_story_assignee ssa
+                        JOIN users u ON u.id = ssa.user_id
+                        WHERE ssa.story_id = ss.id
+                    ) AS assignees,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', sl.id, 'name', sl.name, 'color', sl.color) ORDER BY sl.name), '[]')
+                        FROM storyboard_story_label ssl
+                        JOIN storyboard_label sl ON sl.id = ssl.label_id
+                        WHERE ssl.story_id = ss.id
+                    ) AS labels,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', scr.id, 'story_id', scr.story_id, 'text', scr.text, 'done', scr.done, 'sort_order', scr.sort_order) ORDER BY scr.sort_order), '[]')
+                        FROM story_criterion scr
+                        WHERE scr.story_id = ss.id
+                    ) AS criteria,
+                    (
+                        SELECT COALESCE(count(*) FILTER (WHERE scr.done)::FLOAT / NULLIF(count(*), 0), 0)
+                        FROM story_criterion scr
+                        WHERE scr.story_id = ss.id
+                    ) AS criteria_ratio
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
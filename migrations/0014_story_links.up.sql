@@ -0,0 +1,171 @@
+-- Typed links between stories on the same or different storyboards, a blocks link means the from story blocks the to story --
+CREATE TABLE IF NOT EXISTS story_link (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    from_story_id UUID NOT NULL,
+    to_story_id UUID NOT NULL,
+    link_type VARCHAR(16) NOT NULL,
+    created_by UUID,
+    created_date TIMESTAMP DEFAULT NOW(),
+    UNIQUE(from_story_id, to_story_id, link_type),
+    CONSTRAINT sl_link_type CHECK (link_type IN ('blocks', 'relates_to', 'duplicates')),
+    CONSTRAINT sl_not_self CHECK (from_story_id != to_story_id),
+    CONSTRAINT sl_from_story_id FOREIGN KEY(from_story_id) REFERENCES storyboard_story(id) ON DELETE CASCADE,
+    CONSTRAINT sl_to_story_id FOREIGN KEY(to_story_id) REFERENCES storyboard_story(id) ON DELETE CASCADE,
+    CONSTRAINT sl_created_by FOREIGN KEY(created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS story_link_to_story_id_idx ON story_link (to_story_id);
+
+-- Check whether a blocks link from one story to another would create a cycle of blocking stories --
+CREATE OR REPLACE FUNCTION story_link_creates_cycle(fromStoryId UUID, toStoryId UUID) RETURNS BOOL AS $$
+BEGIN
+    RETURN fromStoryId = toStoryId OR EXISTS (
+        WITH RECURSIVE blocked(id) AS (
+            SELECT sl.to_story_id FROM story_link sl WHERE sl.from_story_id = toStoryId AND sl.link_type = 'blocks'
+            UNION
+            SELECT sl.to_story_id FROM story_link sl JOIN blocked b ON sl.from_story_id = b.id WHERE sl.link_type = 'blocks'
+        )
+        SELECT 1 FROM blocked WHERE id = fromStoryId
+    );
+END;
+$$ LANGUAGE plpgsql;
+
+-- Link a Storyboard Story to a story on any storyboard the user can access, returning the linked storys storyboard --
+CREATE OR REPLACE PROCEDURE story_link_add(
+    storyboardId UUID, storyId UUID, toStoryId UUID, linkType VARCHAR(16), userId UUID, INOUT toStoryboardId UUID
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM storyboard_story WHERE id = storyId AND storyboard_id = storyboardId AND deleted_date IS NULL
+    ) THEN
+        RAISE EXCEPTION 'Story not found';
+    END IF;
+
+    SELECT ss.storyboard_id INTO toStoryboardId
+    FROM storyboard_story ss
+    JOIN storyboard b ON b.id = ss.storyboard_id
+    WHERE ss.id = toStoryId AND ss.deleted_date IS NULL AND b.deleted_date IS NULL
+    AND storyboard_user_assignable(b.id, userId);
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Linked story not found';
+    END IF;
+
+    IF linkType = 'blocks' AND story_link_creates_cycle(storyId, toStoryId) THEN
+        RAISE EXCEPTION 'Blocking link would create a cycle' USING ERRCODE = 'check_violation';
+    END IF;
+
+    INSERT INTO story_link (from_story_id, to_story_id, link_type, created_by)
+    VALUES (storyId, toStoryId, linkType, userId)
+    ON CONFLICT DO NOTHING;
+    UPDATE storyboard SET updated_date = NOW() WHERE id IN (storyboardId, toStoryboardId);
+END;
+$$;
+
+-- Remove a link to or from one of a Storyboards stories, returning the other storys storyboard --
+CREATE OR REPLACE PROCEDURE story_link_remove(storyboardId UUID, linkId UUID, INOUT otherStoryboardId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM story_link sl
+    USING storyboard_story fs, storyboard_story ts
+    WHERE sl.id = linkId AND fs.id = sl.from_story_id AND ts.id = sl.to_story_id
+    AND (fs.storyboard_id = storyboardId OR ts.storyboard_id = storyboardId)
+    RETURNING CASE WHEN fs.storyboard_id = storyboardId THEN ts.storyboard_id ELSE fs.storyboard_id END
+    INTO otherStoryboardId;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Link not found';
+    END IF;
+
+    UPDATE storyboard SET updated_date = NOW() WHERE id IN (storyboardId, otherStoryboardId);
+END;
+$$;
+
+-- Get Storyboard Goals, stories include their comments, assignees, labels, acceptance criteria and links,
+-- a story is blocked while any story blocking it is open --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', u.id, 'name', u.name) ORDER BY u.name), '[]')
+                        FROM storyboard_story_assignee ssa
+                        JOIN users u ON u.id = ssa.user_id
+                        WHERE ssa.story_id = ss.id
+                    ) AS assignees,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', sl.id, 'name', sl.name, 'color', sl.color) ORDER BY sl.name), '[]')
+                        FROM storyboard_story_label ssl
+                        JOIN storyboard_label sl ON sl.id = ssl.label_id
+                        WHERE ssl.story_id = ss.id
+                    ) AS labels,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', scr.id, 'story_id', scr.story_id, 'text', scr.text, 'done', scr.done, 'sort_order', scr.sort_order) ORDER BY scr.sort_order), '[]')
+                        FROM story_criterion scr
+                        WHERE scr.story_id = ss.id
+                    ) AS criteria,
+                    (
+                        SELECT COALESCE(count(*) FILTER (WHERE scr.done)::FLOAT / NULLIF(count(*), 0), 0)
+                        FROM story_criterion scr
+                        WHERE scr.story_id = ss.id
+                    ) AS criteria_ratio,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object(
+                            'id', sl.id,
+                            'type', sl.link_type,
+                            'direction', CASE WHEN sl.from_story_id = ss.id THEN 'outward' ELSE 'inward' END,
+                            'story_id', os.id,
+                            'story_name', coalesce(os.name, ''),
+                            'storyboard_id', os.storyboard_id,
+                            'closed', os.closed
+                        ) ORDER BY sl.created_date), '[]')
+                        FROM story_link sl
+                        JOIN storyboard_story os
+                            ON os.id = CASE WHEN sl.from_story_id = ss.id THEN sl.to_story_id ELSE sl.from_story_id END
+                        JOIN storyboard ob ON ob.id = os.storyboard_id
+                        WHERE (sl.from_story_id = ss.id OR sl.to_story_id = ss.id)
+                        AND os.deleted_date IS NULL AND ob.deleted_date IS NULL
+                    ) AS links,
+                    EXISTS (
+                        SELECT 1 FROM story_link sl
+                        JOIN storyboard_story bs ON bs.id = sl.from_story_id
+                        JOIN storyboard bb ON bb.id = bs.storyboard_id
+                        WHERE sl.to_story_id = ss.id AND sl.link_type = 'blocks'
+                        AND bs.closed = false AND bs.deleted_date IS NULL AND bb.deleted_date IS NULL
+                    ) AS blocked
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
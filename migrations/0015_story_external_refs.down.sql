@@ -0,0 +1,95 @@
+-- Get Storyboard Goals, stories include their comments, assignees, labels, acceptance criteria and links,
+-- a story is blocked while any story blocking it is open --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', u.id, 'name', u.name) ORDER BY u.name), '[]')
+                        FROM storyboard_story_assignee ssa
+                        JOIN users u ON u.id = ssa.user_id
+                        WHERE ssa.story_id = ss.id
+                    ) AS assignees,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', sl.id, 'name', sl.name, 'color', sl.color) ORDER BY sl.name), '[]')
+                        FROM storyboard_story_label ssl
+                        JOIN storyboard_label sl ON sl.id = ssl.label_id
+                        WHERE ssl.story_id = ss.id
+                    ) AS labels,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', scr.id, 'story_id', scr.story_id, 'text', scr.text, 'done', scr.done, 'sort_order', scr.sort_order) ORDER BY scr.sort_order), '[]')
+                        FROM story_criterion scr
+                        WHERE scr.story_id = ss.id
+                    ) AS criteria,
+                    (
+                        SELECT COALESCE(count(*) FILTER (WHERE scr.done)::FLOAT / NULLIF(count(*), 0), 0)
+                        FROM story_criterion scr
+                        WHERE scr.story_id = ss.id
+                    ) AS criteria_ratio,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object(
+                            'id', sl.id,
+                            'type', sl.link_type,
+                            'direction', CASE WHEN sl.from_story_id = ss.id THEN 'outward' ELSE 'inward' END,
+                            'story_id', os.id,
+                            'story_name', coalesce(os.name, ''),
+                            'storyboard_id', os.storyboard_id,
+                            'closed', os.closed
+                        ) ORDER BY sl.created_date), '[]')
+                        FROM story_link sl
+                        JOIN storyboard_story os
+                            ON os.id = CASE WHEN sl.from_story_id = ss.id THEN sl.to_story_id ELSE sl.from_story_id END
+                        JOIN storyboard ob ON ob.id = os.storyboard_id
+                        WHERE (sl.from_story_id = ss.id OR sl.to_story_id = ss.id)
+                        AND os.deleted_date IS NULL AND ob.deleted_date IS NULL
+                    ) AS links,
+                    EXISTS (
+                        SELECT 1 FROM story_link sl
+                        JOIN storyboard_story bs ON bs.id = sl.from_story_id
+                        JOIN storyboard bb ON bb.id = bs.storyboard_id
+                        WHERE sl.to_story_id = ss.id AND sl.link_type = 'blocks'
+                        AND bs.closed = false AND bs.deleted_date IS NULL AND bb.deleted_date IS NULL
+                    ) AS blocked
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP FUNCTION IF EXISTS get_stories_by_external_ref(uuid, varchar, varchar);
+DROP PROCEDURE IF EXISTS story_external_ref_remove(uuid, uuid, uuid);
+DROP PROCEDURE IF EXISTS story_external_ref_add(uuid, uuid, varchar, varchar, text, varchar);
+DROP TABLE IF EXISTS story_external_ref;
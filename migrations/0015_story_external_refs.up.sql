@@ -0,0 +1,171 @@
+-- References from stories to the issues they become in external trackers, status is the last status cached from the tracker --
+CREATE TABLE IF NOT EXISTS story_external_ref (
+    id UUID NOT NULL DEFAULT uuid_generate_v4() PRIMARY KEY,
+    storyboard_id UUID NOT NULL,
+    story_id UUID NOT NULL,
+    system VARCHAR(32) NOT NULL,
+    ref_key VARCHAR(128) NOT NULL,
+    url TEXT NOT NULL DEFAULT '',
+    status VARCHAR(64) NOT NULL DEFAULT '',
+    created_date TIMESTAMP DEFAULT NOW(),
+    updated_date TIMESTAMP DEFAULT NOW(),
+    UNIQUE(story_id, system, ref_key),
+    CONSTRAINT ser_storyboard_id FOREIGN KEY(storyboard_id) REFERENCES storyboard(id) ON DELETE CASCADE,
+    CONSTRAINT ser_story_id FOREIGN KEY(story_id) REFERENCES storyboard_story(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS story_external_ref_key_idx ON story_external_ref (system, ref_key);
+
+-- Add an external reference to a Storyboard Story, an existing reference to the same key has its url and status updated --
+CREATE OR REPLACE PROCEDURE story_external_ref_add(
+    storyboardId UUID, storyId UUID, refSystem VARCHAR(32), refKey VARCHAR(128), refUrl TEXT, refStatus VARCHAR(64)
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM storyboard_story WHERE id = storyId AND storyboard_id = storyboardId AND deleted_date IS NULL
+    ) THEN
+        RAISE EXCEPTION 'Story not found';
+    END IF;
+
+    INSERT INTO story_external_ref (storyboard_id, story_id, system, ref_key, url, status)
+    VALUES (storyboardId, storyId, refSystem, refKey, refUrl, refStatus)
+    ON CONFLICT (story_id, system, ref_key) DO UPDATE
+    SET url = EXCLUDED.url, status = EXCLUDED.status, updated_date = NOW();
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Remove an external reference from a Storyboard Story, returning the story it was on --
+CREATE OR REPLACE PROCEDURE story_external_ref_remove(storyboardId UUID, refId UUID, INOUT refStoryId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM story_external_ref WHERE id = refId AND storyboard_id = storyboardId
+    RETURNING story_id INTO refStoryId;
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Reference not found';
+    END IF;
+
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+END;
+$$;
+
+-- Get the stories referencing an external key on the storyboards a user can access, optionally only from one system --
+CREATE OR REPLACE FUNCTION get_stories_by_external_ref(userId UUID, refSystem VARCHAR(32), refKey VARCHAR(128)) RETURNS table (
+    id UUID, name VARCHAR(256), closed BOOL, storyboard_id UUID, storyboard_name VARCHAR(256),
+    goal_id UUID, goal_name VARCHAR(256), column_id UUID, column_name VARCHAR(256), external_ref JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT ss.id, coalesce(ss.name, ''), ss.closed, b.id, coalesce(b.name, ''), sg.id, coalesce(sg.name, ''), sc.id, coalesce(sc.name, ''),
+            json_build_object(
+                'id', ser.id, 'story_id', ser.story_id, 'system', ser.system, 'key', ser.ref_key, 'url', ser.url, 'status', ser.status
+            )
+        FROM story_external_ref ser
+        JOIN storyboard_story ss ON ss.id = ser.story_id
+        JOIN storyboard_column sc ON sc.id = ss.column_id
+        JOIN storyboard_goal sg ON sg.id = ss.goal_id
+        JOIN storyboard b ON b.id = ss.storyboard_id
+        WHERE ser.ref_key = refKey AND (refSystem = '' OR ser.system = refSystem)
+        AND ss.deleted_date IS NULL AND sc.deleted_date IS NULL AND sg.deleted_date IS NULL AND b.deleted_date IS NULL
+        AND storyboard_user_assignable(b.id, userId)
+        ORDER BY b.name, sg.sort_order, sc.sort_order, ss.sort_order;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Get Storyboard Goals, stories include their comments, assignees, labels, acceptance criteria, links and external references,
+-- a story is blocked while any story blocking it is open --
+DROP FUNCTION IF EXISTS get_storyboard_goals(uuid);
+CREATE FUNCTION get_storyboard_goals(storyboardId UUID) RETURNS table (
+    id UUID, sort_order INTEGER, name VARCHAR(256), columns JSON
+) AS $$
+BEGIN
+    RETURN QUERY
+        SELECT
+            sg.id,
+            sg.sort_order,
+            sg.name,
+            COALESCE(json_agg(to_jsonb(t) - 'goal_id' ORDER BY t.sort_order) FILTER (WHERE t.id IS NOT NULL), '[]') AS columns
+        FROM storyboard_goal sg
+        LEFT JOIN (
+            SELECT
+                sc.*,
+                COALESCE(
+                    json_agg(stss ORDER BY stss.sort_order) FILTER (WHERE stss.id IS NOT NULL), '[]'
+                ) AS stories
+            FROM storyboard_column sc
+            LEFT JOIN (
+                SELECT
+                    ss.*,
+                    COALESCE(
+                        json_agg(stcm ORDER BY stcm.created_date) FILTER (WHERE stcm.id IS NOT NULL), '[]'
+                    ) AS comments,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', u.id, 'name', u.name) ORDER BY u.name), '[]')
+                        FROM storyboard_story_assignee ssa
+                        JOIN users u ON u.id = ssa.user_id
+                        WHERE ssa.story_id = ss.id
+                    ) AS assignees,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', sl.id, 'name', sl.name, 'color', sl.color) ORDER BY sl.name), '[]')
+                        FROM storyboard_story_label ssl
+                        JOIN storyboard_label sl ON sl.id = ssl.label_id
+                        WHERE ssl.story_id = ss.id
+                    ) AS labels,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object('id', scr.id, 'story_id', scr.story_id, 'text', scr.text, 'done', scr.done, 'sort_order', scr.sort_order) ORDER BY scr.sort_order), '[]')
+                        FROM story_criterion scr
+                        WHERE scr.story_id = ss.id
+                    ) AS criteria,
+                    (
+                        SELECT COALESCE(count(*) FILTER (WHERE scr.done)::FLOAT / NULLIF(count(*), 0), 0)
+                        FROM story_criterion scr
+                        WHERE scr.story_id = ss.id
+                    ) AS criteria_ratio,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object(
+                            'id', sl.id,
+                            'type', sl.link_type,
+                            'direction', CASE WHEN sl.from_story_id = ss.id THEN 'outward' ELSE 'inward' END,
+                            'story_id', os.id,
+                            'story_name', coalesce(os.name, ''),
+                            'storyboard_id', os.storyboard_id,
+                            'closed', os.closed
+                        ) ORDER BY sl.created_date), '[]')
+                        FROM story_link sl
+                        JOIN storyboard_story os
+                            ON os.id = CASE WHEN sl.from_story_id = ss.id THEN sl.to_story_id ELSE sl.from_story_id END
+                        JOIN storyboard ob ON ob.id = os.storyboard_id
+                        WHERE (sl.from_story_id = ss.id OR sl.to_story_id = ss.id)
+                        AND os.deleted_date IS NULL AND ob.deleted_date IS NULL
+                    ) AS links,
+                    (
+                        SELECT COALESCE(json_agg(json_build_object(
+                            'id', ser.id, 'story_id', ser.story_id, 'system', ser.system, 'key', ser.ref_key, 'url', ser.url, 'status', ser.status
+                        ) ORDER BY ser.created_date), '[]')
+                        FROM story_external_ref ser
+                        WHERE ser.story_id = ss.id
+                    ) AS external_refs,
+                    EXISTS (
+                        SELECT 1 FROM story_link sl
+                        JOIN storyboard_story bs ON bs.id = sl.from_story_id
+                        JOIN storyboard bb ON bb.id = bs.storyboard_id
+                        WHERE sl.to_story_id = ss.id AND sl.link_type = 'blocks'
+                        AND bs.closed = false AND bs.deleted_date IS NULL AND bb.deleted_date IS NULL
+                    ) AS blocked
+                FROM storyboard_story ss
+                LEFT JOIN (
+                    SELECT c.*, coalesce(u.name, '') AS user_name
+                    FROM story_comment c
+                    LEFT JOIN users u ON u.id = c.user_id
+                ) stcm ON stcm.story_id = ss.id
+                WHERE ss.deleted_date IS NULL
+                GROUP BY ss.id
+            ) stss ON stss.column_id = sc.id
+            WHERE sc.deleted_date IS NULL
+            GROUP BY sc.id
+        ) t ON t.goal_id = sg.id
+        WHERE sg.storyboard_id = storyboardId AND sg.deleted_date IS NULL
+        GROUP BY sg.id
+        ORDER BY sg.sort_order;
+END;
+$$ LANGUAGE plpgsql;
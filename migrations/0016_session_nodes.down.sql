@@ -0,0 +1,29 @@
+DROP FUNCTION IF EXISTS expire_storyboard_user_sessions(varchar, interval);
+
+DROP FUNCTION IF EXISTS storyboard_user_session_add(uuid, uuid, varchar);
+CREATE FUNCTION storyboard_user_session_add(storyboardId UUID, userId UUID) RETURNS UUID AS $$
+DECLARE sessionId UUID;
+BEGIN
+    INSERT INTO storyboard_user (storyboard_id, user_id, active)
+        VALUES (storyboardId, userId, true)
+        ON CONFLICT (storyboard_id, user_id) DO UPDATE SET active = true, abandoned = false;
+
+    INSERT INTO storyboard_user_session (storyboard_id, user_id)
+        VALUES (storyboardId, userId)
+        RETURNING id INTO sessionId;
+
+    RETURN sessionId;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE PROCEDURE deactivate_all_users()
+LANGUAGE plpgsql AS $$
+BEGIN
+    DELETE FROM storyboard_user_session;
+    UPDATE storyboard_user SET active = false WHERE active = true;
+END;
+$$;
+
+DROP TABLE IF EXISTS cluster_node;
+DROP INDEX IF EXISTS sus_node_idx;
+ALTER TABLE storyboard_user_session DROP COLUMN IF EXISTS node_id;
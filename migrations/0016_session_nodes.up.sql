@@ -0,0 +1,60 @@
+-- Storyboard sessions belong to the instance holding their connection, so restarting one instance
+-- only ends its own sessions
+ALTER TABLE storyboard_user_session ADD COLUMN IF NOT EXISTS node_id VARCHAR(64) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS sus_node_idx ON storyboard_user_session (node_id);
+
+-- Instances alive per their cluster presence heartbeat, kept even while they have no connections
+CREATE TABLE IF NOT EXISTS cluster_node (
+    node_id VARCHAR(64) NOT NULL PRIMARY KEY,
+    updated_date TIMESTAMP DEFAULT NOW()
+);
+
+DROP PROCEDURE IF EXISTS deactivate_all_users();
+
+-- Add a session on the instance for the user to the storyboard, returning the session id --
+DROP FUNCTION IF EXISTS storyboard_user_session_add(uuid, uuid);
+CREATE FUNCTION storyboard_user_session_add(storyboardId UUID, userId UUID, nodeId VARCHAR(64)) RETURNS UUID AS $$
+DECLARE sessionId UUID;
+BEGIN
+    INSERT INTO storyboard_user (storyboard_id, user_id, active)
+        VALUES (storyboardId, userId, true)
+        ON CONFLICT (storyboard_id, user_id) DO UPDATE SET active = true, abandoned = false;
+
+    INSERT INTO storyboard_user_session (storyboard_id, user_id, node_id)
+        VALUES (storyboardId, userId, nodeId)
+        RETURNING id INTO sessionId;
+
+    RETURN sessionId;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Remove the sessions of the instance and of instances without a recent heartbeat, users stay active
+-- while other sessions remain. Returns the storyboards that had sessions removed --
+CREATE OR REPLACE FUNCTION expire_storyboard_user_sessions(nodeId VARCHAR(64), heartbeatTtl INTERVAL) RETURNS table (
+    storyboard_id UUID
+) AS $$
+DECLARE
+    storyboardIds UUID[];
+    userIds UUID[];
+BEGIN
+    DELETE FROM cluster_node cn WHERE cn.updated_date < NOW() - heartbeatTtl;
+
+    WITH expired AS (
+        DELETE FROM storyboard_user_session sus
+        WHERE sus.node_id = nodeId
+        OR NOT EXISTS (SELECT 1 FROM cluster_node cn WHERE cn.node_id = sus.node_id)
+        RETURNING sus.storyboard_id, sus.user_id
+    )
+    SELECT array_agg(e.storyboard_id), array_agg(e.user_id) INTO storyboardIds, userIds FROM expired e;
+
+    UPDATE storyboard_user su
+        SET active = EXISTS (
+            SELECT 1 FROM storyboard_user_session sus
+            WHERE sus.storyboard_id = su.storyboard_id AND sus.user_id = su.user_id
+        )
+        FROM unnest(storyboardIds, userIds) AS e(storyboard_id, user_id)
+        WHERE su.storyboard_id = e.storyboard_id AND su.user_id = e.user_id;
+
+    RETURN QUERY SELECT DISTINCT e.storyboard_id FROM unnest(storyboardIds) AS e(storyboard_id);
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,71 @@
+-- Move a Storyboard Story to a new column and/or goal --
+CREATE OR REPLACE PROCEDURE move_story(storyId UUID, goalId UUID, columnId UUID, placeBefore TEXT)
+LANGUAGE plpgsql AS $$
+DECLARE storyboardId UUID;
+DECLARE srcColumnId UUID;
+DECLARE srcSortOrder INTEGER;
+DECLARE targetSortOrder INTEGER;
+BEGIN
+    -- Get Story current details
+    SELECT
+        storyboard_id, column_id, sort_order
+    INTO
+        storyboardId, srcColumnId, srcSortOrder
+    FROM storyboard_story WHERE id = storyId;
+
+    -- Remove from source column ordering, keeping the story (and its comments) intact
+    UPDATE storyboard_story SET sort_order = NULL WHERE id = storyId;
+    -- Update sort order in src column
+    UPDATE storyboard_story ss SET sort_order = (t.sort_order - 1)
+    FROM (
+        SELECT id, sort_order FROM storyboard_story
+        WHERE column_id = srcColumnId AND sort_order > srcSortOrder
+        ORDER BY sort_order ASC
+        FOR UPDATE
+    ) AS t
+    WHERE ss.id = t.id;
+
+    -- Get target sort order
+    IF placeBefore = '' THEN
+        SELECT coalesce(max(sort_order), 0) + 1 INTO targetSortOrder FROM storyboard_story WHERE column_id = columnId;
+    ELSE
+        SELECT sort_order INTO targetSortOrder FROM storyboard_story WHERE column_id = columnId AND id = placeBefore::UUID;
+    END IF;
+
+    -- Update sort order for any story that should come after newly moved story
+    UPDATE storyboard_story ss SET sort_order = (t.sort_order + 1)
+    FROM (
+        SELECT id, sort_order FROM storyboard_story
+        WHERE column_id = columnId AND sort_order >= targetSortOrder
+        ORDER BY sort_order DESC
+        FOR UPDATE
+    ) AS t
+    WHERE ss.id = t.id;
+
+    -- Finally, place the story in its ordered place
+    UPDATE storyboard_story
+    SET goal_id = goalId, column_id = columnId, sort_order = targetSortOrder, updated_date = NOW()
+    WHERE id = storyId;
+
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+
+    COMMIT;
+END;
+$$;
+
+-- Assign a Storyboard Story to a Release, a NULL release unassigns it --
+CREATE OR REPLACE PROCEDURE update_story_release(storyboardId UUID, storyId UUID, releaseId UUID)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF releaseId IS NOT NULL AND NOT EXISTS (
+        SELECT 1 FROM storyboard_release WHERE id = releaseId AND storyboard_id = storyboardId
+    ) THEN
+        RAISE EXCEPTION 'Release not found';
+    END IF;
+
+    UPDATE storyboard_story SET release_id = releaseId, updated_date = NOW() WHERE id = storyId AND storyboard_id = storyboardId;
+    UPDATE storyboard SET updated_date = NOW() WHERE id = storyboardId;
+
+    COMMIT;
+END;
+$$;
@@ -0,0 +1,31 @@
+-- Link a Storyboard Story to a story on any storyboard the user can access, returning the linked storys storyboard --
+CREATE OR REPLACE PROCEDURE story_link_add(
+    storyboardId UUID, storyId UUID, toStoryId UUID, linkType VARCHAR(16), userId UUID, INOUT toStoryboardId UUID
+)
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM storyboard_story WHERE id = storyId AND storyboard_id = storyboardId AND deleted_date IS NULL
+    ) THEN
+        RAISE EXCEPTION 'Story not found';
+    END IF;
+
+    SELECT ss.storyboard_id INTO toStoryboardId
+    FROM storyboard_story ss
+    JOIN storyboard b ON b.id = ss.storyboard_id
+    WHERE ss.id = toStoryId AND ss.deleted_date IS NULL AND b.deleted_date IS NULL
+    AND storyboard_user_assignable(b.id, userId);
+    IF NOT FOUND THEN
+        RAISE EXCEPTION 'Linked story not found';
+    END IF;
+
+    IF linkType = 'blocks' AND story_link_creates_cycle(storyId, toStoryId) THEN
+        RAISE EXCEPTION 'Blocking link would create a cycle' USING ERRCODE = 'check_violation';
+    END IF;
+
+    INSERT INTO story_link (from_story_id, to_story_id, link_type, created_by)
+    VALUES (storyId, toStoryId, linkType, userId)
+    ON CONFLICT DO NOTHING;
+    UPDATE storyboard SET updated_date = NOW() WHERE id IN (storyboardId, toStoryboardId);
+END;
+$$;
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+)
+
+// AddStoryAssignee assigns a user to a story, the user must be on the storyboard or a member of one of its teams
+func (d *Database) AddStoryAssignee(StoryboardID string, UserID string, StoryID string, AssigneeID string) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"assignee"}, ItemID: AssigneeID}
+	if err := d.changeOperation(StoryboardID, UserID, "assign_story", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call story_assignee_add($1, $2, $3);`, StoryboardID, StoryID, AssigneeID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// RemoveStoryAssignee unassigns a user from a story
+func (d *Database) RemoveStoryAssignee(StoryboardID string, UserID string, StoryID string, AssigneeID string) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"assignee"}, ItemID: AssigneeID}
+	if err := d.changeOperation(StoryboardID, UserID, "unassign_story", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call story_assignee_remove($1, $2, $3);`, StoryboardID, StoryID, AssigneeID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// GetUserAssignedStories gets the open stories assigned to a user on the storyboards they can access
+func (d *Database) GetUserAssignedStories(UserID string) ([]*AssignedStory, error) {
+	var stories = make([]*AssignedStory, 0)
+	rows, err := d.db.Query(
+		`SELECT * FROM get_user_assigned_stories($1);`,
+		UserID,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var s AssignedStory
+		var labels string
+		if err := rows.Scan(
+			&s.StoryID,
+			&s.StoryName,
+			&s.StoryColor,
+			&s.StoryPoints,
+			&s.StoryboardID,
+			&s.StoryboardName,
+			&s.GoalID,
+			&s.GoalName,
+			&s.ColumnID,
+			&s.ColumnName,
+			&labels,
+		); err != nil {
+			log.Println(err)
+			continue
+		}
+		if err := json.Unmarshal([]byte(labels), &s.Labels); err != nil {
+			log.Println(err)
+		}
+		stories = append(stories, &s)
+	}
+
+	return stories, nil
+}
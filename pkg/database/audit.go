@@ -0,0 +1,140 @@
+package database
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// AuditEntry A change recorded in the audit log, Before and After hold the
+// targets values (or the requested change) and are nil when not applicable
+type AuditEntry struct {
+	AuditID        int64       `json:"id"`
+	ActorID        string      `json:"actor_id"`
+	ActorName      string      `json:"actor_name"`
+	Action         string      `json:"action"`
+	TargetType     string      `json:"target_type"`
+	TargetID       string      `json:"target_id"`
+	StoryboardID   string      `json:"storyboard_id"`
+	OrganizationID string      `json:"organization_id"`
+	DepartmentID   string      `json:"department_id"`
+	TeamID         string      `json:"team_id"`
+	Before         interface{} `json:"before"`
+	After          interface{} `json:"after"`
+	CreatedDate    string      `json:"created_date"`
+}
+
+// AuditFilter narrows an audit log query, empty fields match everything,
+// Since and Until are inclusive timestamps
+type AuditFilter struct {
+	ActorID        string
+	Action         string
+	TargetType     string
+	TargetID       string
+	StoryboardID   string
+	OrganizationID string
+	TeamID         string
+	Since          string
+	Until          string
+}
+
+// auditValue gets the JSON for an audit before or after value, NULL when nil
+func auditValue(v interface{}) interface{} {
+	if v == nil {
+		return nil
+	}
+	if raw, ok := v.(json.RawMessage); ok {
+		if !json.Valid(raw) {
+			b, _ := json.Marshal(string(raw))
+			return string(b)
+		}
+		return string(raw)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+
+	return string(b)
+}
+
+// RecordAudit appends an entry to the audit log, failures are logged rather than
+// returned as the change being audited has already been made
+func (d *Database) RecordAudit(e *AuditEntry) {
+	if _, err := d.db.Exec(
+		`call audit_log_add(NULLIF($1, '')::UUID, $2, $3, NULLIF($4, ''), NULLIF($5, '')::UUID,
+			NULLIF($6, '')::UUID, NULLIF($7, '')::UUID, NULLIF($8, '')::UUID, $9, $10);`,
+		e.ActorID,
+		e.Action,
+		e.TargetType,
+		e.TargetID,
+		e.StoryboardID,
+		e.OrganizationID,
+		e.DepartmentID,
+		e.TeamID,
+		auditValue(e.Before),
+		auditValue(e.After),
+	); err != nil {
+		log.Println("unable to record audit entry: ", err)
+	}
+}
+
+// GetAuditLog gets the audit log entries matching the filter, newest first
+func (d *Database) GetAuditLog(Filter *AuditFilter, Limit int, Offset int) []*AuditEntry {
+	var entries = make([]*AuditEntry, 0)
+
+	rows, err := d.db.Query(
+		`SELECT
+			al.id, coalesce(al.actor_id::TEXT, ''), coalesce(u.name, ''), al.action, al.target_type,
+			coalesce(al.target_id, ''), coalesce(al.storyboard_id::TEXT, ''), coalesce(al.organization_id::TEXT, ''),
+			coalesce(al.department_id::TEXT, ''), coalesce(al.team_id::TEXT, ''),
+			coalesce(al.before_value, 'null'::JSONB), coalesce(al.after_value, 'null'::JSONB), al.created_date
+		FROM audit_log al
+		LEFT JOIN users u ON u.id = al.actor_id
+		WHERE ($1::TEXT = '' OR al.actor_id::TEXT = $1)
+		AND ($2::TEXT = '' OR al.action = $2)
+		AND ($3::TEXT = '' OR al.target_type = $3)
+		AND ($4::TEXT = '' OR al.target_id = $4)
+		AND ($5::TEXT = '' OR al.storyboard_id::TEXT = $5)
+		AND ($6::TEXT = '' OR al.organization_id::TEXT = $6)
+		AND ($7::TEXT = '' OR al.team_id::TEXT = $7)
+		AND ($8::TEXT = '' OR al.created_date >= $8::TEXT::TIMESTAMP)
+		AND ($9::TEXT = '' OR al.created_date <= $9::TEXT::TIMESTAMP)
+		ORDER BY al.id DESC
+		LIMIT $10
+		OFFSET $11;`,
+		Filter.ActorID,
+		Filter.Action,
+		Filter.TargetType,
+		Filter.TargetID,
+		Filter.StoryboardID,
+		Filter.OrganizationID,
+		Filter.TeamID,
+		Filter.Since,
+		Filter.Until,
+		Limit,
+		Offset,
+	)
+	if err != nil {
+		log.Println(err)
+		return entries
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e AuditEntry
+		var before, after []byte
+		if err := rows.Scan(
+			&e.AuditID, &e.ActorID, &e.ActorName, &e.Action, &e.TargetType, &e.TargetID, &e.StoryboardID,
+			&e.OrganizationID, &e.DepartmentID, &e.TeamID, &before, &after, &e.CreatedDate,
+		); err != nil {
+			log.Println(err)
+			continue
+		}
+		e.Before = json.RawMessage(before)
+		e.After = json.RawMessage(after)
+		entries = append(entries, &e)
+	}
+
+	return entries
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAuditValue(t *testing.T) {
+	if v := auditValue(nil); v != nil {
+		t.Error("Expected nil, got ", v)
+	}
+	if v := auditValue(json.RawMessage(`{"name":"Onboarding"}`)); v != `{"name":"Onboarding"}` {
+		t.Error(`Expected {"name":"Onboarding"}, got `, v)
+	}
+	if v := auditValue(json.RawMessage("c1")); v != `"c1"` {
+		t.Error(`Expected "c1", got `, v)
+	}
+	if v := auditValue(map[string]string{"role": "ADMIN"}); v != `{"role":"ADMIN"}` {
+		t.Error(`Expected {"role":"ADMIN"}, got `, v)
+	}
+}
@@ -1,21 +1,22 @@
 package database
 
 import (
+	"database/sql"
 	"errors"
-	"log"
 )
 
 // CreateStoryboardColumn adds a new column to a Storyboard
 func (d *Database) CreateStoryboardColumn(StoryboardID string, GoalID string, userID string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call create_storyboard_column($1, $2);`, StoryboardID, GoalID,
-	); err != nil {
-		log.Println(err)
+	if err := d.createOperation(StoryboardID, userID, "add_column", "column", "goal_id", GoalID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call create_storyboard_column($1, $2);`, StoryboardID, GoalID)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -25,18 +26,17 @@ func (d *Database) CreateStoryboardColumn(StoryboardID string, GoalID string, us
 
 // ReviseStoryboardColumn revises a storyboard column
 func (d *Database) ReviseStoryboardColumn(StoryboardID string, UserID string, ColumnID string, ColumnName string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, UserID)
+	err := d.ConfirmEditor(StoryboardID, UserID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call revise_storyboard_column($1, $2, $3);`,
-		StoryboardID,
-		ColumnID,
-		ColumnName,
-	); err != nil {
-		log.Println(err)
+	change := &OperationState{Kind: "column", ID: ColumnID, Fields: []string{"name"}}
+	if err := d.changeOperation(StoryboardID, UserID, "revise_column", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call revise_storyboard_column($1, $2, $3);`, StoryboardID, ColumnID, ColumnName)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -44,16 +44,38 @@ func (d *Database) ReviseStoryboardColumn(StoryboardID string, UserID string, Co
 	return goals, nil
 }
 
-// DeleteStoryboardColumn removes a column from the current board by ID
+// MoveStoryboardColumn moves a column with its stories to before another column (or last) of a goal by ID
+func (d *Database) MoveStoryboardColumn(StoryboardID string, UserID string, ColumnID string, GoalID string, PlaceBefore string) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	change := &OperationState{Kind: "column", ID: ColumnID, Fields: []string{"position"}}
+	if err := d.changeOperation(StoryboardID, UserID, "move_column", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call move_storyboard_column($1, $2, $3, $4);`, StoryboardID, ColumnID, GoalID, PlaceBefore)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// DeleteStoryboardColumn moves a column and its stories to the storyboards trash by ID
 func (d *Database) DeleteStoryboardColumn(StoryboardID string, userID string, ColumnID string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call delete_storyboard_column($1);`, ColumnID); err != nil {
-		log.Println(err)
+	if err := d.deleteOperation(StoryboardID, userID, "delete_column", "column", ColumnID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call delete_storyboard_column($1, $2);`, ColumnID, userID)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// AddStoryCriterion adds an acceptance criterion to the end of a storys checklist
+func (d *Database) AddStoryCriterion(StoryboardID string, UserID string, StoryID string, Text string) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"criterion"}}
+	if err := d.changeOperation(StoryboardID, UserID, "add_story_criterion", change, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`call story_criterion_add($1, $2, $3);`, StoryboardID, StoryID, Text); err != nil {
+			return err
+		}
+
+		// the new criterion is the last of the storys checklist
+		return tx.QueryRow(
+			`SELECT id FROM story_criterion WHERE story_id = $1 ORDER BY sort_order DESC LIMIT 1;`, StoryID,
+		).Scan(&change.ItemID)
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// GetStoryCriterion gets a storyboard story acceptance criterion by ID
+func (d *Database) GetStoryCriterion(StoryboardID string, CriterionID string) (*StoryCriterion, error) {
+	var c StoryCriterion
+
+	if err := d.db.QueryRow(
+		`SELECT id, story_id, text, done, sort_order FROM story_criterion WHERE id = $1 AND storyboard_id = $2;`,
+		CriterionID,
+		StoryboardID,
+	).Scan(&c.CriterionID, &c.StoryID, &c.Text, &c.Done, &c.SortOrder); err != nil {
+		if err != sql.ErrNoRows {
+			log.Println(err)
+		}
+		return nil, errors.New("Criterion not found")
+	}
+
+	return &c, nil
+}
+
+// ReviseStoryCriterion updates an acceptance criterions text
+func (d *Database) ReviseStoryCriterion(StoryboardID string, UserID string, CriterionID string, Text string) ([]*StoryboardGoal, error) {
+	return d.changeStoryCriterion(StoryboardID, UserID, CriterionID, "update_story_criterion",
+		`call story_criterion_edit($1, $2, $3);`, Text)
+}
+
+// ToggleStoryCriterion marks an acceptance criterion done or open
+func (d *Database) ToggleStoryCriterion(StoryboardID string, UserID string, CriterionID string, Done bool) ([]*StoryboardGoal, error) {
+	return d.changeStoryCriterion(StoryboardID, UserID, CriterionID, "toggle_story_criterion",
+		`call story_criterion_toggle($1, $2, $3);`, Done)
+}
+
+// UpdateStoryCriterion revises an acceptance criterions text and/or marks it done or open as one change,
+// nil fields are left unchanged
+func (d *Database) UpdateStoryCriterion(StoryboardID string, UserID string, CriterionID string, Text *string, Done *bool) ([]*StoryboardGoal, error) {
+	if Text == nil && Done == nil {
+		return d.GetStoryboardGoals(StoryboardID), nil
+	}
+
+	Operation := "update_story_criterion"
+	if Text == nil {
+		Operation = "toggle_story_criterion"
+	}
+
+	return d.changeStoryCriterionWith(StoryboardID, UserID, CriterionID, Operation, func(tx *sql.Tx) error {
+		if Text != nil {
+			if _, err := tx.Exec(`call story_criterion_edit($1, $2, $3);`, StoryboardID, CriterionID, *Text); err != nil {
+				return err
+			}
+		}
+		if Done != nil {
+			if _, err := tx.Exec(`call story_criterion_toggle($1, $2, $3);`, StoryboardID, CriterionID, *Done); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// MoveStoryCriterion reorders an acceptance criterion to before another in its checklist (or last)
+func (d *Database) MoveStoryCriterion(StoryboardID string, UserID string, CriterionID string, PlaceBefore string) ([]*StoryboardGoal, error) {
+	return d.changeStoryCriterion(StoryboardID, UserID, CriterionID, "move_story_criterion",
+		`call story_criterion_move($1, $2, $3);`, PlaceBefore)
+}
+
+// DeleteStoryCriterion removes an acceptance criterion from its storys checklist
+func (d *Database) DeleteStoryCriterion(StoryboardID string, UserID string, CriterionID string) ([]*StoryboardGoal, error) {
+	return d.changeStoryCriterion(StoryboardID, UserID, CriterionID, "delete_story_criterion",
+		`call story_criterion_delete($1, $2);`)
+}
+
+// changeStoryCriterion calls the procedure changing an existing acceptance criterion with the storyboard and
+// criterion IDs followed by the Args, recording the change to the criterions story as the operation
+func (d *Database) changeStoryCriterion(StoryboardID string, UserID string, CriterionID string, Operation string, Query string, Args ...interface{}) ([]*StoryboardGoal, error) {
+	return d.changeStoryCriterionWith(StoryboardID, UserID, CriterionID, Operation, func(tx *sql.Tx) error {
+		_, err := tx.Exec(Query, append([]interface{}{StoryboardID, CriterionID}, Args...)...)
+		return err
+	})
+}
+
+// changeStoryCriterionWith changes an existing acceptance criterion in the Mutate transaction,
+// recording the change to the criterions story as the operation
+func (d *Database) changeStoryCriterionWith(StoryboardID string, UserID string, CriterionID string, Operation string, Mutate func(tx *sql.Tx) error) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	c, err := d.GetStoryCriterion(StoryboardID, CriterionID)
+	if err != nil {
+		return nil, err
+	}
+
+	change := &OperationState{Kind: "story", ID: c.StoryID, Fields: []string{"criterion"}, ItemID: CriterionID}
+	if err := d.changeOperation(StoryboardID, UserID, Operation, change, Mutate); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
@@ -9,9 +9,35 @@ import (
 	"github.com/spf13/viper"
 )
 
-// New runs db migrations, sets up a db connection pool
-// and sets previously active users to false during startup
-func New(AdminEmail string, schemaSQL string) *Database {
+// New sets up a db connection pool, runs any pending db migrations
+// and ends the storyboard sessions left by this and any stopped instance during startup
+func New(AdminEmail string, NodeID string, migrations []*Migration) *Database {
+	d := Connect()
+
+	if _, err := d.MigrateUp(migrations); err != nil {
+		log.Fatal(err)
+	}
+
+	// on server start end the sessions of instances no longer running, other instances keep theirs
+	if _, err := d.ExpireUserSessions(NodeID); err != nil {
+		log.Println(err)
+	}
+
+	// on server start if admin email is specified set that user to ADMIN type
+	if AdminEmail != "" {
+		if _, err := d.db.Exec(
+			`call promote_user_by_email($1);`,
+			AdminEmail,
+		); err != nil {
+			log.Println(err)
+		}
+	}
+
+	return d
+}
+
+// Connect sets up a db connection pool without running migrations
+func Connect() *Database {
 	var d = &Database{
 		// read environment variables and sets up mailserver configuration values
 		config: &Config{
@@ -24,7 +50,18 @@ func New(AdminEmail string, schemaSQL string) *Database {
 		},
 	}
 
-	psqlInfo := fmt.Sprintf(
+	pdb, err := sql.Open("postgres", d.connectionString())
+	if err != nil {
+		log.Fatal("error connecting to the database: ", err)
+	}
+	d.db = pdb
+
+	return d
+}
+
+// connectionString builds the postgres connection string from the db config
+func (d *Database) connectionString() string {
+	return fmt.Sprintf(
 		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		d.config.host,
 		d.config.port,
@@ -33,32 +70,4 @@ func New(AdminEmail string, schemaSQL string) *Database {
 		d.config.dbname,
 		d.config.sslmode,
 	)
-
-	pdb, err := sql.Open("postgres", psqlInfo)
-	if err != nil {
-		log.Fatal("error connecting to the database: ", err)
-	}
-	d.db = pdb
-
-	if _, err := d.db.Exec(schemaSQL); err != nil {
-		log.Fatal(err)
-	}
-
-	// on server start reset all users to active false for storyboards
-	if _, err := d.db.Exec(
-		`call deactivate_all_users();`); err != nil {
-		log.Println(err)
-	}
-
-	// on server start if admin email is specified set that user to ADMIN type
-	if AdminEmail != "" {
-		if _, err := d.db.Exec(
-			`call promote_user_by_email($1);`,
-			AdminEmail,
-		); err != nil {
-			log.Println(err)
-		}
-	}
-
-	return d
 }
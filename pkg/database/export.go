@@ -0,0 +1,251 @@
+package database
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StoryboardExportVersion is the current version of the storyboard export document
+const StoryboardExportVersion = 1
+
+// StoryboardExport is a versioned self-contained storyboard document,
+// the IDs within are only used to relate items to each other on import
+type StoryboardExport struct {
+	Version             int                  `json:"version"`
+	ExportedDate        time.Time            `json:"exported_date"`
+	StoryboardName      string               `json:"name"`
+	ColorLegend         []*Color             `json:"color_legend"`
+	Personas            []*StoryboardPersona `json:"personas"`
+	Releases            []*StoryboardRelease `json:"releases"`
+	Labels              []*StoryboardLabel   `json:"labels"`
+	RequireCriteriaDone bool                 `json:"require_criteria_done"`
+	Goals               []*StoryboardGoal    `json:"goals"`
+}
+
+// ExportStoryboard creates the export document for a storyboard
+func (d *Database) ExportStoryboard(StoryboardID string) (*StoryboardExport, error) {
+	storyboard, err := d.GetStoryboard(StoryboardID)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStoryboardExport(storyboard), nil
+}
+
+// newStoryboardExport creates the export document from a storyboard
+func newStoryboardExport(storyboard *Storyboard) *StoryboardExport {
+	return &StoryboardExport{
+		Version:             StoryboardExportVersion,
+		ExportedDate:        time.Now().UTC(),
+		StoryboardName:      storyboard.StoryboardName,
+		ColorLegend:         storyboard.ColorLegend,
+		Personas:            storyboard.Personas,
+		Releases:            storyboard.Releases,
+		Labels:              storyboard.Labels,
+		RequireCriteriaDone: storyboard.RequireCriteriaDone,
+		Goals:               storyboard.Goals,
+	}
+}
+
+// ImportStoryboard recreates an exported storyboard with fresh IDs owned by the user,
+// optionally adding it to a team. Nothing is kept if any part of the import fails.
+func (d *Database) ImportStoryboard(OwnerID string, TeamID string, export *StoryboardExport) (*Storyboard, error) {
+	if export.Version < 1 || export.Version > StoryboardExportVersion {
+		return nil, errors.New("Invalid export version")
+	}
+	if export.StoryboardName == "" {
+		return nil, errors.New("Invalid export, storyboard name is required")
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var StoryboardID string
+	if err := tx.QueryRow(
+		`INSERT INTO storyboard (owner_id, name) VALUES ($1, $2) RETURNING id;`,
+		OwnerID,
+		export.StoryboardName,
+	).Scan(&StoryboardID); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	if export.RequireCriteriaDone {
+		if _, err := tx.Exec(
+			`UPDATE storyboard SET require_criteria_done = true WHERE id = $1;`, StoryboardID); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	// without a color legend the storyboard keeps the default legend
+	if export.ColorLegend != nil {
+		colorLegend, _ := json.Marshal(export.ColorLegend)
+		if _, err := tx.Exec(
+			`UPDATE storyboard SET color_legend = $2 WHERE id = $1;`, StoryboardID, string(colorLegend)); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	for _, p := range export.Personas {
+		if _, err := tx.Exec(
+			`INSERT INTO storyboard_persona (storyboard_id, name, role, description) VALUES ($1, $2, $3, $4);`,
+			StoryboardID, p.Name, p.Role, p.Description,
+		); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	releaseIDs := make(map[string]string)
+	releases := append([]*StoryboardRelease(nil), export.Releases...)
+	sort.SliceStable(releases, func(i, j int) bool { return releases[i].SortOrder < releases[j].SortOrder })
+	for i, sr := range releases {
+		var ReleaseID string
+		if err := tx.QueryRow(
+			`INSERT INTO storyboard_release (storyboard_id, name, sort_order, target_date) VALUES ($1, $2, $3, $4) RETURNING id;`,
+			StoryboardID, sr.ReleaseName, i+1, nullString(sr.TargetDate),
+		).Scan(&ReleaseID); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		releaseIDs[sr.ReleaseID] = ReleaseID
+	}
+
+	labelIDs := make(map[string]string)
+	for _, l := range export.Labels {
+		var LabelID string
+		if err := tx.QueryRow(
+			`INSERT INTO storyboard_label (storyboard_id, name, color) VALUES ($1, $2, $3) RETURNING id;`,
+			StoryboardID, l.Name, labelColor(l.Color),
+		).Scan(&LabelID); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		labelIDs[l.LabelID] = LabelID
+	}
+
+	goals := append([]*StoryboardGoal(nil), export.Goals...)
+	sort.SliceStable(goals, func(i, j int) bool { return goals[i].SortOrder < goals[j].SortOrder })
+	for gi, g := range goals {
+		var GoalID string
+		if err := tx.QueryRow(
+			`INSERT INTO storyboard_goal (storyboard_id, name, sort_order) VALUES ($1, $2, $3) RETURNING id;`,
+			StoryboardID, g.GoalName, gi+1,
+		).Scan(&GoalID); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+
+		columns := append([]*StoryboardColumn(nil), g.Columns...)
+		sort.SliceStable(columns, func(i, j int) bool { return columns[i].SortOrder < columns[j].SortOrder })
+		for ci, c := range columns {
+			var ColumnID string
+			if err := tx.QueryRow(
+				`INSERT INTO storyboard_column (storyboard_id, goal_id, name, sort_order) VALUES ($1, $2, $3, $4) RETURNING id;`,
+				StoryboardID, GoalID, c.ColumnName, ci+1,
+			).Scan(&ColumnID); err != nil {
+				log.Println(err)
+				return nil, err
+			}
+
+			stories := append([]*StoryboardStory(nil), c.Stories...)
+			sort.SliceStable(stories, func(i, j int) bool { return stories[i].SortOrder < stories[j].SortOrder })
+			for si, st := range stories {
+				color := st.StoryColor
+				if color == "" {
+					color = "gray"
+				}
+
+				var StoryID string
+				if err := tx.QueryRow(
+					`INSERT INTO storyboard_story
+						(storyboard_id, goal_id, column_id, name, content, color, points, closed, sort_order, release_id)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id;`,
+					StoryboardID, GoalID, ColumnID, st.StoryName, st.StoryContent, color,
+					st.StoryPoints, st.StoryClosed, si+1, nullString(releaseIDs[st.ReleaseID]),
+				).Scan(&StoryID); err != nil {
+					log.Println(err)
+					return nil, err
+				}
+
+				for _, l := range st.Labels {
+					if LabelID, ok := labelIDs[l.LabelID]; ok {
+						if _, err := tx.Exec(
+							`INSERT INTO storyboard_story_label (story_id, label_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;`,
+							StoryID, LabelID,
+						); err != nil {
+							log.Println(err)
+							return nil, err
+						}
+					}
+				}
+
+				criteria := append([]*StoryCriterion(nil), st.Criteria...)
+				sort.SliceStable(criteria, func(i, j int) bool { return criteria[i].SortOrder < criteria[j].SortOrder })
+				for ci, c := range criteria {
+					if _, err := tx.Exec(
+						`INSERT INTO story_criterion (storyboard_id, story_id, text, done, sort_order) VALUES ($1, $2, $3, $4, $5);`,
+						StoryboardID, StoryID, c.Text, c.Done, ci+1,
+					); err != nil {
+						log.Println(err)
+						return nil, err
+					}
+				}
+
+				for _, ref := range st.ExternalRefs {
+					if !ValidExternalRef(ref.System, ref.Key, ref.URL) {
+						continue
+					}
+					if _, err := tx.Exec(
+						`INSERT INTO story_external_ref (storyboard_id, story_id, system, ref_key, url, status)
+						VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT DO NOTHING;`,
+						StoryboardID, StoryID, ExternalRefSystem(ref.System), strings.TrimSpace(ref.Key), ref.URL, ref.Status,
+					); err != nil {
+						log.Println(err)
+						return nil, err
+					}
+				}
+
+				// comments keep their author when they exist on this instance, otherwise belong to the importer,
+				// replies are linked to the imported comment they reply to
+				commentIDs := make(map[string]string)
+				for _, cm := range st.Comments {
+					var CommentID string
+					if err := tx.QueryRow(
+						`INSERT INTO story_comment (storyboard_id, story_id, user_id, comment, parent_id)
+						VALUES ($1, $2, COALESCE((SELECT id FROM users WHERE id::TEXT = $3), $4), $5, $6) RETURNING id;`,
+						StoryboardID, StoryID, cm.UserID, OwnerID, cm.Comment, nullString(commentIDs[cm.ParentID]),
+					).Scan(&CommentID); err != nil {
+						log.Println(err)
+						return nil, err
+					}
+					commentIDs[cm.CommentID] = CommentID
+				}
+			}
+		}
+	}
+
+	if TeamID != "" {
+		if _, err := tx.Exec(`SELECT team_storyboard_add($1, $2);`, TeamID, StoryboardID); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return d.GetStoryboard(StoryboardID)
+}
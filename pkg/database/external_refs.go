@@ -0,0 +1,166 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// ExternalRefSystem normalizes the name of an external tracker so references to it match regardless of case
+func ExternalRefSystem(System string) string {
+	return strings.ToLower(strings.TrimSpace(System))
+}
+
+// ValidExternalRef is whether an external reference has a system and key that fit, and either no URL
+// or an absolute http(s) URL
+func ValidExternalRef(System string, Key string, URL string) bool {
+	System = ExternalRefSystem(System)
+	Key = strings.TrimSpace(Key)
+	if System == "" || len(System) > 32 || Key == "" || len(Key) > 128 {
+		return false
+	}
+	if URL == "" {
+		return true
+	}
+
+	u, err := url.Parse(URL)
+
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// AddStoryExternalRef adds a reference to an external tracker issue to a story,
+// re-adding a reference to the same key updates its URL and cached status
+func (d *Database) AddStoryExternalRef(StoryboardID string, UserID string, StoryID string, System string, Key string, URL string, Status string) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+	if !ValidExternalRef(System, Key, URL) {
+		return nil, errors.New("Invalid external reference")
+	}
+
+	// re-adding a key updates its reference in place, so it's recorded as it was
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"external_ref"}}
+	if err := d.db.QueryRow(
+		`SELECT id FROM story_external_ref WHERE story_id = $1 AND system = $2 AND ref_key = $3;`,
+		StoryID, ExternalRefSystem(System), strings.TrimSpace(Key),
+	).Scan(&change.ItemID); err != nil && err != sql.ErrNoRows {
+		log.Println(err)
+		return nil, err
+	}
+
+	if err := d.changeOperation(StoryboardID, UserID, "add_story_external_ref", change, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(
+			`call story_external_ref_add($1, $2, $3, $4, $5, $6);`,
+			StoryboardID,
+			StoryID,
+			ExternalRefSystem(System),
+			strings.TrimSpace(Key),
+			URL,
+			Status,
+		); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(
+			`SELECT id FROM story_external_ref WHERE story_id = $1 AND system = $2 AND ref_key = $3;`,
+			StoryID, ExternalRefSystem(System), strings.TrimSpace(Key),
+		).Scan(&change.ItemID)
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// RemoveStoryExternalRef removes an external reference from one of the storyboards stories,
+// returning the ID of the story it was on
+func (d *Database) RemoveStoryExternalRef(StoryboardID string, UserID string, RefID string) ([]*StoryboardGoal, string, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, "", errors.New("Incorrect permissions")
+	}
+
+	StoryID, err := d.GetStoryExternalRefStoryID(StoryboardID, RefID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"external_ref"}, ItemID: RefID}
+	if err := d.changeOperation(StoryboardID, UserID, "remove_story_external_ref", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call story_external_ref_remove($1, $2, NULL);`, StoryboardID, RefID)
+		return err
+	}); err != nil {
+		return nil, "", err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, StoryID, nil
+}
+
+// GetStoryExternalRefStoryID gets the ID of the story an external reference on one of the storyboards stories is on
+func (d *Database) GetStoryExternalRefStoryID(StoryboardID string, RefID string) (string, error) {
+	var StoryID string
+
+	if err := d.db.QueryRow(
+		`SELECT story_id FROM story_external_ref WHERE id = $1 AND storyboard_id = $2;`,
+		RefID,
+		StoryboardID,
+	).Scan(&StoryID); err != nil {
+		if err != sql.ErrNoRows {
+			log.Println(err)
+		}
+		return "", errors.New("External reference not found")
+	}
+
+	return StoryID, nil
+}
+
+// GetStoriesByExternalRef gets the stories referencing an external key on the storyboards the user can access,
+// an empty System matches references from any tracker
+func (d *Database) GetStoriesByExternalRef(UserID string, System string, Key string) ([]*ReferencedStory, error) {
+	var stories = make([]*ReferencedStory, 0)
+	rows, err := d.db.Query(
+		`SELECT * FROM get_stories_by_external_ref($1, $2, $3);`,
+		UserID,
+		ExternalRefSystem(System),
+		strings.TrimSpace(Key),
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var s ReferencedStory
+		var ref string
+		if err := rows.Scan(
+			&s.StoryID,
+			&s.StoryName,
+			&s.StoryClosed,
+			&s.StoryboardID,
+			&s.StoryboardName,
+			&s.GoalID,
+			&s.GoalName,
+			&s.ColumnID,
+			&s.ColumnName,
+			&ref,
+		); err != nil {
+			log.Println(err)
+			continue
+		}
+		if err := json.Unmarshal([]byte(ref), &s.ExternalRef); err != nil {
+			log.Println(err)
+		}
+		stories = append(stories, &s)
+	}
+
+	return stories, nil
+}
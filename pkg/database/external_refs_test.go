@@ -0,0 +1,30 @@
+package database
+
+import "testing"
+
+func TestValidExternalRef(t *testing.T) {
+	if !ValidExternalRef("Jira", "PROJ-123", "https://example.atlassian.net/browse/PROJ-123") {
+		t.Error("Expected Jira reference with https URL to be valid")
+	}
+	if !ValidExternalRef("github", "owner/repo#12", "") {
+		t.Error("Expected reference without URL to be valid")
+	}
+	if ValidExternalRef(" ", "PROJ-123", "") {
+		t.Error("Expected reference without system to be invalid")
+	}
+	if ValidExternalRef("jira", "", "") {
+		t.Error("Expected reference without key to be invalid")
+	}
+	if ValidExternalRef("jira", "PROJ-123", "javascript:alert(1)") {
+		t.Error("Expected reference with non http URL to be invalid")
+	}
+	if ValidExternalRef("jira", "PROJ-123", "/browse/PROJ-123") {
+		t.Error("Expected reference with relative URL to be invalid")
+	}
+}
+
+func TestExternalRefSystem(t *testing.T) {
+	if s := ExternalRefSystem(" GitHub "); s != "github" {
+		t.Error("Expected github, got ", s)
+	}
+}
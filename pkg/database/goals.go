@@ -1,6 +1,7 @@
 package database
 
 import (
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"log"
@@ -8,15 +9,16 @@ import (
 
 // CreateStoryboardGoal adds a new goal to a Storyboard
 func (d *Database) CreateStoryboardGoal(StoryboardID string, userID string, GoalName string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call create_storyboard_goal($1, $2);`, StoryboardID, GoalName,
-	); err != nil {
-		log.Println(err)
+	if err := d.createOperation(StoryboardID, userID, "add_goal", "goal", "storyboard_id", StoryboardID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call create_storyboard_goal($1, $2);`, StoryboardID, GoalName)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -26,17 +28,17 @@ func (d *Database) CreateStoryboardGoal(StoryboardID string, userID string, Goal
 
 // ReviseGoalName updates the plan name by ID
 func (d *Database) ReviseGoalName(StoryboardID string, userID string, GoalID string, GoalName string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call update_storyboard_goal($1, $2);`,
-		GoalID,
-		GoalName,
-	); err != nil {
-		log.Println(err)
+	change := &OperationState{Kind: "goal", ID: GoalID, Fields: []string{"name"}}
+	if err := d.changeOperation(StoryboardID, userID, "revise_goal", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call update_storyboard_goal($1, $2);`, GoalID, GoalName)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -44,16 +46,38 @@ func (d *Database) ReviseGoalName(StoryboardID string, userID string, GoalID str
 	return goals, nil
 }
 
-// DeleteStoryboardGoal removes a goal from the current board by ID
+// MoveStoryboardGoal moves a goal to before another goal (or last) by ID
+func (d *Database) MoveStoryboardGoal(StoryboardID string, userID string, GoalID string, PlaceBefore string) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, userID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	change := &OperationState{Kind: "goal", ID: GoalID, Fields: []string{"position"}}
+	if err := d.changeOperation(StoryboardID, userID, "move_goal", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call move_storyboard_goal($1, $2, $3);`, StoryboardID, GoalID, PlaceBefore)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// DeleteStoryboardGoal moves a goal with its columns and stories to the storyboards trash by ID
 func (d *Database) DeleteStoryboardGoal(StoryboardID string, userID string, GoalID string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call delete_storyboard_goal($1);`, GoalID); err != nil {
-		log.Println(err)
+	if err := d.deleteOperation(StoryboardID, userID, "delete_goal", "goal", GoalID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call delete_storyboard_goal($1, $2);`, GoalID, userID)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -63,9 +87,14 @@ func (d *Database) DeleteStoryboardGoal(StoryboardID string, userID string, Goal
 
 // GetStoryboardGoals retrieves goals for given storyboard from db
 func (d *Database) GetStoryboardGoals(StoryboardID string) []*StoryboardGoal {
+	return getStoryboardGoals(d.db, StoryboardID)
+}
+
+// getStoryboardGoals retrieves goals for given storyboard from the database or a transaction on it
+func getStoryboardGoals(q queryer, StoryboardID string) []*StoryboardGoal {
 	var goals = make([]*StoryboardGoal, 0)
 
-	goalRows, goalsErr := d.db.Query(
+	goalRows, goalsErr := q.Query(
 		`SELECT * FROM get_storyboard_goals($1);`,
 		StoryboardID,
 	)
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+)
+
+// AddLabel adds a label to a storyboard, label names are unique per storyboard regardless of case
+func (d *Database) AddLabel(StoryboardID string, UserID string, LabelName string, LabelColor string) ([]*StoryboardLabel, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.db.Exec(
+		`call label_add($1, $2, $3);`,
+		StoryboardID,
+		LabelName,
+		labelColor(LabelColor),
+	); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	labels := d.GetStoryboardLabels(StoryboardID)
+
+	return labels, nil
+}
+
+// ReviseLabel updates a storyboard labels name and color
+func (d *Database) ReviseLabel(StoryboardID string, UserID string, LabelID string, LabelName string, LabelColor string) ([]*StoryboardLabel, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.db.Exec(
+		`call label_edit($1, $2, $3, $4);`,
+		StoryboardID,
+		LabelID,
+		LabelName,
+		labelColor(LabelColor),
+	); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	labels := d.GetStoryboardLabels(StoryboardID)
+
+	return labels, nil
+}
+
+// DeleteLabel removes a label from a storyboard and its stories
+func (d *Database) DeleteLabel(StoryboardID string, UserID string, LabelID string) ([]*StoryboardLabel, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.db.Exec(
+		`call label_delete($1, $2);`,
+		StoryboardID,
+		LabelID,
+	); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	labels := d.GetStoryboardLabels(StoryboardID)
+
+	return labels, nil
+}
+
+// AddStoryLabel adds one of the storyboards labels to a story
+func (d *Database) AddStoryLabel(StoryboardID string, UserID string, StoryID string, LabelID string) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"label"}, ItemID: LabelID}
+	if err := d.changeOperation(StoryboardID, UserID, "add_story_label", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call story_label_add($1, $2, $3);`, StoryboardID, StoryID, LabelID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// RemoveStoryLabel removes a label from a story
+func (d *Database) RemoveStoryLabel(StoryboardID string, UserID string, StoryID string, LabelID string) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"label"}, ItemID: LabelID}
+	if err := d.changeOperation(StoryboardID, UserID, "remove_story_label", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call story_label_remove($1, $2, $3);`, StoryboardID, StoryID, LabelID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// GetStoryboardLabels retrieves the labels for a given storyboard from db
+func (d *Database) GetStoryboardLabels(StoryboardID string) []*StoryboardLabel {
+	var labels = make([]*StoryboardLabel, 0)
+	rows, err := d.db.Query(
+		`SELECT * FROM get_storyboard_labels($1);`,
+		StoryboardID,
+	)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var l StoryboardLabel
+			if err := rows.Scan(&l.LabelID, &l.Name, &l.Color); err != nil {
+				log.Println(err)
+			} else {
+				labels = append(labels, &l)
+			}
+		}
+	}
+
+	return labels
+}
+
+// HasLabels is whether the labels include every label in the filter, each matched by ID or case insensitive name
+func HasLabels(Labels []*StoryboardLabel, Filter []string) bool {
+	for _, f := range Filter {
+		found := false
+		for _, l := range Labels {
+			if l.LabelID == f || strings.EqualFold(l.Name, f) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// labelColor defaults an empty label color to gray like a new story
+func labelColor(Color string) string {
+	if Color == "" {
+		return "gray"
+	}
+
+	return Color
+}
@@ -0,0 +1,23 @@
+package database
+
+import "testing"
+
+func TestHasLabels(t *testing.T) {
+	labels := []*StoryboardLabel{
+		{LabelID: "l1", Name: "Backend", Color: "blue"},
+		{LabelID: "l2", Name: "Compliance", Color: "red"},
+	}
+
+	if !HasLabels(labels, nil) {
+		t.Error("Expected no filter to match")
+	}
+	if !HasLabels(labels, []string{"backend", "l2"}) {
+		t.Error("Expected name and ID filter to match")
+	}
+	if HasLabels(labels, []string{"backend", "frontend"}) {
+		t.Error("Expected filter with a missing label not to match")
+	}
+	if HasLabels(nil, []string{"backend"}) {
+		t.Error("Expected story without labels not to match")
+	}
+}
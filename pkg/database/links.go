@@ -0,0 +1,229 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// storyLinkTypes are the kinds of link between stories
+var storyLinkTypes = map[string]bool{
+	"blocks":     true,
+	"relates_to": true,
+	"duplicates": true,
+}
+
+// storyLinkLockID is the postgres advisory lock key held until the transaction ends while adding a blocks link,
+// the same key story_link_add takes so links added at once can't together close a cycle
+const storyLinkLockID = 7_201_313
+
+// ValidStoryLinkType is whether the type is a kind of link between stories
+func ValidStoryLinkType(LinkType string) bool {
+	return storyLinkTypes[LinkType]
+}
+
+// DependencyNode A story in a storyboards dependency graph, stories on other storyboards are External
+type DependencyNode struct {
+	StoryID      string `json:"id"`
+	StoryName    string `json:"name"`
+	StoryboardID string `json:"storyboard_id"`
+	GoalID       string `json:"goal_id,omitempty"`
+	ColumnID     string `json:"column_id,omitempty"`
+	Closed       bool   `json:"closed"`
+	Blocked      bool   `json:"blocked"`
+	External     bool   `json:"external"`
+}
+
+// DependencyEdge A link in a storyboards dependency graph, from the story linking to the story linked
+type DependencyEdge struct {
+	LinkID string `json:"id"`
+	Type   string `json:"type"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// DependencyGraph The stories of a storyboard and those they're linked to, with the links between them
+type DependencyGraph struct {
+	Nodes []*DependencyNode `json:"nodes"`
+	Edges []*DependencyEdge `json:"edges"`
+}
+
+// AddStoryLink links a story to another story on any storyboard the user can access,
+// returning the linked storys storyboard ID
+func (d *Database) AddStoryLink(StoryboardID string, UserID string, StoryID string, ToStoryID string, LinkType string) ([]*StoryboardGoal, string, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, "", errors.New("Incorrect permissions")
+	}
+	if !ValidStoryLinkType(LinkType) {
+		return nil, "", errors.New("Invalid link type")
+	}
+
+	var ToStoryboardID string
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"link"}}
+	if err := d.changeOperation(StoryboardID, UserID, "add_story_link", change, func(tx *sql.Tx) error {
+		if err := tx.QueryRow(
+			`call story_link_add($1, $2, $3, $4, $5, NULL);`,
+			StoryboardID,
+			StoryID,
+			ToStoryID,
+			LinkType,
+			UserID,
+		).Scan(&ToStoryboardID); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(
+			`SELECT id FROM story_link WHERE from_story_id = $1 AND to_story_id = $2 AND link_type = $3;`,
+			StoryID, ToStoryID, LinkType,
+		).Scan(&change.ItemID)
+	}); err != nil {
+		return nil, "", err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, ToStoryboardID, nil
+}
+
+// RemoveStoryLink removes a link to or from one of the storyboards stories,
+// returning the other storys storyboard ID
+func (d *Database) RemoveStoryLink(StoryboardID string, UserID string, LinkID string) ([]*StoryboardGoal, string, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, "", errors.New("Incorrect permissions")
+	}
+
+	// the link is recorded on the story it's from, or to when linked from another storyboard
+	var StoryID string
+	if err := d.db.QueryRow(
+		`SELECT CASE WHEN fs.storyboard_id = $2 THEN fs.id ELSE ts.id END
+		FROM story_link sl
+		JOIN storyboard_story fs ON fs.id = sl.from_story_id
+		JOIN storyboard_story ts ON ts.id = sl.to_story_id
+		WHERE sl.id = $1 AND (fs.storyboard_id = $2 OR ts.storyboard_id = $2);`,
+		LinkID,
+		StoryboardID,
+	).Scan(&StoryID); err != nil {
+		if err != sql.ErrNoRows {
+			log.Println(err)
+		}
+		return nil, "", errors.New("Link not found")
+	}
+
+	var OtherStoryboardID string
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"link"}, ItemID: LinkID}
+	if err := d.changeOperation(StoryboardID, UserID, "remove_story_link", change, func(tx *sql.Tx) error {
+		return tx.QueryRow(`call story_link_remove($1, $2, NULL);`, StoryboardID, LinkID).Scan(&OtherStoryboardID)
+	}); err != nil {
+		return nil, "", err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, OtherStoryboardID, nil
+}
+
+// GetStoryLinkStories gets the stories at each end of a link to or from one of the storyboards stories,
+// keyed by story ID with the storyboard ID each is on
+func (d *Database) GetStoryLinkStories(StoryboardID string, LinkID string) (map[string]string, error) {
+	var FromStoryID, FromStoryboardID, ToStoryID, ToStoryboardID string
+
+	if err := d.db.QueryRow(
+		`SELECT fs.id, fs.storyboard_id, ts.id, ts.storyboard_id
+		FROM story_link sl
+		JOIN storyboard_story fs ON fs.id = sl.from_story_id
+		JOIN storyboard_story ts ON ts.id = sl.to_story_id
+		WHERE sl.id = $1 AND (fs.storyboard_id = $2 OR ts.storyboard_id = $2);`,
+		LinkID,
+		StoryboardID,
+	).Scan(&FromStoryID, &FromStoryboardID, &ToStoryID, &ToStoryboardID); err != nil {
+		if err != sql.ErrNoRows {
+			log.Println(err)
+		}
+		return nil, errors.New("Link not found")
+	}
+
+	return map[string]string{FromStoryID: FromStoryboardID, ToStoryID: ToStoryboardID}, nil
+}
+
+// GetLinkedStoryStoryboardID gets the ID of the storyboard a story that can be linked to is on
+func (d *Database) GetLinkedStoryStoryboardID(StoryID string) (string, error) {
+	var StoryboardID string
+
+	if err := d.db.QueryRow(
+		`SELECT storyboard_id FROM storyboard_story WHERE id::TEXT = $1 AND deleted_date IS NULL;`,
+		StoryID,
+	).Scan(&StoryboardID); err != nil {
+		if err != sql.ErrNoRows {
+			log.Println(err)
+		}
+		return "", errors.New("Linked story not found")
+	}
+
+	return StoryboardID, nil
+}
+
+// GetStoryboardDependencies gets the dependency graph of a storyboards stories
+func (d *Database) GetStoryboardDependencies(StoryboardID string) *DependencyGraph {
+	return NewDependencyGraph(StoryboardID, d.GetStoryboardGoals(StoryboardID))
+}
+
+// NewDependencyGraph creates the dependency graph from a storyboards goal tree, with a node for each of its stories
+// and each story on another storyboard they're linked to
+func NewDependencyGraph(StoryboardID string, goals []*StoryboardGoal) *DependencyGraph {
+	g := &DependencyGraph{
+		Nodes: make([]*DependencyNode, 0),
+		Edges: make([]*DependencyEdge, 0),
+	}
+	nodes := make(map[string]bool)
+	edges := make(map[string]bool)
+
+	for _, goal := range goals {
+		for _, c := range goal.Columns {
+			for _, st := range c.Stories {
+				nodes[st.StoryID] = true
+				g.Nodes = append(g.Nodes, &DependencyNode{
+					StoryID:      st.StoryID,
+					StoryName:    st.StoryName,
+					StoryboardID: StoryboardID,
+					GoalID:       goal.GoalID,
+					ColumnID:     c.ColumnID,
+					Closed:       st.StoryClosed,
+					Blocked:      st.Blocked,
+				})
+			}
+		}
+	}
+
+	for _, goal := range goals {
+		for _, c := range goal.Columns {
+			for _, st := range c.Stories {
+				for _, l := range st.Links {
+					if !nodes[l.StoryID] {
+						nodes[l.StoryID] = true
+						g.Nodes = append(g.Nodes, &DependencyNode{
+							StoryID:      l.StoryID,
+							StoryName:    l.StoryName,
+							StoryboardID: l.StoryboardID,
+							Closed:       l.Closed,
+							External:     true,
+						})
+					}
+					if edges[l.LinkID] {
+						continue
+					}
+					edges[l.LinkID] = true
+
+					e := &DependencyEdge{LinkID: l.LinkID, Type: l.Type, From: st.StoryID, To: l.StoryID}
+					if l.Direction == "inward" {
+						e.From, e.To = l.StoryID, st.StoryID
+					}
+					g.Edges = append(g.Edges, e)
+				}
+			}
+		}
+	}
+
+	return g
+}
@@ -0,0 +1,43 @@
+package database
+
+import "testing"
+
+func TestNewDependencyGraph(t *testing.T) {
+	goals := []*StoryboardGoal{{
+		GoalID: "g1",
+		Columns: []*StoryboardColumn{{
+			ColumnID: "c1",
+			Stories: []*StoryboardStory{
+				{StoryID: "s1", StoryName: "Sign up", Links: []*StoryLink{
+					{LinkID: "l1", Type: "blocks", Direction: "outward", StoryID: "s2", StoryboardID: "b1"},
+					{LinkID: "l2", Type: "relates_to", Direction: "inward", StoryID: "x1", StoryName: "Audit", StoryboardID: "b2"},
+				}},
+				{StoryID: "s2", StoryName: "Log in", Blocked: true, Links: []*StoryLink{
+					{LinkID: "l1", Type: "blocks", Direction: "inward", StoryID: "s1", StoryboardID: "b1"},
+				}},
+			},
+		}},
+	}}
+
+	g := NewDependencyGraph("b1", goals)
+
+	if len(g.Nodes) != 3 {
+		t.Fatal("Expected 3 nodes, got ", len(g.Nodes))
+	}
+	if x := g.Nodes[2]; x.StoryID != "x1" || !x.External || x.StoryboardID != "b2" {
+		t.Error("Expected external node x1 on b2, got ", x)
+	}
+	if !g.Nodes[1].Blocked || g.Nodes[1].GoalID != "g1" || g.Nodes[1].ColumnID != "c1" {
+		t.Error("Expected blocked s2 in g1/c1, got ", g.Nodes[1])
+	}
+
+	if len(g.Edges) != 2 {
+		t.Fatal("Expected 2 edges, got ", len(g.Edges))
+	}
+	if e := g.Edges[0]; e.From != "s1" || e.To != "s2" || e.Type != "blocks" {
+		t.Error("Expected s1 blocks s2, got ", e)
+	}
+	if e := g.Edges[1]; e.From != "x1" || e.To != "s1" {
+		t.Error("Expected link from x1 to s1, got ", e)
+	}
+}
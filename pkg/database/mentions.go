@@ -0,0 +1,237 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// mentionEmailBatchSize is the most mention emails claimed at a time
+const mentionEmailBatchSize = 50
+
+// StoryMention A user mentioned in a story comment, EmailQueued is set when the user
+// wasn't on the storyboard to see it
+type StoryMention struct {
+	MentionID    string `json:"id"`
+	StoryboardID string `json:"storyboard_id"`
+	StoryID      string `json:"story_id"`
+	CommentID    string `json:"comment_id"`
+	UserID       string `json:"user_id"`
+	UserName     string `json:"user_name"`
+	AuthorID     string `json:"author_id"`
+	EmailQueued  bool   `json:"email_queued"`
+}
+
+// MentionCandidate A user that can be mentioned on a storyboard
+type MentionCandidate struct {
+	UserID   string
+	UserName string
+	Active   bool
+}
+
+// MentionEmail A queued mention email with what's needed to write it
+type MentionEmail struct {
+	UserName       string
+	UserEmail      string
+	AuthorName     string
+	StoryboardID   string
+	StoryboardName string
+	StoryID        string
+	StoryName      string
+	Comment        string
+}
+
+// isMentionWordRune is whether the rune continues a word, so a mention can't end or start inside one
+func isMentionWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// ParseMentions gets the candidates mentioned by @name in a comment, names match case insensitively
+// and must end at a word boundary, the longest name is matched first so "@Jane Doe" isn't a mention of "Jane"
+func ParseMentions(Comment string, Candidates []*MentionCandidate) []*MentionCandidate {
+	var mentioned = make([]*MentionCandidate, 0)
+
+	sorted := make([]*MentionCandidate, 0, len(Candidates))
+	for _, c := range Candidates {
+		if strings.TrimSpace(c.UserName) != "" {
+			sorted = append(sorted, c)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i].UserName) > len(sorted[j].UserName)
+	})
+
+	comment := strings.ToLower(Comment)
+	seen := make(map[string]bool)
+	for i := 0; i < len(comment); i++ {
+		if comment[i] != '@' {
+			continue
+		}
+		if prev, _ := utf8.DecodeLastRuneInString(comment[:i]); i > 0 && isMentionWordRune(prev) {
+			continue
+		}
+
+		rest := comment[i+1:]
+		for _, c := range sorted {
+			name := strings.ToLower(c.UserName)
+			if !strings.HasPrefix(rest, name) {
+				continue
+			}
+			if next, _ := utf8.DecodeRuneInString(rest[len(name):]); len(rest) > len(name) && isMentionWordRune(next) {
+				continue
+			}
+
+			if !seen[c.UserID] {
+				seen[c.UserID] = true
+				mentioned = append(mentioned, c)
+			}
+			i = i + len(name)
+			break
+		}
+	}
+
+	return mentioned
+}
+
+// getMentionCandidates gets the users that can be mentioned on a storyboard,
+// its users and the members of its teams
+func (d *Database) getMentionCandidates(StoryboardID string) []*MentionCandidate {
+	var candidates = make([]*MentionCandidate, 0)
+
+	rows, err := d.db.Query(
+		`SELECT u.id, u.name, coalesce(su.active, false)
+		FROM users u
+		LEFT JOIN storyboard_user su ON su.storyboard_id = $1 AND su.user_id = u.id
+		WHERE su.user_id IS NOT NULL OR u.id IN (
+			SELECT tu.user_id FROM team_user tu
+			JOIN team_storyboard tb ON tb.team_id = tu.team_id
+			WHERE tb.storyboard_id = $1
+		);`,
+		StoryboardID,
+	)
+	if err != nil {
+		log.Println(err)
+		return candidates
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c MentionCandidate
+		if err := rows.Scan(&c.UserID, &c.UserName, &c.Active); err != nil {
+			log.Println(err)
+			continue
+		}
+		candidates = append(candidates, &c)
+	}
+
+	return candidates
+}
+
+// recordMentions records the users newly mentioned in a comment, queuing an email
+// for those not on the storyboard, the author mentioning themselves is ignored
+func (d *Database) recordMentions(StoryboardID string, CommentID string) []*StoryMention {
+	var mentions = make([]*StoryMention, 0)
+
+	var StoryID, AuthorID, Comment string
+	if err := d.db.QueryRow(
+		`SELECT story_id, user_id, coalesce(comment, '') FROM story_comment WHERE id = $1 AND storyboard_id = $2;`,
+		CommentID, StoryboardID,
+	).Scan(&StoryID, &AuthorID, &Comment); err != nil {
+		log.Println(err)
+		return mentions
+	}
+
+	for _, c := range ParseMentions(Comment, d.getMentionCandidates(StoryboardID)) {
+		if c.UserID == AuthorID {
+			continue
+		}
+
+		m := &StoryMention{
+			StoryboardID: StoryboardID,
+			StoryID:      StoryID,
+			CommentID:    CommentID,
+			UserID:       c.UserID,
+			UserName:     c.UserName,
+			AuthorID:     AuthorID,
+			EmailQueued:  !c.Active,
+		}
+		if err := d.db.QueryRow(
+			`INSERT INTO story_comment_mention (comment_id, storyboard_id, story_id, user_id, email_queued)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (comment_id, user_id) DO NOTHING
+			RETURNING id;`,
+			CommentID, StoryboardID, StoryID, c.UserID, m.EmailQueued,
+		).Scan(&m.MentionID); err != nil {
+			if err != sql.ErrNoRows {
+				log.Println(err)
+			}
+			continue
+		}
+		mentions = append(mentions, m)
+	}
+
+	return mentions
+}
+
+// ClaimMentionEmails marks the oldest queued mention emails as sent and gets those for users
+// that haven't turned mention emails off, claiming first keeps instances from sending the same email
+func (d *Database) ClaimMentionEmails() []*MentionEmail {
+	var emails = make([]*MentionEmail, 0)
+
+	rows, err := d.db.Query(
+		`WITH claimed AS (
+			UPDATE story_comment_mention SET emailed_date = NOW()
+			WHERE id IN (
+				SELECT id FROM story_comment_mention
+				WHERE email_queued = true AND emailed_date IS NULL
+				ORDER BY created_date
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING comment_id, storyboard_id, story_id, user_id
+		)
+		SELECT u.name, u.email, coalesce(a.name, ''), sb.id, sb.name, ss.id, coalesce(ss.name, ''), coalesce(sc.comment, '')
+		FROM claimed c
+		JOIN users u ON u.id = c.user_id
+		JOIN storyboard sb ON sb.id = c.storyboard_id
+		JOIN storyboard_story ss ON ss.id = c.story_id
+		JOIN story_comment sc ON sc.id = c.comment_id
+		LEFT JOIN users a ON a.id = sc.user_id
+		WHERE u.mention_emails = true AND coalesce(u.email, '') != '';`,
+		mentionEmailBatchSize,
+	)
+	if err != nil {
+		log.Println(err)
+		return emails
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e MentionEmail
+		if err := rows.Scan(
+			&e.UserName, &e.UserEmail, &e.AuthorName, &e.StoryboardID, &e.StoryboardName, &e.StoryID, &e.StoryName, &e.Comment,
+		); err != nil {
+			log.Println(err)
+			continue
+		}
+		emails = append(emails, &e)
+	}
+
+	return emails
+}
+
+// UpdateUserMentionEmails turns the users story comment mention emails on or off
+func (d *Database) UpdateUserMentionEmails(UserID string, Enabled bool) error {
+	if _, err := d.db.Exec(
+		`UPDATE users SET mention_emails = $2, updated_date = NOW() WHERE id = $1;`, UserID, Enabled,
+	); err != nil {
+		log.Println(err)
+		return errors.New("Error attempting to update users mention emails")
+	}
+
+	return nil
+}
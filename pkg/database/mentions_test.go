@@ -0,0 +1,31 @@
+package database
+
+import "testing"
+
+func TestParseMentions(t *testing.T) {
+	candidates := []*MentionCandidate{
+		{UserID: "u1", UserName: "Jane"},
+		{UserID: "u2", UserName: "Jane Doe"},
+		{UserID: "u3", UserName: "Sam"},
+		{UserID: "u4", UserName: ""},
+	}
+
+	mentioned := ParseMentions("@jane doe can you pair with @Sam? cc @Jane, not sam@example.com or @Samuel", candidates)
+
+	if len(mentioned) != 3 {
+		t.Fatal("Expected 3 mentions, got ", len(mentioned))
+	}
+	if mentioned[0].UserID != "u2" || mentioned[1].UserID != "u3" || mentioned[2].UserID != "u1" {
+		t.Error("Expected u2, u3 and u1 mentioned, got ", mentioned[0].UserID, mentioned[1].UserID, mentioned[2].UserID)
+	}
+}
+
+func TestParseMentionsDuplicates(t *testing.T) {
+	candidates := []*MentionCandidate{{UserID: "u1", UserName: "Sam"}}
+
+	mentioned := ParseMentions("@Sam @sam", candidates)
+
+	if len(mentioned) != 1 {
+		t.Error("Expected 1 mention, got ", len(mentioned))
+	}
+}
@@ -0,0 +1,238 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migrationLockID is the postgres advisory lock key held while migrating,
+// so multiple replicas starting at once don't race
+const migrationLockID = 7_201_311
+
+// migrationFile matches migration file names e.g. 0002_story_labels.up.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// errNoMigrations is returned when no migration files are found
+var errNoMigrations = errors.New("no migrations found")
+
+// Migration is a numbered schema change with its up and (optional) down step
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus is a migration and when it was applied (if it has been)
+type MigrationStatus struct {
+	Version     int
+	Name        string
+	AppliedDate *time.Time
+}
+
+// LoadMigrations reads the numbered up/down migration files from the filesystem in version order
+func LoadMigrations(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, e := range entries {
+		parts := migrationFile.FindStringSubmatch(e.Name())
+		if e.IsDir() || parts == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(parts[1])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, m.Name, parts[2])
+		}
+
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		if parts[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up step", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	if len(migrations) == 0 {
+		return nil, errNoMigrations
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// withMigrationLock runs fn on a single connection holding the migration advisory lock
+func (d *Database) withMigrationLock(fn func(ctx context.Context, conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationLockID); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1);`, migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER NOT NULL PRIMARY KEY,
+			name VARCHAR(256) NOT NULL,
+			applied_date TIMESTAMP DEFAULT NOW()
+		);`); err != nil {
+		return err
+	}
+
+	return fn(ctx, conn)
+}
+
+// appliedMigrations gets the applied migration versions and when they were applied
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_date FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int
+		var appliedDate time.Time
+		if err := rows.Scan(&version, &appliedDate); err != nil {
+			return nil, err
+		}
+		applied[version] = appliedDate
+	}
+
+	return applied, rows.Err()
+}
+
+// runMigrationStep runs a migration step and records it within a single transaction
+func runMigrationStep(ctx context.Context, conn *sql.Conn, step string, record string, m *Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, step); err != nil {
+		return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+	}
+
+	if record == "up" {
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, m.Version, m.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1;`, m.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// MigrateUp applies all pending migrations in version order, returning how many were applied
+func (d *Database) MigrateUp(migrations []*Migration) (int, error) {
+	var count int
+	err := d.withMigrationLock(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := runMigrationStep(ctx, conn, m.Up, "up", m); err != nil {
+				return err
+			}
+			log.Printf("applied migration %d_%s\n", m.Version, m.Name)
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// MigrateDown rolls back the given number of most recently applied migrations
+func (d *Database) MigrateDown(migrations []*Migration, Steps int) (int, error) {
+	var count int
+	err := d.withMigrationLock(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && count < Steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if m.Down == "" {
+				return fmt.Errorf("migration %d_%s can not be rolled back", m.Version, m.Name)
+			}
+			if err := runMigrationStep(ctx, conn, m.Down, "down", m); err != nil {
+				return err
+			}
+			log.Printf("rolled back migration %d_%s\n", m.Version, m.Name)
+			count++
+		}
+
+		return nil
+	})
+
+	return count, err
+}
+
+// MigrationStatuses gets each known migration along with when it was applied
+func (d *Database) MigrationStatuses(migrations []*Migration) ([]*MigrationStatus, error) {
+	var statuses = make([]*MigrationStatus, 0, len(migrations))
+	err := d.withMigrationLock(func(ctx context.Context, conn *sql.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migrations {
+			status := &MigrationStatus{Version: m.Version, Name: m.Name}
+			if appliedDate, ok := applied[m.Version]; ok {
+				status.AppliedDate = &appliedDate
+			}
+			statuses = append(statuses, status)
+		}
+
+		return nil
+	})
+
+	return statuses, err
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrationsOrdered(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_labels.up.sql":   {Data: []byte("CREATE TABLE label ();")},
+		"0002_labels.down.sql": {Data: []byte("DROP TABLE label;")},
+		"0001_baseline.up.sql": {Data: []byte("CREATE TABLE storyboard ();")},
+		"README.md":            {Data: []byte("not a migration")},
+	}
+
+	migrations, err := LoadMigrations(fsys)
+	if err != nil {
+		t.Fatal("Expected no error, got ", err)
+	}
+
+	if len(migrations) != 2 {
+		t.Fatal("Expected 2 migrations, got ", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[1].Version != 2 {
+		t.Error("Expected migrations in version order, got ", migrations[0].Version, migrations[1].Version)
+	}
+	if migrations[0].Down != "" {
+		t.Error("Expected baseline without a down step, got ", migrations[0].Down)
+	}
+	if migrations[1].Name != "labels" || migrations[1].Down != "DROP TABLE label;" {
+		t.Error("Expected labels migration with down step, got ", migrations[1].Name, migrations[1].Down)
+	}
+}
+
+func TestLoadMigrationsMissingUp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_baseline.down.sql": {Data: []byte("DROP TABLE storyboard;")},
+	}
+
+	if _, err := LoadMigrations(fsys); err == nil {
+		t.Error("Expected error for migration without up step")
+	}
+}
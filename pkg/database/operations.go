@@ -0,0 +1,1019 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// operationHistorySize is how many operations are kept per user on each storyboard
+const operationHistorySize = 50
+
+// queryer is the database or a transaction on it, operations are recorded in the transaction making the change
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// OperationState is a goal, column or story as it was before or after an operation,
+// the Goal, Column or Story is nil when it didn't exist
+type OperationState struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	// Fields are the fields the operation changed and the only ones undo and redo put back, without any
+	// the operation created or deleted the goal, column or story and it's restored or trashed whole
+	Fields []string `json:"fields,omitempty"`
+	// ItemID is the assignee, label, criterion, link or external reference of the story the operation changed
+	ItemID   string            `json:"item_id,omitempty"`
+	GoalID   string            `json:"goal_id,omitempty"`
+	ColumnID string            `json:"column_id,omitempty"`
+	Goal     *StoryboardGoal   `json:"goal,omitempty"`
+	Column   *StoryboardColumn `json:"column,omitempty"`
+	Story    *StoryboardStory  `json:"story,omitempty"`
+}
+
+// exists is whether the goal, column or story existed in this state
+func (s *OperationState) exists() bool {
+	return s.Goal != nil || s.Column != nil || s.Story != nil
+}
+
+// goalState gets the state of a goal from the goal tree
+func goalState(goals []*StoryboardGoal, GoalID string) *OperationState {
+	s := &OperationState{Kind: "goal", ID: GoalID}
+	for _, g := range goals {
+		if g.GoalID == GoalID {
+			s.Goal = g
+		}
+	}
+
+	return s
+}
+
+// columnState gets the state of a column from the goal tree
+func columnState(goals []*StoryboardGoal, ColumnID string) *OperationState {
+	s := &OperationState{Kind: "column", ID: ColumnID}
+	for _, g := range goals {
+		for _, c := range g.Columns {
+			if c.ColumnID == ColumnID {
+				s.GoalID = g.GoalID
+				s.Column = c
+			}
+		}
+	}
+
+	return s
+}
+
+// storyState gets the state of a story from the goal tree
+func storyState(goals []*StoryboardGoal, StoryID string) *OperationState {
+	s := &OperationState{Kind: "story", ID: StoryID}
+	for _, g := range goals {
+		for _, c := range g.Columns {
+			for _, st := range c.Stories {
+				if st.StoryID == StoryID {
+					s.GoalID = g.GoalID
+					s.ColumnID = c.ColumnID
+					s.Story = st
+				}
+			}
+		}
+	}
+
+	return s
+}
+
+// itemState gets the state of a goal, column or story with everything in it from the goal tree
+func itemState(goals []*StoryboardGoal, Kind string, ID string) *OperationState {
+	switch Kind {
+	case "goal":
+		return goalState(goals, ID)
+	case "column":
+		return columnState(goals, ID)
+	}
+
+	return storyState(goals, ID)
+}
+
+// changeOperation runs a change to the fields of a goal, column or story in a transaction, recording the
+// operation with only those fields as they were before and after, the change sets Change.ItemID of an item it adds
+func (d *Database) changeOperation(StoryboardID string, UserID string, Type string, Change *OperationState, Mutate func(tx *sql.Tx) error) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	before, err := readOperationState(tx, StoryboardID, Change)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if err := Mutate(tx); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	before.ItemID = Change.ItemID
+	after, err := readOperationState(tx, StoryboardID, Change)
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if err := recordOperation(tx, StoryboardID, UserID, Type, before, after); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// createOperation runs the creation of a goal, column or story at the end of its parent (the Scope column
+// of its table) in a transaction, recording the operation with the new item
+func (d *Database) createOperation(StoryboardID string, UserID string, Type string, Kind string, Scope string, ScopeID string, Mutate func(tx *sql.Tx) error) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := Mutate(tx); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	var ID string
+	if err := tx.QueryRow(
+		fmt.Sprintf(`SELECT id FROM storyboard_%s WHERE %s = $1 AND sort_order IS NOT NULL ORDER BY sort_order DESC LIMIT 1;`, Kind, Scope),
+		ScopeID,
+	).Scan(&ID); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	after, err := readOperationState(tx, StoryboardID, &OperationState{Kind: Kind, ID: ID})
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if err := recordOperation(tx, StoryboardID, UserID, Type, &OperationState{Kind: Kind, ID: ID}, after); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteOperation runs the deletion of a goal, column or story in a transaction, recording the operation
+// with everything in the item so undoing can recreate it should it be purged from the trash
+func (d *Database) deleteOperation(StoryboardID string, UserID string, Type string, Kind string, ID string, Mutate func(tx *sql.Tx) error) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	before := itemState(getStoryboardGoals(tx, StoryboardID), Kind, ID)
+
+	if err := Mutate(tx); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if err := recordOperation(tx, StoryboardID, UserID, Type, before, &OperationState{Kind: Kind, ID: ID}); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// readOperationState reads the row of the goal, column or story being changed (with the story item changed),
+// locking it until the transaction ends
+func readOperationState(q queryer, StoryboardID string, Change *OperationState) (*OperationState, error) {
+	s := &OperationState{Kind: Change.Kind, ID: Change.ID, Fields: Change.Fields, ItemID: Change.ItemID}
+
+	var err error
+	switch s.Kind {
+	case "goal":
+		g := &StoryboardGoal{GoalID: s.ID, Columns: make([]*StoryboardColumn, 0)}
+		err = q.QueryRow(
+			`SELECT COALESCE(name, ''), sort_order FROM storyboard_goal
+			WHERE id = $1 AND storyboard_id = $2 AND deleted_date IS NULL FOR UPDATE;`,
+			s.ID, StoryboardID,
+		).Scan(&g.GoalName, &g.SortOrder)
+		if err == nil {
+			s.Goal = g
+		}
+	case "column":
+		c := &StoryboardColumn{ColumnID: s.ID, Stories: make([]*StoryboardStory, 0)}
+		err = q.QueryRow(
+			`SELECT goal_id, COALESCE(name, ''), sort_order FROM storyboard_column
+			WHERE id = $1 AND storyboard_id = $2 AND deleted_date IS NULL FOR UPDATE;`,
+			s.ID, StoryboardID,
+		).Scan(&s.GoalID, &c.ColumnName, &c.SortOrder)
+		if err == nil {
+			s.Column = c
+		}
+	case "story":
+		st := &StoryboardStory{StoryID: s.ID}
+		err = q.QueryRow(
+			`SELECT goal_id, column_id, COALESCE(name, ''), COALESCE(content, ''), COALESCE(color, ''), COALESCE(points, 0),
+				COALESCE(closed, false), sort_order, COALESCE(release_id::TEXT, '')
+			FROM storyboard_story WHERE id = $1 AND storyboard_id = $2 AND deleted_date IS NULL FOR UPDATE;`,
+			s.ID, StoryboardID,
+		).Scan(&s.GoalID, &s.ColumnID, &st.StoryName, &st.StoryContent, &st.StoryColor, &st.StoryPoints,
+			&st.StoryClosed, &st.SortOrder, &st.ReleaseID)
+		if err == nil {
+			s.Story = st
+			err = readStoryItem(q, s)
+		}
+	default:
+		return nil, errors.New("Invalid operation")
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// readStoryItem reads the assignee, label, criterion, link or external reference the operation changed
+// into the storys state, leaving it off when the story doesn't have it
+func readStoryItem(q queryer, s *OperationState) error {
+	if s.ItemID == "" {
+		return nil
+	}
+
+	st := s.Story
+	var err error
+	for _, f := range s.Fields {
+		switch f {
+		case "assignee":
+			a := &StoryAssignee{}
+			err = q.QueryRow(
+				`SELECT u.id, COALESCE(u.name, '') FROM storyboard_story_assignee ssa JOIN users u ON u.id = ssa.user_id
+				WHERE ssa.story_id = $1 AND ssa.user_id::TEXT = $2;`,
+				st.StoryID, s.ItemID,
+			).Scan(&a.UserID, &a.UserName)
+			if err == nil {
+				st.Assignees = []*StoryAssignee{a}
+			}
+		case "label":
+			l := &StoryboardLabel{}
+			err = q.QueryRow(
+				`SELECT sl.id, sl.name, sl.color FROM storyboard_story_label ssl JOIN storyboard_label sl ON sl.id = ssl.label_id
+				WHERE ssl.story_id = $1 AND ssl.label_id::TEXT = $2;`,
+				st.StoryID, s.ItemID,
+			).Scan(&l.LabelID, &l.Name, &l.Color)
+			if err == nil {
+				st.Labels = []*StoryboardLabel{l}
+			}
+		case "criterion":
+			c := &StoryCriterion{StoryID: st.StoryID}
+			err = q.QueryRow(
+				`SELECT id, text, done, sort_order FROM story_criterion WHERE story_id = $1 AND id::TEXT = $2;`,
+				st.StoryID, s.ItemID,
+			).Scan(&c.CriterionID, &c.Text, &c.Done, &c.SortOrder)
+			if err == nil {
+				st.Criteria = []*StoryCriterion{c}
+			}
+		case "link":
+			l := &StoryLink{Direction: "outward"}
+			var FromID string
+			err = q.QueryRow(
+				`SELECT sl.id, sl.link_type, sl.from_story_id,
+					CASE WHEN sl.from_story_id = $1 THEN ts.id ELSE fs.id END,
+					CASE WHEN sl.from_story_id = $1 THEN ts.storyboard_id ELSE fs.storyboard_id END
+				FROM story_link sl
+				JOIN storyboard_story fs ON fs.id = sl.from_story_id
+				JOIN storyboard_story ts ON ts.id = sl.to_story_id
+				WHERE sl.id::TEXT = $2 AND (sl.from_story_id = $1 OR sl.to_story_id = $1);`,
+				st.StoryID, s.ItemID,
+			).Scan(&l.LinkID, &l.Type, &FromID, &l.StoryID, &l.StoryboardID)
+			if err == nil {
+				if FromID != st.StoryID {
+					l.Direction = "inward"
+				}
+				st.Links = []*StoryLink{l}
+			}
+		case "external_ref":
+			ref := &StoryExternalRef{StoryID: st.StoryID}
+			err = q.QueryRow(
+				`SELECT id, system, ref_key, url, status FROM story_external_ref WHERE story_id = $1 AND id::TEXT = $2;`,
+				st.StoryID, s.ItemID,
+			).Scan(&ref.RefID, &ref.System, &ref.Key, &ref.URL, &ref.Status)
+			if err == nil {
+				st.ExternalRefs = []*StoryExternalRef{ref}
+			}
+		}
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordOperation adds an operation to the users log for the storyboard, discarding any
+// operations the user had undone as they can no longer be redone
+func recordOperation(q queryer, StoryboardID string, UserID string, Type string, Before *OperationState, After *OperationState) error {
+	if !Before.exists() && !After.exists() {
+		return nil
+	}
+
+	before, _ := json.Marshal(Before)
+	after, _ := json.Marshal(After)
+
+	if _, err := q.Exec(
+		`DELETE FROM storyboard_operation WHERE storyboard_id = $1 AND user_id = $2 AND undone = true;`,
+		StoryboardID, UserID,
+	); err != nil {
+		return err
+	}
+
+	if _, err := q.Exec(
+		`INSERT INTO storyboard_operation (storyboard_id, user_id, type, before_state, after_state) VALUES ($1, $2, $3, $4, $5);`,
+		StoryboardID, UserID, Type, string(before), string(after),
+	); err != nil {
+		return err
+	}
+
+	_, err := q.Exec(
+		`DELETE FROM storyboard_operation WHERE id IN (
+			SELECT id FROM storyboard_operation WHERE storyboard_id = $1 AND user_id = $2 ORDER BY id DESC OFFSET $3
+		);`,
+		StoryboardID, UserID, operationHistorySize,
+	)
+
+	return err
+}
+
+// UndoOperation reverts the users most recent operation on the storyboard
+func (d *Database) UndoOperation(StoryboardID string, UserID string) ([]*StoryboardGoal, error) {
+	return d.stepOperation(StoryboardID, UserID, true)
+}
+
+// RedoOperation reapplies the users most recently undone operation on the storyboard
+func (d *Database) RedoOperation(StoryboardID string, UserID string) ([]*StoryboardGoal, error) {
+	return d.stepOperation(StoryboardID, UserID, false)
+}
+
+// NextOperationState gets the state undoing (or redoing) the users next operation on the storyboard would restore
+func (d *Database) NextOperationState(StoryboardID string, UserID string, Undo bool) (*OperationState, error) {
+	_, s, err := nextOperation(d.db, StoryboardID, UserID, Undo)
+
+	return s, err
+}
+
+// nextOperation gets the users next operation to undo (or redo) on the storyboard with the state it restores,
+// locking it until the transaction ends
+func nextOperation(q queryer, StoryboardID string, UserID string, Undo bool) (int64, *OperationState, error) {
+	// undone operations are always the newest in the users log, redo takes the last one undone
+	query := `SELECT id, before_state FROM storyboard_operation
+		WHERE storyboard_id = $1 AND user_id = $2 AND undone = false ORDER BY id DESC LIMIT 1 FOR UPDATE;`
+	action := "undo"
+	if !Undo {
+		query = `SELECT id, after_state FROM storyboard_operation
+			WHERE storyboard_id = $1 AND user_id = $2 AND undone = true ORDER BY id ASC LIMIT 1 FOR UPDATE;`
+		action = "redo"
+	}
+
+	var OperationID int64
+	var state string
+	if err := q.QueryRow(query, StoryboardID, UserID).Scan(&OperationID, &state); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil, fmt.Errorf("Operation to %s not found", action)
+		}
+		log.Println(err)
+		return 0, nil, err
+	}
+
+	var s OperationState
+	if err := json.Unmarshal([]byte(state), &s); err != nil {
+		log.Println(err)
+		return 0, nil, err
+	}
+
+	return OperationID, &s, nil
+}
+
+// stepOperation undoes or redoes an operation, restoring the state from before or after it
+func (d *Database) stepOperation(StoryboardID string, UserID string, Undo bool) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	OperationID, s, err := nextOperation(tx, StoryboardID, UserID, Undo)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyOperationState(tx, StoryboardID, UserID, s); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE storyboard_operation SET undone = $2 WHERE id = $1;`, OperationID, Undo); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	if _, err := tx.Exec(
+		`UPDATE storyboard SET updated_date = NOW() WHERE id = $1;`, StoryboardID); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// applyOperationState puts back the fields of the goal, column or story the operation changed, leaving any others
+// as they are now, or without fields moves it to the trash when it didn't exist and restores it from the trash
+// (or recreates it with its original IDs) when deleted
+func applyOperationState(tx *sql.Tx, StoryboardID string, UserID string, s *OperationState) error {
+	if len(s.Fields) > 0 {
+		return applyOperationFields(tx, StoryboardID, s)
+	}
+
+	switch s.Kind {
+	case "goal":
+		if s.Goal == nil {
+			return removeGoal(tx, StoryboardID, UserID, s.ID)
+		}
+		return restoreGoal(tx, StoryboardID, s.Goal)
+	case "column":
+		if s.Column == nil {
+			return removeColumn(tx, StoryboardID, UserID, s.ID)
+		}
+		return restoreColumn(tx, StoryboardID, s.GoalID, s.Column)
+	case "story":
+		if s.Story == nil {
+			return removeStory(tx, StoryboardID, UserID, s.ID)
+		}
+		return restoreStory(tx, StoryboardID, s.GoalID, s.ColumnID, s.Story)
+	}
+
+	return errors.New("Invalid operation")
+}
+
+// applyOperationFields puts back the fields of the goal, column or story in the state,
+// there's nothing to put back once it's been deleted
+func applyOperationFields(tx *sql.Tx, StoryboardID string, s *OperationState) error {
+	if !s.exists() {
+		return nil
+	}
+
+	current, err := readOperationState(tx, StoryboardID, &OperationState{Kind: s.Kind, ID: s.ID})
+	if err != nil || !current.exists() {
+		return err
+	}
+
+	for _, f := range s.Fields {
+		var err error
+		switch s.Kind {
+		case "goal":
+			err = applyGoalField(tx, StoryboardID, current.Goal, s.Goal, f)
+		case "column":
+			err = applyColumnField(tx, StoryboardID, current, s, f)
+		case "story":
+			err = applyStoryField(tx, StoryboardID, current, s, f)
+		default:
+			err = errors.New("Invalid operation")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyGoalField puts back a goals name or position
+func applyGoalField(tx *sql.Tx, StoryboardID string, current *StoryboardGoal, g *StoryboardGoal, Field string) error {
+	switch Field {
+	case "name":
+		_, err := tx.Exec(
+			`UPDATE storyboard_goal SET name = $2, updated_date = NOW() WHERE id = $1;`, g.GoalID, g.GoalName)
+		return err
+	case "position":
+		if current.SortOrder == g.SortOrder {
+			return nil
+		}
+
+		sortOrder, err := placeItem(tx, "storyboard_goal", "storyboard_id", g.GoalID, StoryboardID, current.SortOrder, StoryboardID, g.SortOrder)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE storyboard_goal SET sort_order = $2 WHERE id = $1;`, g.GoalID, sortOrder)
+
+		return err
+	}
+
+	return errors.New("Invalid operation")
+}
+
+// applyColumnField puts back a columns name or position, carrying its stories back to the goal
+func applyColumnField(tx *sql.Tx, StoryboardID string, current *OperationState, s *OperationState, Field string) error {
+	c := s.Column
+	switch Field {
+	case "name":
+		_, err := tx.Exec(
+			`UPDATE storyboard_column SET name = $2, updated_date = NOW() WHERE id = $1;`, c.ColumnID, c.ColumnName)
+		return err
+	case "position":
+		if current.GoalID == s.GoalID && current.Column.SortOrder == c.SortOrder {
+			return nil
+		}
+
+		sortOrder, err := placeItem(tx, "storyboard_column", "goal_id", c.ColumnID, current.GoalID, current.Column.SortOrder, s.GoalID, c.SortOrder)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			`UPDATE storyboard_column SET goal_id = $2, sort_order = $3 WHERE id = $1;`, c.ColumnID, s.GoalID, sortOrder,
+		); err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE storyboard_story SET goal_id = $2 WHERE column_id = $1;`, c.ColumnID, s.GoalID)
+
+		return err
+	}
+
+	return errors.New("Invalid operation")
+}
+
+// applyStoryField puts back a storys field, position or one of its assignees, labels, criteria, links or external references
+func applyStoryField(tx *sql.Tx, StoryboardID string, current *OperationState, s *OperationState, Field string) error {
+	st := s.Story
+	switch Field {
+	case "name", "content", "color", "points":
+		values := map[string]interface{}{
+			"name":    st.StoryName,
+			"content": st.StoryContent,
+			"color":   st.StoryColor,
+			"points":  st.StoryPoints,
+		}
+		_, err := tx.Exec(
+			fmt.Sprintf(`UPDATE storyboard_story SET %s = $2, updated_date = NOW() WHERE id = $1;`, Field), st.StoryID, values[Field])
+		return err
+	case "closed":
+		// closing goes through the procedure so it's refused while acceptance criteria the storyboard requires are open
+		_, err := tx.Exec(`call update_story_closed($1, $2);`, st.StoryID, st.StoryClosed)
+		return err
+	case "release":
+		_, err := tx.Exec(
+			`UPDATE storyboard_story SET release_id = (SELECT id FROM storyboard_release WHERE id::TEXT = $3 AND storyboard_id = $2),
+				updated_date = NOW()
+			WHERE id = $1;`,
+			st.StoryID, StoryboardID, st.ReleaseID,
+		)
+		return err
+	case "position":
+		if current.ColumnID == s.ColumnID && current.Story.SortOrder == st.SortOrder {
+			return nil
+		}
+
+		sortOrder, err := placeItem(tx, "storyboard_story", "column_id", st.StoryID, current.ColumnID, current.Story.SortOrder, s.ColumnID, st.SortOrder)
+		if err != nil {
+			return err
+		}
+		// the goal is taken from the column as it may have been moved since
+		_, err = tx.Exec(
+			`UPDATE storyboard_story SET goal_id = (SELECT goal_id FROM storyboard_column WHERE id = $2), column_id = $2, sort_order = $3
+			WHERE id = $1;`,
+			st.StoryID, s.ColumnID, sortOrder,
+		)
+
+		return err
+	case "assignee":
+		if _, err := tx.Exec(
+			`DELETE FROM storyboard_story_assignee WHERE story_id = $1 AND user_id::TEXT = $2;`, st.StoryID, s.ItemID); err != nil {
+			return err
+		}
+		for _, a := range st.Assignees {
+			if err := insertAssignee(tx, StoryboardID, st.StoryID, a); err != nil {
+				return err
+			}
+		}
+	case "label":
+		if _, err := tx.Exec(
+			`DELETE FROM storyboard_story_label WHERE story_id = $1 AND label_id::TEXT = $2;`, st.StoryID, s.ItemID); err != nil {
+			return err
+		}
+		for _, l := range st.Labels {
+			if err := insertLabel(tx, StoryboardID, st.StoryID, l); err != nil {
+				return err
+			}
+		}
+	case "criterion":
+		return applyCriterion(tx, StoryboardID, st, s.ItemID)
+	case "link":
+		if _, err := tx.Exec(`DELETE FROM story_link WHERE id::TEXT = $1;`, s.ItemID); err != nil {
+			return err
+		}
+		for _, l := range st.Links {
+			if err := insertLink(tx, st.StoryID, l); err != nil {
+				return err
+			}
+		}
+	case "external_ref":
+		if _, err := tx.Exec(
+			`DELETE FROM story_external_ref WHERE story_id = $1 AND id::TEXT = $2;`, st.StoryID, s.ItemID); err != nil {
+			return err
+		}
+		for _, ref := range st.ExternalRefs {
+			if err := insertExternalRef(tx, StoryboardID, st.StoryID, ref); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("Invalid operation")
+	}
+
+	return nil
+}
+
+// applyCriterion puts an acceptance criterion back as it is in the storys state at its sort order,
+// closing the gap it leaves when the state doesn't have it
+func applyCriterion(tx *sql.Tx, StoryboardID string, st *StoryboardStory, CriterionID string) error {
+	var currentSortOrder int
+	err := tx.QueryRow(
+		`DELETE FROM story_criterion WHERE story_id = $1 AND id::TEXT = $2 RETURNING sort_order;`, st.StoryID, CriterionID,
+	).Scan(&currentSortOrder)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		if err := shiftSortOrder(tx, "story_criterion", "story_id", st.StoryID, currentSortOrder+1, -1); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range st.Criteria {
+		sortOrder, err := openSortOrder(tx, "story_criterion", "story_id", st.StoryID, c.SortOrder)
+		if err != nil {
+			return err
+		}
+		if err := insertCriterion(tx, StoryboardID, st.StoryID, c, sortOrder); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shiftSortOrder moves the sort order of the items in a goal, column or storyboard from a position by delta,
+// in two steps so the unique sort order constraints never see a duplicate
+func shiftSortOrder(tx *sql.Tx, Table string, Scope string, ScopeID string, From int, Delta int) error {
+	if _, err := tx.Exec(
+		fmt.Sprintf(`UPDATE %s SET sort_order = -(sort_order + $3) WHERE %s = $1 AND sort_order >= $2;`, Table, Scope),
+		ScopeID, From, Delta,
+	); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(
+		fmt.Sprintf(`UPDATE %s SET sort_order = -sort_order WHERE %s = $1 AND sort_order < 0;`, Table, Scope),
+		ScopeID,
+	)
+
+	return err
+}
+
+// openSortOrder makes room for an item at the sort order, clamped to just after the last item
+func openSortOrder(tx *sql.Tx, Table string, Scope string, ScopeID string, SortOrder int) (int, error) {
+	var next int
+	if err := tx.QueryRow(
+		fmt.Sprintf(`SELECT coalesce(MAX(sort_order), 0) + 1 FROM %s WHERE %s = $1;`, Table, Scope),
+		ScopeID,
+	).Scan(&next); err != nil {
+		return 0, err
+	}
+
+	if SortOrder < 1 || SortOrder >= next {
+		return next, nil
+	}
+
+	return SortOrder, shiftSortOrder(tx, Table, Scope, ScopeID, SortOrder, 1)
+}
+
+// removeGoal moves a goal with its columns and stories to the trash
+func removeGoal(tx *sql.Tx, StoryboardID string, UserID string, GoalID string) error {
+	return trashItem(tx, "goal", StoryboardID, UserID, GoalID)
+}
+
+// removeColumn moves a column with its stories to the trash
+func removeColumn(tx *sql.Tx, StoryboardID string, UserID string, ColumnID string) error {
+	return trashItem(tx, "column", StoryboardID, UserID, ColumnID)
+}
+
+// removeStory moves a story to the trash
+func removeStory(tx *sql.Tx, StoryboardID string, UserID string, StoryID string) error {
+	return trashItem(tx, "story", StoryboardID, UserID, StoryID)
+}
+
+// trashItem moves a goal, column or story of the storyboard to the trash when it isn't there already
+func trashItem(tx *sql.Tx, Kind string, StoryboardID string, UserID string, ID string) error {
+	var deleted bool
+	if err := tx.QueryRow(
+		fmt.Sprintf(`SELECT deleted_date IS NOT NULL FROM storyboard_%s WHERE id = $1 AND storyboard_id = $2;`, Kind),
+		ID, StoryboardID,
+	).Scan(&deleted); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if deleted {
+		return nil
+	}
+
+	_, err := tx.Exec(fmt.Sprintf(`CALL delete_storyboard_%s($1, $2);`, Kind), ID, UserID)
+
+	return err
+}
+
+// untrashItem restores a goal, column or story of the storyboard from the trash when it's there
+func untrashItem(tx *sql.Tx, Kind string, StoryboardID string, ID string) error {
+	var deleted bool
+	if err := tx.QueryRow(
+		fmt.Sprintf(`SELECT deleted_date IS NOT NULL FROM storyboard_%s WHERE id = $1 AND storyboard_id = $2;`, Kind),
+		ID, StoryboardID,
+	).Scan(&deleted); err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if !deleted {
+		return nil
+	}
+
+	_, err := tx.Exec(fmt.Sprintf(`CALL restore_storyboard_%s($1);`, Kind), ID)
+
+	return err
+}
+
+// restoreGoal restores a deleted goal from the trash, or recreates it with its columns and stories once purged
+func restoreGoal(tx *sql.Tx, StoryboardID string, g *StoryboardGoal) error {
+	if err := untrashItem(tx, "goal", StoryboardID, g.GoalID); err != nil {
+		return err
+	}
+
+	var found bool
+	if err := tx.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM storyboard_goal WHERE id = $1 AND storyboard_id = $2);`, g.GoalID, StoryboardID,
+	).Scan(&found); err != nil || found {
+		return err
+	}
+
+	sortOrder, err := openSortOrder(tx, "storyboard_goal", "storyboard_id", StoryboardID, g.SortOrder)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO storyboard_goal (id, storyboard_id, name, sort_order) VALUES ($1, $2, $3, $4);`,
+		g.GoalID, StoryboardID, g.GoalName, sortOrder,
+	); err != nil {
+		return err
+	}
+
+	for _, c := range g.Columns {
+		if err := insertColumn(tx, StoryboardID, g.GoalID, c, c.SortOrder); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreColumn restores a deleted column from the trash, or recreates it with its stories once purged
+func restoreColumn(tx *sql.Tx, StoryboardID string, GoalID string, c *StoryboardColumn) error {
+	if err := untrashItem(tx, "column", StoryboardID, c.ColumnID); err != nil {
+		return err
+	}
+
+	var found bool
+	if err := tx.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM storyboard_column WHERE id = $1 AND storyboard_id = $2);`, c.ColumnID, StoryboardID,
+	).Scan(&found); err != nil || found {
+		return err
+	}
+
+	sortOrder, err := openSortOrder(tx, "storyboard_column", "goal_id", GoalID, c.SortOrder)
+	if err != nil {
+		return err
+	}
+
+	return insertColumn(tx, StoryboardID, GoalID, c, sortOrder)
+}
+
+// restoreStory restores a deleted story from the trash, or recreates it with its comments once purged
+func restoreStory(tx *sql.Tx, StoryboardID string, GoalID string, ColumnID string, st *StoryboardStory) error {
+	if err := untrashItem(tx, "story", StoryboardID, st.StoryID); err != nil {
+		return err
+	}
+
+	var found bool
+	if err := tx.QueryRow(
+		`SELECT EXISTS (SELECT 1 FROM storyboard_story WHERE id = $1 AND storyboard_id = $2);`, st.StoryID, StoryboardID,
+	).Scan(&found); err != nil || found {
+		return err
+	}
+
+	sortOrder, err := openSortOrder(tx, "storyboard_story", "column_id", ColumnID, st.SortOrder)
+	if err != nil {
+		return err
+	}
+
+	return insertStory(tx, StoryboardID, GoalID, ColumnID, st, sortOrder)
+}
+
+// placeItem takes a goal, column or story out of its current scopes order and makes room for it at the sort order
+// in the (possibly different) scope, returning the sort order to give it
+func placeItem(tx *sql.Tx, Table string, Scope string, ID string, CurrentScopeID string, CurrentSortOrder int, ScopeID string, SortOrder int) (int, error) {
+	if _, err := tx.Exec(fmt.Sprintf(`UPDATE %s SET sort_order = NULL WHERE id = $1;`, Table), ID); err != nil {
+		return 0, err
+	}
+	if err := shiftSortOrder(tx, Table, Scope, CurrentScopeID, CurrentSortOrder+1, -1); err != nil {
+		return 0, err
+	}
+
+	return openSortOrder(tx, Table, Scope, ScopeID, SortOrder)
+}
+
+// insertColumn adds a column with its stories using their original IDs
+func insertColumn(tx *sql.Tx, StoryboardID string, GoalID string, c *StoryboardColumn, SortOrder int) error {
+	if _, err := tx.Exec(
+		`INSERT INTO storyboard_column (id, storyboard_id, goal_id, name, sort_order) VALUES ($1, $2, $3, $4, $5);`,
+		c.ColumnID, StoryboardID, GoalID, c.ColumnName, SortOrder,
+	); err != nil {
+		return err
+	}
+
+	for _, st := range c.Stories {
+		if err := insertStory(tx, StoryboardID, GoalID, c.ColumnID, st, st.SortOrder); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertStory adds a story with its comments, assignees, labels, acceptance criteria, links and external references using their original IDs, releases and comment authors
+// that no longer exist are left off (replies to a comment left off become top level comments)
+func insertStory(tx *sql.Tx, StoryboardID string, GoalID string, ColumnID string, st *StoryboardStory, SortOrder int) error {
+	if _, err := tx.Exec(
+		`INSERT INTO storyboard_story
+			(id, storyboard_id, goal_id, column_id, name, content, color, points, closed, sort_order, release_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+			(SELECT id FROM storyboard_release WHERE id::TEXT = $11 AND storyboard_id = $2));`,
+		st.StoryID, StoryboardID, GoalID, ColumnID, st.StoryName, st.StoryContent, st.StoryColor,
+		st.StoryPoints, st.StoryClosed, SortOrder, st.ReleaseID,
+	); err != nil {
+		return err
+	}
+
+	return insertStoryItems(tx, StoryboardID, st)
+}
+
+// insertStoryItems adds a storys comments, assignees, labels, acceptance criteria, links and external references using their original IDs
+func insertStoryItems(tx *sql.Tx, StoryboardID string, st *StoryboardStory) error {
+	for _, cm := range st.Comments {
+		if _, err := tx.Exec(
+			`INSERT INTO story_comment (id, storyboard_id, story_id, user_id, comment, created_date, parent_id, edited, updated_date)
+			SELECT $1, $2, $3, u.id, $5, COALESCE(NULLIF($6, '')::TIMESTAMP, NOW()),
+				(SELECT id FROM story_comment WHERE id::TEXT = $7 AND story_id = $3), $8, COALESCE(NULLIF($9, '')::TIMESTAMP, NOW())
+			FROM users u WHERE u.id::TEXT = $4;`,
+			cm.CommentID, StoryboardID, st.StoryID, cm.UserID, cm.Comment, cm.CreateDate, cm.ParentID, cm.Edited, cm.UpdatedDate,
+		); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range st.Assignees {
+		if err := insertAssignee(tx, StoryboardID, st.StoryID, a); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range st.Labels {
+		if err := insertLabel(tx, StoryboardID, st.StoryID, l); err != nil {
+			return err
+		}
+	}
+
+	for _, c := range st.Criteria {
+		if err := insertCriterion(tx, StoryboardID, st.StoryID, c, c.SortOrder); err != nil {
+			return err
+		}
+	}
+
+	for _, l := range st.Links {
+		if err := insertLink(tx, st.StoryID, l); err != nil {
+			return err
+		}
+	}
+
+	for _, ref := range st.ExternalRefs {
+		if err := insertExternalRef(tx, StoryboardID, st.StoryID, ref); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// insertExternalRef adds an external reference to a story using its original ID
+func insertExternalRef(tx *sql.Tx, StoryboardID string, StoryID string, ref *StoryExternalRef) error {
+	_, err := tx.Exec(
+		`INSERT INTO story_external_ref (id, storyboard_id, story_id, system, ref_key, url, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT DO NOTHING;`,
+		ref.RefID, StoryboardID, StoryID, ref.System, ref.Key, ref.URL, ref.Status,
+	)
+
+	return err
+}
+
+// insertLink adds a link to or from a story using its original ID, a link to a story that no longer exists
+// or a blocking link that would now create a cycle is left off
+func insertLink(tx *sql.Tx, StoryID string, l *StoryLink) error {
+	FromID, ToID := StoryID, l.StoryID
+	if l.Direction == "inward" {
+		FromID, ToID = l.StoryID, StoryID
+	}
+	if l.Type == "blocks" {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1);`, storyLinkLockID); err != nil {
+			return err
+		}
+	}
+
+	_, err := tx.Exec(
+		`INSERT INTO story_link (id, from_story_id, to_story_id, link_type)
+		SELECT $1, fs.id, ts.id, $4 FROM storyboard_story fs, storyboard_story ts
+		WHERE fs.id::TEXT = $2 AND ts.id::TEXT = $3
+		AND ($4 != 'blocks' OR NOT story_link_creates_cycle(fs.id, ts.id))
+		ON CONFLICT DO NOTHING;`,
+		l.LinkID, FromID, ToID, l.Type,
+	)
+
+	return err
+}
+
+// insertCriterion adds an acceptance criterion to a story using its original ID
+func insertCriterion(tx *sql.Tx, StoryboardID string, StoryID string, c *StoryCriterion, SortOrder int) error {
+	_, err := tx.Exec(
+		`INSERT INTO story_criterion (id, storyboard_id, story_id, text, done, sort_order) VALUES ($1, $2, $3, $4, $5, $6);`,
+		c.CriterionID, StoryboardID, StoryID, c.Text, c.Done, SortOrder,
+	)
+
+	return err
+}
+
+// insertLabel adds one of the storyboards labels to a story, a label since deleted from the storyboard is left off
+func insertLabel(tx *sql.Tx, StoryboardID string, StoryID string, l *StoryboardLabel) error {
+	_, err := tx.Exec(
+		`INSERT INTO storyboard_story_label (story_id, label_id)
+		SELECT $1, sl.id FROM storyboard_label sl WHERE sl.id::TEXT = $3 AND sl.storyboard_id = $2
+		ON CONFLICT DO NOTHING;`,
+		StoryID, StoryboardID, l.LabelID,
+	)
+
+	return err
+}
+
+// insertAssignee assigns a user to a story, a user that no longer exists is left off
+func insertAssignee(tx *sql.Tx, StoryboardID string, StoryID string, a *StoryAssignee) error {
+	_, err := tx.Exec(
+		`INSERT INTO storyboard_story_assignee (story_id, user_id, storyboard_id)
+		SELECT $1, u.id, $2 FROM users u WHERE u.id::TEXT = $3
+		ON CONFLICT DO NOTHING;`,
+		StoryID, StoryboardID, a.UserID,
+	)
+
+	return err
+}
@@ -0,0 +1,31 @@
+package database
+
+import "testing"
+
+func TestOperationStates(t *testing.T) {
+	goals := []*StoryboardGoal{
+		{GoalID: "g1", SortOrder: 1, Columns: []*StoryboardColumn{
+			{ColumnID: "c1", SortOrder: 1, Stories: []*StoryboardStory{
+				{StoryID: "s1", SortOrder: 1},
+				{StoryID: "s2", SortOrder: 2},
+			}},
+		}},
+		{GoalID: "g2", SortOrder: 2},
+	}
+
+	s := storyState(goals, "s2")
+	if s.Story == nil || s.GoalID != "g1" || s.ColumnID != "c1" {
+		t.Error("Expected story s2 in goal g1 column c1, got ", s.GoalID, s.ColumnID)
+	}
+
+	if removed := columnState(goals, "c9"); removed.exists() || removed.ID != "c9" {
+		t.Error("Expected missing column state for c9, got ", removed.ID)
+	}
+
+	if g := itemState(goals, "goal", "g2"); g.Goal == nil || g.Kind != "goal" {
+		t.Error("Expected goal g2 state")
+	}
+	if c := itemState(goals, "column", "c1"); c.Column == nil || c.GoalID != "g1" || len(c.Column.Stories) != 2 {
+		t.Error("Expected column c1 with its stories in goal g1")
+	}
+}
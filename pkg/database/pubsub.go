@@ -0,0 +1,192 @@
+package database
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// eventsChannel is the postgres NOTIFY channel socket events are published on
+	eventsChannel = "exothermic_events"
+	// maxNotifyPayload keeps notifications under the postgres 8000 byte payload limit
+	maxNotifyPayload = 7000
+	// presenceTTL is how long an instances presence counts are trusted without a heartbeat
+	presenceTTL = "45 seconds"
+)
+
+// PublishEvent publishes a socket event to every instance, payloads too large
+// for a notification are stored in socket_event and referenced by id instead
+func (d *Database) PublishEvent(payload []byte) error {
+	notification := "m" + string(payload)
+
+	if len(notification) > maxNotifyPayload {
+		var id int64
+		if err := d.db.QueryRow(
+			`INSERT INTO socket_event (payload) VALUES ($1) RETURNING id;`,
+			string(payload),
+		).Scan(&id); err != nil {
+			log.Println(err)
+			return err
+		}
+		notification = "r" + strconv.FormatInt(id, 10)
+	}
+
+	if _, err := d.db.Exec(`SELECT pg_notify($1, $2);`, eventsChannel, notification); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// ListenEvents relays socket events published by any instance to the handler,
+// reconnected is called once the listener reconnects, before any event published after
+func (d *Database) ListenEvents(handler func(payload []byte), reconnected func()) error {
+	listener := pq.NewListener(d.connectionString(), 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("socket event listener: ", err)
+		}
+	})
+	if err := listener.Listen(eventsChannel); err != nil {
+		return err
+	}
+
+	go func() {
+		for n := range listener.Notify {
+			// a nil notification means the connection was re-established, events may have been missed
+			if n == nil {
+				log.Println("socket event listener reconnected")
+				reconnected()
+				continue
+			}
+
+			switch {
+			case strings.HasPrefix(n.Extra, "m"):
+				handler([]byte(n.Extra[1:]))
+			case strings.HasPrefix(n.Extra, "r"):
+				var payload string
+				if err := d.db.QueryRow(
+					`SELECT payload FROM socket_event WHERE id = $1;`, n.Extra[1:],
+				).Scan(&payload); err != nil {
+					log.Println(err)
+					continue
+				}
+				handler([]byte(payload))
+			}
+		}
+	}()
+
+	return nil
+}
+
+// SaveClusterPresence marks this instance alive, replaces its active connection counts per storyboard
+// and removes large socket events every instance has had time to read
+func (d *Database) SaveClusterPresence(NodeID string, Connections map[string]int) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		`INSERT INTO cluster_node (node_id) VALUES ($1)
+		ON CONFLICT (node_id) DO UPDATE SET updated_date = NOW();`,
+		NodeID,
+	); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM cluster_presence WHERE node_id = $1;`, NodeID); err != nil {
+		log.Println(err)
+		return err
+	}
+	for StoryboardID, count := range Connections {
+		if _, err := tx.Exec(
+			`INSERT INTO cluster_presence (node_id, storyboard_id, connections) VALUES ($1, $2, $3);`,
+			NodeID, StoryboardID, count,
+		); err != nil {
+			log.Println(err)
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM socket_event WHERE created_date < NOW() - INTERVAL '5 minutes';`); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetClusterPresence gets the active storyboard and storyboard user counts across every instance
+func (d *Database) GetClusterPresence() (int, int, error) {
+	var storyboards, users int
+	err := d.db.QueryRow(
+		`SELECT COUNT(DISTINCT storyboard_id), COALESCE(SUM(connections), 0)
+		FROM cluster_presence WHERE updated_date > NOW() - $1::INTERVAL;`,
+		presenceTTL,
+	).Scan(&storyboards, &users)
+	if err != nil {
+		log.Println(err)
+		return 0, 0, err
+	}
+
+	return storyboards, users, nil
+}
+
+// GetClusterNodes gets the instances with a recent presence heartbeat
+func (d *Database) GetClusterNodes() ([]string, error) {
+	rows, err := d.db.Query(
+		`SELECT node_id FROM cluster_node WHERE updated_date > NOW() - $1::INTERVAL;`,
+		presenceTTL,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var NodeIDs []string
+	for rows.Next() {
+		var NodeID string
+		if err := rows.Scan(&NodeID); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		NodeIDs = append(NodeIDs, NodeID)
+	}
+
+	return NodeIDs, rows.Err()
+}
+
+// ExpireUserSessions ends the storyboard sessions of the instance (when set) and of instances without a recent
+// presence heartbeat, returning the storyboards whose users changed
+func (d *Database) ExpireUserSessions(NodeID string) ([]string, error) {
+	rows, err := d.db.Query(
+		`SELECT * FROM expire_storyboard_user_sessions($1, $2::INTERVAL);`,
+		NodeID,
+		presenceTTL,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var StoryboardIDs []string
+	for rows.Next() {
+		var StoryboardID string
+		if err := rows.Scan(&StoryboardID); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+		StoryboardIDs = append(StoryboardIDs, StoryboardID)
+	}
+
+	return StoryboardIDs, rows.Err()
+}
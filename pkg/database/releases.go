@@ -0,0 +1,142 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// nullString converts an optional string (e.g. a YYYY-MM-DD date) into a nullable db value
+func nullString(Value string) sql.NullString {
+	return sql.NullString{String: Value, Valid: Value != ""}
+}
+
+// AddRelease adds a release slice to a storyboard
+func (d *Database) AddRelease(StoryboardID string, UserID string, ReleaseName string, TargetDate string) ([]*StoryboardRelease, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.db.Exec(
+		`call release_add($1, $2, $3);`,
+		StoryboardID,
+		ReleaseName,
+		nullString(TargetDate),
+	); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	releases := d.GetStoryboardReleases(StoryboardID)
+
+	return releases, nil
+}
+
+// ReviseRelease updates a storyboard releases name and target date
+func (d *Database) ReviseRelease(StoryboardID string, UserID string, ReleaseID string, ReleaseName string, TargetDate string) ([]*StoryboardRelease, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.db.Exec(
+		`call release_edit($1, $2, $3, $4);`,
+		StoryboardID,
+		ReleaseID,
+		ReleaseName,
+		nullString(TargetDate),
+	); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	releases := d.GetStoryboardReleases(StoryboardID)
+
+	return releases, nil
+}
+
+// MoveRelease reorders a storyboard release to before another release (or last)
+func (d *Database) MoveRelease(StoryboardID string, UserID string, ReleaseID string, PlaceBefore string) ([]*StoryboardRelease, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.db.Exec(
+		`call release_move($1, $2, $3);`,
+		StoryboardID,
+		ReleaseID,
+		PlaceBefore,
+	); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	releases := d.GetStoryboardReleases(StoryboardID)
+
+	return releases, nil
+}
+
+// DeleteRelease deletes a storyboard release, its stories become unassigned
+func (d *Database) DeleteRelease(StoryboardID string, UserID string, ReleaseID string) ([]*StoryboardRelease, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.db.Exec(
+		`call release_delete($1, $2);`,
+		StoryboardID,
+		ReleaseID,
+	); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	releases := d.GetStoryboardReleases(StoryboardID)
+
+	return releases, nil
+}
+
+// ReviseStoryRelease moves a story into a release, an empty ReleaseID unassigns it
+func (d *Database) ReviseStoryRelease(StoryboardID string, UserID string, StoryID string, ReleaseID string) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"release"}}
+	if err := d.changeOperation(StoryboardID, UserID, "update_story_release", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call update_story_release($1, $2, $3);`, StoryboardID, StoryID, nullString(ReleaseID))
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// GetStoryboardReleases retrieves the releases (with point totals) for a given storyboard from db
+func (d *Database) GetStoryboardReleases(StoryboardID string) []*StoryboardRelease {
+	var releases = make([]*StoryboardRelease, 0)
+	rows, err := d.db.Query(
+		`SELECT * FROM get_storyboard_releases($1);`,
+		StoryboardID,
+	)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var sr StoryboardRelease
+			if err := rows.Scan(&sr.ReleaseID, &sr.ReleaseName, &sr.SortOrder, &sr.TargetDate, &sr.Points, &sr.StoryCount); err != nil {
+				log.Println(err)
+			} else {
+				releases = append(releases, &sr)
+			}
+		}
+	}
+
+	return releases
+}
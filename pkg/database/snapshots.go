@@ -0,0 +1,617 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+)
+
+// StoryboardSnapshot is a point in time copy of a storyboard, Storyboard is only set when getting a single snapshot
+type StoryboardSnapshot struct {
+	SnapshotID   string            `json:"id"`
+	StoryboardID string            `json:"storyboard_id"`
+	Name         string            `json:"name"`
+	Automatic    bool              `json:"automatic"`
+	CreatedBy    string            `json:"created_by"`
+	Revision     int               `json:"revision"`
+	CreatedDate  string            `json:"created_date"`
+	Storyboard   *StoryboardExport `json:"storyboard,omitempty"`
+}
+
+// StoryLocation is where a story sits on the board
+type StoryLocation struct {
+	GoalID     string `json:"goal_id"`
+	GoalName   string `json:"goal_name"`
+	ColumnID   string `json:"column_id"`
+	ColumnName string `json:"column_name"`
+}
+
+// GoalChange is a goal added or removed between two versions of a storyboard
+type GoalChange struct {
+	GoalID   string `json:"id"`
+	GoalName string `json:"name"`
+}
+
+// StoryChange is a story added, removed, moved or revised between two versions of a storyboard
+type StoryChange struct {
+	StoryID    string         `json:"id"`
+	StoryName  string         `json:"name"`
+	From       *StoryLocation `json:"from,omitempty"`
+	To         *StoryLocation `json:"to,omitempty"`
+	FromPoints *int           `json:"from_points,omitempty"`
+	ToPoints   *int           `json:"to_points,omitempty"`
+	Closed     *bool          `json:"closed,omitempty"`
+}
+
+// StoryboardDiff is the structural difference between two versions of a storyboard
+type StoryboardDiff struct {
+	GoalsAdded     []*GoalChange  `json:"goals_added"`
+	GoalsRemoved   []*GoalChange  `json:"goals_removed"`
+	StoriesAdded   []*StoryChange `json:"stories_added"`
+	StoriesRemoved []*StoryChange `json:"stories_removed"`
+	StoriesMoved   []*StoryChange `json:"stories_moved"`
+	PointsChanged  []*StoryChange `json:"points_changed"`
+	ClosedToggled  []*StoryChange `json:"closed_toggled"`
+}
+
+// locatedStory is a story with its location, used to compare versions
+type locatedStory struct {
+	story    *StoryboardStory
+	location *StoryLocation
+}
+
+// locateStories lists the stories of a goal tree in board order with their locations
+func locateStories(goals []*StoryboardGoal) ([]*locatedStory, map[string]*locatedStory) {
+	list := make([]*locatedStory, 0)
+	byID := make(map[string]*locatedStory)
+	for _, g := range goals {
+		for _, c := range g.Columns {
+			for _, st := range c.Stories {
+				ls := &locatedStory{story: st, location: &StoryLocation{
+					GoalID:     g.GoalID,
+					GoalName:   g.GoalName,
+					ColumnID:   c.ColumnID,
+					ColumnName: c.ColumnName,
+				}}
+				list = append(list, ls)
+				byID[st.StoryID] = ls
+			}
+		}
+	}
+
+	return list, byID
+}
+
+// DiffStoryboards compares two versions of a storyboard
+func DiffStoryboards(From *StoryboardExport, To *StoryboardExport) *StoryboardDiff {
+	diff := &StoryboardDiff{
+		GoalsAdded:     make([]*GoalChange, 0),
+		GoalsRemoved:   make([]*GoalChange, 0),
+		StoriesAdded:   make([]*StoryChange, 0),
+		StoriesRemoved: make([]*StoryChange, 0),
+		StoriesMoved:   make([]*StoryChange, 0),
+		PointsChanged:  make([]*StoryChange, 0),
+		ClosedToggled:  make([]*StoryChange, 0),
+	}
+
+	fromGoals := make(map[string]bool)
+	for _, g := range From.Goals {
+		fromGoals[g.GoalID] = true
+	}
+	toGoals := make(map[string]bool)
+	for _, g := range To.Goals {
+		toGoals[g.GoalID] = true
+		if !fromGoals[g.GoalID] {
+			diff.GoalsAdded = append(diff.GoalsAdded, &GoalChange{GoalID: g.GoalID, GoalName: g.GoalName})
+		}
+	}
+	for _, g := range From.Goals {
+		if !toGoals[g.GoalID] {
+			diff.GoalsRemoved = append(diff.GoalsRemoved, &GoalChange{GoalID: g.GoalID, GoalName: g.GoalName})
+		}
+	}
+
+	fromStories, fromByID := locateStories(From.Goals)
+	toStories, toByID := locateStories(To.Goals)
+
+	for _, t := range toStories {
+		f, existed := fromByID[t.story.StoryID]
+		if !existed {
+			diff.StoriesAdded = append(diff.StoriesAdded, &StoryChange{
+				StoryID: t.story.StoryID, StoryName: t.story.StoryName, To: t.location,
+			})
+			continue
+		}
+
+		if f.location.ColumnID != t.location.ColumnID {
+			diff.StoriesMoved = append(diff.StoriesMoved, &StoryChange{
+				StoryID: t.story.StoryID, StoryName: t.story.StoryName, From: f.location, To: t.location,
+			})
+		}
+		if f.story.StoryPoints != t.story.StoryPoints {
+			fromPoints, toPoints := f.story.StoryPoints, t.story.StoryPoints
+			diff.PointsChanged = append(diff.PointsChanged, &StoryChange{
+				StoryID: t.story.StoryID, StoryName: t.story.StoryName, FromPoints: &fromPoints, ToPoints: &toPoints,
+			})
+		}
+		if f.story.StoryClosed != t.story.StoryClosed {
+			closed := t.story.StoryClosed
+			diff.ClosedToggled = append(diff.ClosedToggled, &StoryChange{
+				StoryID: t.story.StoryID, StoryName: t.story.StoryName, Closed: &closed,
+			})
+		}
+	}
+
+	for _, f := range fromStories {
+		if _, kept := toByID[f.story.StoryID]; !kept {
+			diff.StoriesRemoved = append(diff.StoriesRemoved, &StoryChange{
+				StoryID: f.story.StoryID, StoryName: f.story.StoryName, From: f.location,
+			})
+		}
+	}
+
+	return diff
+}
+
+// CreateStoryboardSnapshot saves a named snapshot of the storyboard as it is now
+func (d *Database) CreateStoryboardSnapshot(StoryboardID string, UserID string, Name string) (*StoryboardSnapshot, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	return d.saveStoryboardSnapshot(StoryboardID, UserID, Name, false)
+}
+
+// saveStoryboardSnapshot saves a snapshot of the storyboard, CreatedBy is empty for automatic snapshots
+func (d *Database) saveStoryboardSnapshot(StoryboardID string, CreatedBy string, Name string, Automatic bool) (*StoryboardSnapshot, error) {
+	storyboard, err := d.GetStoryboard(StoryboardID)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, _ := json.Marshal(newStoryboardExport(storyboard))
+
+	s := &StoryboardSnapshot{
+		StoryboardID: StoryboardID,
+		Name:         Name,
+		Automatic:    Automatic,
+		CreatedBy:    CreatedBy,
+		Revision:     storyboard.Revision,
+	}
+	if err := d.db.QueryRow(
+		`INSERT INTO storyboard_snapshot (storyboard_id, name, automatic, created_by, revision, snapshot)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_date;`,
+		StoryboardID, Name, Automatic, nullString(CreatedBy), storyboard.Revision, string(snapshot),
+	).Scan(&s.SnapshotID, &s.CreatedDate); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// GetStoryboardSnapshots retrieves the snapshots of a storyboard newest first, without their contents
+func (d *Database) GetStoryboardSnapshots(StoryboardID string) []*StoryboardSnapshot {
+	var snapshots = make([]*StoryboardSnapshot, 0)
+	rows, err := d.db.Query(
+		`SELECT id, storyboard_id, coalesce(name, ''), automatic, coalesce(created_by::TEXT, ''), revision, created_date
+		FROM storyboard_snapshot WHERE storyboard_id = $1 ORDER BY created_date DESC;`,
+		StoryboardID,
+	)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var s StoryboardSnapshot
+			if err := rows.Scan(
+				&s.SnapshotID, &s.StoryboardID, &s.Name, &s.Automatic, &s.CreatedBy, &s.Revision, &s.CreatedDate,
+			); err != nil {
+				log.Println(err)
+			} else {
+				snapshots = append(snapshots, &s)
+			}
+		}
+	}
+
+	return snapshots
+}
+
+// GetStoryboardSnapshot retrieves a storyboard snapshot with its contents
+func (d *Database) GetStoryboardSnapshot(StoryboardID string, SnapshotID string) (*StoryboardSnapshot, error) {
+	var s StoryboardSnapshot
+	var snapshot string
+
+	if err := d.db.QueryRow(
+		`SELECT id, storyboard_id, coalesce(name, ''), automatic, coalesce(created_by::TEXT, ''), revision, created_date, snapshot
+		FROM storyboard_snapshot WHERE storyboard_id = $1 AND id = $2;`,
+		StoryboardID, SnapshotID,
+	).Scan(
+		&s.SnapshotID, &s.StoryboardID, &s.Name, &s.Automatic, &s.CreatedBy, &s.Revision, &s.CreatedDate, &snapshot,
+	); err != nil {
+		log.Println(err)
+		return nil, errors.New("Snapshot not found")
+	}
+
+	if err := json.Unmarshal([]byte(snapshot), &s.Storyboard); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// RestoreStoryboardSnapshot replaces the storyboards goals, columns, stories, personas, releases
+// and color legend with those of the snapshot, keeping their original IDs, goals, columns and stories
+// not in the snapshot are moved to the trash
+func (d *Database) RestoreStoryboardSnapshot(StoryboardID string, UserID string, SnapshotID string) (*Storyboard, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	snapshot, err := d.GetStoryboardSnapshot(StoryboardID, SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+	export := snapshot.Storyboard
+
+	// keep the board as it was so the restore itself can be reverted
+	if _, err := d.saveStoryboardSnapshot(StoryboardID, UserID, "Before restoring snapshot", false); err != nil {
+		return nil, err
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, clear := range []string{
+		`DELETE FROM storyboard_persona WHERE storyboard_id = $1;`,
+		// the undo history refers to the board as it was before the restore
+		`DELETE FROM storyboard_operation WHERE storyboard_id = $1;`,
+	} {
+		if _, err := tx.Exec(clear, StoryboardID); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	if err := trashSnapshotRemoved(tx, StoryboardID, UserID, export); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	if export.ColorLegend != nil {
+		colorLegend, _ := json.Marshal(export.ColorLegend)
+		if _, err := tx.Exec(
+			`UPDATE storyboard SET color_legend = $2 WHERE id = $1;`, StoryboardID, string(colorLegend)); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	for _, p := range export.Personas {
+		if _, err := tx.Exec(
+			`INSERT INTO storyboard_persona (id, storyboard_id, name, role, description) VALUES ($1, $2, $3, $4, $5);`,
+			p.PersonaID, StoryboardID, p.Name, p.Role, p.Description,
+		); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	// releases are kept in place (rather than replaced) so stories in the trash stay in theirs
+	releases := append([]*StoryboardRelease(nil), export.Releases...)
+	sort.SliceStable(releases, func(i, j int) bool { return releases[i].SortOrder < releases[j].SortOrder })
+	for i, sr := range releases {
+		if err := upsertSnapshotRow(tx,
+			`INSERT INTO storyboard_release (id, storyboard_id, name, sort_order, target_date) VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, sort_order = EXCLUDED.sort_order,
+				target_date = EXCLUDED.target_date, updated_date = NOW()
+			WHERE storyboard_release.storyboard_id = EXCLUDED.storyboard_id;`,
+			sr.ReleaseID, StoryboardID, sr.ReleaseName, i+1, nullString(sr.TargetDate),
+		); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	// the goals, columns and stories left are all in the snapshot, their places are cleared for it to fill
+	for _, clear := range []string{
+		`UPDATE storyboard_story SET sort_order = NULL WHERE storyboard_id = $1 AND deleted_date IS NULL;`,
+		`UPDATE storyboard_column SET sort_order = NULL WHERE storyboard_id = $1 AND deleted_date IS NULL;`,
+		`UPDATE storyboard_goal SET sort_order = NULL WHERE storyboard_id = $1 AND deleted_date IS NULL;`,
+	} {
+		if _, err := tx.Exec(clear, StoryboardID); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	for _, g := range export.Goals {
+		if err := restoreSnapshotGoal(tx, StoryboardID, g); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	if _, err := tx.Exec(
+		`UPDATE storyboard SET updated_date = NOW() WHERE id = $1;`, StoryboardID); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return d.GetStoryboard(StoryboardID)
+}
+
+// trashSnapshotRemoved moves the storyboards goals, columns and stories that aren't in the snapshot to the trash
+// and removes its releases that aren't, what's already in the trash stays there
+func trashSnapshotRemoved(tx *sql.Tx, StoryboardID string, UserID string, export *StoryboardExport) error {
+	keep := make(map[string]bool)
+	for _, sr := range export.Releases {
+		keep[sr.ReleaseID] = true
+	}
+	for _, g := range export.Goals {
+		keep[g.GoalID] = true
+		for _, c := range g.Columns {
+			keep[c.ColumnID] = true
+			for _, st := range c.Stories {
+				keep[st.StoryID] = true
+			}
+		}
+	}
+
+	// goals first as their columns and stories are trashed along with them
+	for _, Kind := range []string{"goal", "column", "story"} {
+		IDs, err := liveItemIDs(tx, fmt.Sprintf(
+			`SELECT id FROM storyboard_%s WHERE storyboard_id = $1 AND deleted_date IS NULL;`, Kind), StoryboardID)
+		if err != nil {
+			return err
+		}
+		for _, ID := range IDs {
+			if keep[ID] {
+				continue
+			}
+			if err := trashItem(tx, Kind, StoryboardID, UserID, ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	IDs, err := liveItemIDs(tx, `SELECT id FROM storyboard_release WHERE storyboard_id = $1;`, StoryboardID)
+	if err != nil {
+		return err
+	}
+	for _, ID := range IDs {
+		if keep[ID] {
+			continue
+		}
+		if _, err := tx.Exec(`DELETE FROM storyboard_release WHERE id = $1;`, ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// liveItemIDs gets the IDs the query selects for the storyboard
+func liveItemIDs(tx *sql.Tx, Query string, StoryboardID string) ([]string, error) {
+	rows, err := tx.Query(Query, StoryboardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	IDs := make([]string, 0)
+	for rows.Next() {
+		var ID string
+		if err := rows.Scan(&ID); err != nil {
+			return nil, err
+		}
+		IDs = append(IDs, ID)
+	}
+
+	return IDs, rows.Err()
+}
+
+// upsertSnapshotRow inserts a snapshot row or updates the row with its ID, which must be on the same storyboard
+func upsertSnapshotRow(tx *sql.Tx, Query string, Args ...interface{}) error {
+	res, err := tx.Exec(Query, Args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return errors.New("Snapshot item belongs to another storyboard")
+	}
+
+	return nil
+}
+
+// restoreSnapshotGoal puts a snapshot goal with its columns and stories on the storyboard, those with IDs
+// of items in the trash are taken back out of it as they are in the snapshot
+func restoreSnapshotGoal(tx *sql.Tx, StoryboardID string, g *StoryboardGoal) error {
+	if err := upsertSnapshotRow(tx,
+		`INSERT INTO storyboard_goal (id, storyboard_id, name, sort_order) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, sort_order = EXCLUDED.sort_order,
+			deleted_date = NULL, deleted_by = NULL, deleted_sort_order = NULL, updated_date = NOW()
+		WHERE storyboard_goal.storyboard_id = EXCLUDED.storyboard_id;`,
+		g.GoalID, StoryboardID, g.GoalName, g.SortOrder,
+	); err != nil {
+		return err
+	}
+
+	// columns in the trash along with the goal stay there on their own
+	if _, err := tx.Exec(
+		`UPDATE storyboard_column SET deleted_sort_order = sort_order, sort_order = NULL
+		WHERE goal_id = $1 AND deleted_date IS NOT NULL AND sort_order IS NOT NULL;`, g.GoalID,
+	); err != nil {
+		return err
+	}
+
+	for _, c := range g.Columns {
+		if err := restoreSnapshotColumn(tx, StoryboardID, g.GoalID, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreSnapshotColumn puts a snapshot column with its stories on the storyboard
+func restoreSnapshotColumn(tx *sql.Tx, StoryboardID string, GoalID string, c *StoryboardColumn) error {
+	if err := upsertSnapshotRow(tx,
+		`INSERT INTO storyboard_column (id, storyboard_id, goal_id, name, sort_order) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (id) DO UPDATE SET goal_id = EXCLUDED.goal_id, name = EXCLUDED.name, sort_order = EXCLUDED.sort_order,
+			deleted_date = NULL, deleted_by = NULL, deleted_sort_order = NULL, updated_date = NOW()
+		WHERE storyboard_column.storyboard_id = EXCLUDED.storyboard_id;`,
+		c.ColumnID, StoryboardID, GoalID, c.ColumnName, c.SortOrder,
+	); err != nil {
+		return err
+	}
+
+	// stories in the trash along with the column stay there on their own
+	if _, err := tx.Exec(
+		`UPDATE storyboard_story SET deleted_sort_order = sort_order, sort_order = NULL
+		WHERE column_id = $1 AND deleted_date IS NOT NULL AND sort_order IS NOT NULL;`, c.ColumnID,
+	); err != nil {
+		return err
+	}
+
+	for _, st := range c.Stories {
+		if err := restoreSnapshotStory(tx, StoryboardID, GoalID, c.ColumnID, st); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restoreSnapshotStory puts a snapshot story on the storyboard, replacing its comments, assignees, labels,
+// acceptance criteria, links and external references with those in the snapshot
+func restoreSnapshotStory(tx *sql.Tx, StoryboardID string, GoalID string, ColumnID string, st *StoryboardStory) error {
+	if err := upsertSnapshotRow(tx,
+		`INSERT INTO storyboard_story
+			(id, storyboard_id, goal_id, column_id, name, content, color, points, closed, sort_order, release_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10,
+			(SELECT id FROM storyboard_release WHERE id::TEXT = $11 AND storyboard_id = $2))
+		ON CONFLICT (id) DO UPDATE SET goal_id = EXCLUDED.goal_id, column_id = EXCLUDED.column_id, name = EXCLUDED.name,
+			content = EXCLUDED.content, color = EXCLUDED.color, points = EXCLUDED.points, closed = EXCLUDED.closed,
+			sort_order = EXCLUDED.sort_order, release_id = EXCLUDED.release_id,
+			deleted_date = NULL, deleted_by = NULL, deleted_sort_order = NULL, updated_date = NOW()
+		WHERE storyboard_story.storyboard_id = EXCLUDED.storyboard_id;`,
+		st.StoryID, StoryboardID, GoalID, ColumnID, st.StoryName, st.StoryContent, st.StoryColor,
+		st.StoryPoints, st.StoryClosed, st.SortOrder, st.ReleaseID,
+	); err != nil {
+		return err
+	}
+
+	for _, clear := range []string{
+		`DELETE FROM story_comment WHERE story_id = $1;`,
+		`DELETE FROM storyboard_story_assignee WHERE story_id = $1;`,
+		`DELETE FROM storyboard_story_label WHERE story_id = $1;`,
+		`DELETE FROM story_criterion WHERE story_id = $1;`,
+		`DELETE FROM story_link WHERE from_story_id = $1 OR to_story_id = $1;`,
+		`DELETE FROM story_external_ref WHERE story_id = $1;`,
+	} {
+		if _, err := tx.Exec(clear, st.StoryID); err != nil {
+			return err
+		}
+	}
+
+	return insertStoryItems(tx, StoryboardID, st)
+}
+
+// RestoreStoryboardSnapshotAsNew creates a new storyboard owned by the user from a snapshot
+func (d *Database) RestoreStoryboardSnapshotAsNew(StoryboardID string, UserID string, SnapshotID string, StoryboardName string) (*Storyboard, error) {
+	snapshot, err := d.GetStoryboardSnapshot(StoryboardID, SnapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	if StoryboardName != "" {
+		snapshot.Storyboard.StoryboardName = StoryboardName
+	}
+
+	return d.ImportStoryboard(UserID, "", snapshot.Storyboard)
+}
+
+// snapshotChangedStoryboards takes an automatic snapshot of each storyboard changed since its last one
+// within the interval, keeping the newest Retention automatic snapshots per storyboard
+func (d *Database) snapshotChangedStoryboards(IntervalHours int, Retention int) int {
+	var storyboardIDs []string
+
+	rows, err := d.db.Query(
+		`SELECT s.id FROM storyboard s
+		WHERE s.deleted_date IS NULL AND s.updated_date > NOW() - make_interval(hours => $1)
+		AND s.updated_date > coalesce(
+			(SELECT MAX(ss.created_date) FROM storyboard_snapshot ss WHERE ss.storyboard_id = s.id AND ss.automatic),
+			'-infinity'
+		);`,
+		IntervalHours,
+	)
+	if err != nil {
+		log.Println(err)
+		return 0
+	}
+	for rows.Next() {
+		var StoryboardID string
+		if err := rows.Scan(&StoryboardID); err == nil {
+			storyboardIDs = append(storyboardIDs, StoryboardID)
+		}
+	}
+	rows.Close()
+
+	var taken int
+	for _, StoryboardID := range storyboardIDs {
+		if _, err := d.saveStoryboardSnapshot(StoryboardID, "", "", true); err != nil {
+			continue
+		}
+		taken++
+
+		if _, err := d.db.Exec(
+			`DELETE FROM storyboard_snapshot WHERE id IN (
+				SELECT id FROM storyboard_snapshot WHERE storyboard_id = $1 AND automatic
+				ORDER BY created_date DESC OFFSET $2
+			);`,
+			StoryboardID, Retention,
+		); err != nil {
+			log.Println(err)
+		}
+	}
+
+	return taken
+}
+
+// snapshotLockID is the postgres advisory lock key held while taking automatic snapshots,
+// so only one instance takes them at a time
+const snapshotLockID = 7_201_312
+
+// TakeAutomaticSnapshots takes the automatic snapshots unless another instance already is
+func (d *Database) TakeAutomaticSnapshots(IntervalHours int, Retention int) int {
+	var locked bool
+	ctx := context.Background()
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		log.Println(err)
+		return 0
+	}
+	defer conn.Close()
+
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1);`, snapshotLockID).Scan(&locked); err != nil || !locked {
+		return 0
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1);`, snapshotLockID)
+
+	return d.snapshotChangedStoryboards(IntervalHours, Retention)
+}
@@ -0,0 +1,50 @@
+package database
+
+import "testing"
+
+func TestDiffStoryboards(t *testing.T) {
+	from := &StoryboardExport{Goals: []*StoryboardGoal{
+		{GoalID: "g1", GoalName: "Onboarding", Columns: []*StoryboardColumn{
+			{ColumnID: "c1", Stories: []*StoryboardStory{
+				{StoryID: "s1", StoryPoints: 3},
+				{StoryID: "s2"},
+			}},
+			{ColumnID: "c2", Stories: []*StoryboardStory{
+				{StoryID: "s3"},
+			}},
+		}},
+	}}
+	to := &StoryboardExport{Goals: []*StoryboardGoal{
+		{GoalID: "g1", GoalName: "Onboarding", Columns: []*StoryboardColumn{
+			{ColumnID: "c1", Stories: []*StoryboardStory{
+				{StoryID: "s1", StoryPoints: 5},
+			}},
+			{ColumnID: "c2", Stories: []*StoryboardStory{
+				{StoryID: "s2", StoryClosed: true},
+				{StoryID: "s4"},
+			}},
+		}},
+		{GoalID: "g2", GoalName: "Billing"},
+	}}
+
+	diff := DiffStoryboards(from, to)
+
+	if len(diff.GoalsAdded) != 1 || diff.GoalsAdded[0].GoalID != "g2" {
+		t.Error("Expected goal g2 added")
+	}
+	if len(diff.StoriesAdded) != 1 || diff.StoriesAdded[0].StoryID != "s4" {
+		t.Error("Expected story s4 added")
+	}
+	if len(diff.StoriesRemoved) != 1 || diff.StoriesRemoved[0].StoryID != "s3" {
+		t.Error("Expected story s3 removed")
+	}
+	if len(diff.StoriesMoved) != 1 || diff.StoriesMoved[0].To.ColumnID != "c2" {
+		t.Error("Expected story s2 moved to column c2")
+	}
+	if len(diff.PointsChanged) != 1 || *diff.PointsChanged[0].FromPoints != 3 || *diff.PointsChanged[0].ToPoints != 5 {
+		t.Error("Expected story s1 points changed from 3 to 5")
+	}
+	if len(diff.ClosedToggled) != 1 || !*diff.ClosedToggled[0].Closed {
+		t.Error("Expected story s2 closed")
+	}
+}
@@ -1,21 +1,24 @@
 package database
 
 import (
+	"database/sql"
 	"errors"
 	"log"
+	"strings"
 )
 
 // CreateStoryboardStory adds a new story to a Storyboard
 func (d *Database) CreateStoryboardStory(StoryboardID string, GoalID string, ColumnID string, userID string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call create_storyboard_story($1, $2, $3);`, StoryboardID, GoalID, ColumnID,
-	); err != nil {
-		log.Println(err)
+	if err := d.createOperation(StoryboardID, userID, "add_story", "story", "column_id", ColumnID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call create_storyboard_story($1, $2, $3);`, StoryboardID, GoalID, ColumnID)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -25,17 +28,17 @@ func (d *Database) CreateStoryboardStory(StoryboardID string, GoalID string, Col
 
 // ReviseStoryName updates the story name by ID
 func (d *Database) ReviseStoryName(StoryboardID string, userID string, StoryID string, StoryName string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call update_story_name($1, $2);`,
-		StoryID,
-		StoryName,
-	); err != nil {
-		log.Println(err)
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"name"}}
+	if err := d.changeOperation(StoryboardID, userID, "update_story_name", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call update_story_name($1, $2);`, StoryID, StoryName)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -45,17 +48,17 @@ func (d *Database) ReviseStoryName(StoryboardID string, userID string, StoryID s
 
 // ReviseStoryContent updates the story content by ID
 func (d *Database) ReviseStoryContent(StoryboardID string, userID string, StoryID string, StoryContent string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call update_story_content($1, $2);`,
-		StoryID,
-		StoryContent,
-	); err != nil {
-		log.Println(err)
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"content"}}
+	if err := d.changeOperation(StoryboardID, userID, "update_story_content", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call update_story_content($1, $2);`, StoryID, StoryContent)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -65,17 +68,17 @@ func (d *Database) ReviseStoryContent(StoryboardID string, userID string, StoryI
 
 // ReviseStoryColor updates the story color by ID
 func (d *Database) ReviseStoryColor(StoryboardID string, userID string, StoryID string, StoryColor string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call update_story_color($1, $2);`,
-		StoryID,
-		StoryColor,
-	); err != nil {
-		log.Println(err)
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"color"}}
+	if err := d.changeOperation(StoryboardID, userID, "update_story_color", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call update_story_color($1, $2);`, StoryID, StoryColor)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -85,17 +88,17 @@ func (d *Database) ReviseStoryColor(StoryboardID string, userID string, StoryID
 
 // ReviseStoryPoints updates the story points by ID
 func (d *Database) ReviseStoryPoints(StoryboardID string, userID string, StoryID string, Points int) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call update_story_points($1, $2);`,
-		StoryID,
-		Points,
-	); err != nil {
-		log.Println(err)
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"points"}}
+	if err := d.changeOperation(StoryboardID, userID, "update_story_points", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call update_story_points($1, $2);`, StoryID, Points)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -105,17 +108,86 @@ func (d *Database) ReviseStoryPoints(StoryboardID string, userID string, StoryID
 
 // ReviseStoryClosed updates the story closed status by ID
 func (d *Database) ReviseStoryClosed(StoryboardID string, userID string, StoryID string, Closed bool) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call update_story_closed($1, $2);`,
-		StoryID,
-		Closed,
-	); err != nil {
-		log.Println(err)
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"closed"}}
+	if err := d.changeOperation(StoryboardID, userID, "update_story_closed", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call update_story_closed($1, $2);`, StoryID, Closed)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
+
+// ReviseStory updates the revised fields of a story by ID together, none are changed when any fails
+// (closing first, so a story refused closing with open acceptance criteria is left unchanged)
+func (d *Database) ReviseStory(StoryboardID string, userID string, StoryID string, Revision *StoryRevision) ([]*StoryboardGoal, error) {
+	err := d.ConfirmEditor(StoryboardID, userID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.GetStoryPosition(StoryboardID, StoryID); err != nil {
+		return nil, err
+	}
+
+	change := &OperationState{Kind: "story", ID: StoryID}
+	if Revision.Closed != nil {
+		change.Fields = append(change.Fields, "closed")
+	}
+	if Revision.Name != nil {
+		change.Fields = append(change.Fields, "name")
+	}
+	if Revision.Content != nil {
+		change.Fields = append(change.Fields, "content")
+	}
+	if Revision.Color != nil {
+		change.Fields = append(change.Fields, "color")
+	}
+	if Revision.Points != nil {
+		change.Fields = append(change.Fields, "points")
+	}
+	if len(change.Fields) == 0 {
+		return d.GetStoryboardGoals(StoryboardID), nil
+	}
+
+	if err := d.changeOperation(StoryboardID, userID, "update_story", change, func(tx *sql.Tx) error {
+		if Revision.Closed != nil {
+			if _, err := tx.Exec(`call update_story_closed($1, $2);`, StoryID, *Revision.Closed); err != nil {
+				return err
+			}
+		}
+		if Revision.Name != nil {
+			if _, err := tx.Exec(`call update_story_name($1, $2);`, StoryID, *Revision.Name); err != nil {
+				return err
+			}
+		}
+		if Revision.Content != nil {
+			if _, err := tx.Exec(`call update_story_content($1, $2);`, StoryID, *Revision.Content); err != nil {
+				return err
+			}
+		}
+		if Revision.Color != nil {
+			if _, err := tx.Exec(`call update_story_color($1, $2);`, StoryID, *Revision.Color); err != nil {
+				return err
+			}
+		}
+		if Revision.Points != nil {
+			if _, err := tx.Exec(`call update_story_points($1, $2);`, StoryID, *Revision.Points); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -125,19 +197,17 @@ func (d *Database) ReviseStoryClosed(StoryboardID string, userID string, StoryID
 
 // MoveStoryboardStory moves the story by ID to Goal/Column by ID
 func (d *Database) MoveStoryboardStory(StoryboardID string, userID string, StoryID string, GoalID string, ColumnID string, PlaceBefore string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call move_story($1, $2, $3, $4);`,
-		StoryID,
-		GoalID,
-		ColumnID,
-		PlaceBefore,
-	); err != nil {
-		log.Println(err)
+	change := &OperationState{Kind: "story", ID: StoryID, Fields: []string{"position"}}
+	if err := d.changeOperation(StoryboardID, userID, "move_story", change, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call move_story($1, $2, $3, $4);`, StoryID, GoalID, ColumnID, PlaceBefore)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -145,16 +215,39 @@ func (d *Database) MoveStoryboardStory(StoryboardID string, userID string, Story
 	return goals, nil
 }
 
-// DeleteStoryboardStory removes a story from the current board by ID
+// GetStoryPosition gets the goal, column and sort order of a story by ID
+func (d *Database) GetStoryPosition(StoryboardID string, StoryID string) (*StoryPosition, error) {
+	var p StoryPosition
+
+	e := d.db.QueryRow(
+		`SELECT goal_id, column_id, sort_order FROM storyboard_story WHERE storyboard_id = $1 AND id = $2;`,
+		StoryboardID,
+		StoryID,
+	).Scan(
+		&p.GoalID,
+		&p.ColumnID,
+		&p.SortOrder,
+	)
+	if e != nil {
+		log.Println(e)
+		return nil, errors.New("Story Not found")
+	}
+
+	return &p, nil
+}
+
+// DeleteStoryboardStory moves a story to the storyboards trash by ID
 func (d *Database) DeleteStoryboardStory(StoryboardID string, userID string, StoryID string) ([]*StoryboardGoal, error) {
-	err := d.ConfirmOwner(StoryboardID, userID)
+	err := d.ConfirmEditor(StoryboardID, userID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
 
-	if _, err := d.db.Exec(
-		`call delete_storyboard_story($1);`, StoryID); err != nil {
-		log.Println(err)
+	if err := d.deleteOperation(StoryboardID, userID, "delete_story", "story", StoryID, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`call delete_storyboard_story($1, $2);`, StoryID, userID)
+		return err
+	}); err != nil {
+		return nil, err
 	}
 
 	goals := d.GetStoryboardGoals(StoryboardID)
@@ -162,52 +255,117 @@ func (d *Database) DeleteStoryboardStory(StoryboardID string, userID string, Sto
 	return goals, nil
 }
 
-// AddStoryComment adds a comment to a story
-func (d *Database) AddStoryComment(StoryboardID string, UserID string, StoryID string, Comment string) ([]*StoryboardGoal, error) {
-	if _, err := d.db.Exec(
-		`call story_comment_add($1, $2, $3, $4);`,
+// AddStoryComment adds a comment to a story, or a reply when ParentID is a comment on the story,
+// returning the users it mentions
+func (d *Database) AddStoryComment(StoryboardID string, UserID string, StoryID string, Comment string, ParentID string) ([]*StoryboardGoal, []*StoryMention, error) {
+	if _, err := d.GetStoryboardUserRole(StoryboardID, UserID); err != nil {
+		return nil, nil, errors.New("Incorrect permissions")
+	}
+	if strings.TrimSpace(Comment) == "" {
+		return nil, nil, errors.New("Invalid comment")
+	}
+	if ParentID != "" {
+		if p, err := d.GetStoryComment(StoryboardID, ParentID); err != nil || p.StoryID != StoryID {
+			return nil, nil, errors.New("Parent comment not found")
+		}
+	}
+
+	var CommentID string
+	if err := d.db.QueryRow(
+		`call story_comment_add($1, $2, $3, $4, NULLIF($5, '')::UUID, NULL);`,
 		StoryboardID,
 		StoryID,
 		UserID,
 		Comment,
+		ParentID,
+	).Scan(&CommentID); err != nil {
+		log.Println(err)
+		return nil, nil, err
+	}
+
+	mentions := d.recordMentions(StoryboardID, CommentID)
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, mentions, nil
+}
+
+// GetStoryComment gets a storyboard story comment by ID
+func (d *Database) GetStoryComment(StoryboardID string, CommentID string) (*StoryComment, error) {
+	var c StoryComment
+
+	if err := d.db.QueryRow(
+		`SELECT c.id, c.story_id, coalesce(c.parent_id::TEXT, ''), c.user_id, coalesce(u.name, ''),
+			coalesce(c.comment, ''), c.edited, c.created_date, c.updated_date
+		FROM story_comment c
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE c.id = $1 AND c.storyboard_id = $2;`,
+		CommentID,
+		StoryboardID,
+	).Scan(
+		&c.CommentID, &c.StoryID, &c.ParentID, &c.UserID, &c.UserName,
+		&c.Comment, &c.Edited, &c.CreateDate, &c.UpdatedDate,
+	); err != nil {
+		if err != sql.ErrNoRows {
+			log.Println(err)
+		}
+		return nil, errors.New("Comment not found")
+	}
+
+	return &c, nil
+}
+
+// confirmCommentAuthor confirms the user wrote the comment or owns the storyboard
+func (d *Database) confirmCommentAuthor(StoryboardID string, UserID string, CommentID string) error {
+	c, err := d.GetStoryComment(StoryboardID, CommentID)
+	if err != nil {
+		return err
+	}
+
+	if c.UserID != UserID && d.ConfirmOwner(StoryboardID, UserID) != nil {
+		return errors.New("Incorrect permissions")
+	}
+
+	return nil
+}
+
+// UpdateStoryComment updates a story comment, marking it as edited, returning the users newly mentioned by the edit
+func (d *Database) UpdateStoryComment(StoryboardID string, UserID string, CommentID string, Comment string) ([]*StoryboardGoal, []*StoryMention, error) {
+	if err := d.confirmCommentAuthor(StoryboardID, UserID, CommentID); err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := d.db.Exec(
+		`call story_comment_edit($1, $2, $3);`,
+		StoryboardID,
+		CommentID,
+		Comment,
 	); err != nil {
 		log.Println(err)
+		return nil, nil, err
 	}
 
+	mentions := d.recordMentions(StoryboardID, CommentID)
 	goals := d.GetStoryboardGoals(StoryboardID)
 
-	return goals, nil
+	return goals, mentions, nil
 }
 
-// // UpdateStoryComment updates a story comment
-// func (d *Database) UpdateStoryComment(StoryboardID string, UserID string, commentID string, Comment string) ([]*StoryboardGoal, error) {
-// 	if _, err := d.db.Exec(
-// 		`call story_comment_edit($1, $2, $3, $4);`,
-// 		StoryboardID,
-// 		commentID,
-// 		UserID,
-// 		Comment,
-// 	); err != nil {
-// 		log.Println(err)
-// 	}
-
-// 	goals := d.GetStoryboardGoals(StoryboardID)
-
-// 	return goals, nil
-// }
-
-// // DeleteStoryComment deletes a story comment
-// func (d *Database) DeleteStoryComment(StoryboardID string, UserID string, commentID string) ([]*StoryboardGoal, error) {
-// 	if _, err := d.db.Exec(
-// 		`call story_comment_delete($1, $2, $3, $4);`,
-// 		StoryboardID,
-// 		commentID,
-// 		UserID,
-// 	); err != nil {
-// 		log.Println(err)
-// 	}
-
-// 	goals := d.GetStoryboardGoals(StoryboardID)
-
-// 	return goals, nil
-// }
+// DeleteStoryComment deletes a story comment along with its replies
+func (d *Database) DeleteStoryComment(StoryboardID string, UserID string, CommentID string) ([]*StoryboardGoal, error) {
+	if err := d.confirmCommentAuthor(StoryboardID, UserID, CommentID); err != nil {
+		return nil, err
+	}
+
+	if _, err := d.db.Exec(
+		`call story_comment_delete($1, $2);`,
+		StoryboardID,
+		CommentID,
+	); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	goals := d.GetStoryboardGoals(StoryboardID)
+
+	return goals, nil
+}
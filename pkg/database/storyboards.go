@@ -1,6 +1,9 @@
 package database
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"errors"
 	"log"
@@ -39,19 +42,23 @@ func (d *Database) GetStoryboard(StoryboardID string) (*Storyboard, error) {
 		Goals:          make([]*StoryboardGoal, 0),
 		ColorLegend:    make([]*Color, 0),
 		Personas:       make([]*StoryboardPersona, 0),
+		Releases:       make([]*StoryboardRelease, 0),
+		Labels:         make([]*StoryboardLabel, 0),
 	}
 
 	// get storyboard
 	e := d.db.QueryRow(
 		`SELECT
-			id, name, owner_id, color_legend
-		FROM storyboard WHERE id = $1`,
+			id, name, owner_id, color_legend, revision, require_criteria_done
+		FROM storyboard WHERE id = $1 AND deleted_date IS NULL`,
 		StoryboardID,
 	).Scan(
 		&b.StoryboardID,
 		&b.StoryboardName,
 		&b.OwnerID,
 		&cl,
+		&b.Revision,
+		&b.RequireCriteriaDone,
 	)
 	if e != nil {
 		log.Println(e)
@@ -66,6 +73,8 @@ func (d *Database) GetStoryboard(StoryboardID string) (*Storyboard, error) {
 	b.Users = d.GetStoryboardUsers(StoryboardID)
 	b.Goals = d.GetStoryboardGoals(StoryboardID)
 	b.Personas = d.GetStoryboardPersonas(StoryboardID)
+	b.Releases = d.GetStoryboardReleases(StoryboardID)
+	b.Labels = d.GetStoryboardLabels(StoryboardID)
 
 	return b, nil
 }
@@ -118,6 +127,116 @@ func (d *Database) ConfirmOwner(StoryboardID string, userID string) error {
 	return nil
 }
 
+// GetStoryboardUserRole gets the users role on the storyboard (OWNER, EDITOR or VIEWER)
+func (d *Database) GetStoryboardUserRole(StoryboardID string, userID string) (string, error) {
+	var role string
+	e := d.db.QueryRow("SELECT storyboard_user_role($1, $2)", StoryboardID, userID).Scan(&role)
+	if e != nil {
+		log.Println(e)
+		return "", errors.New("Storyboard Not found")
+	}
+
+	return role, nil
+}
+
+// ConfirmEditor confirms the user is the owner or an editor of the storyboard
+func (d *Database) ConfirmEditor(StoryboardID string, userID string) error {
+	role, err := d.GetStoryboardUserRole(StoryboardID, userID)
+	if err != nil {
+		return err
+	}
+
+	if role != "OWNER" && role != "EDITOR" {
+		return errors.New("Not Editor")
+	}
+
+	return nil
+}
+
+// SetStoryboardUserRole sets a users EDITOR or VIEWER role on the storyboard,
+// an empty role reverts the user to their default role
+func (d *Database) SetStoryboardUserRole(StoryboardID string, userID string, MemberID string, Role string) ([]*StoryboardUser, error) {
+	err := d.ConfirmOwner(StoryboardID, userID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	var role sql.NullString
+	switch Role {
+	case "EDITOR", "VIEWER":
+		role = sql.NullString{String: Role, Valid: true}
+	case "":
+	default:
+		return nil, errors.New("Invalid role")
+	}
+
+	if _, err := d.db.Exec(
+		`call set_storyboard_user_role($1, $2, $3);`, StoryboardID, MemberID, role); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	users := d.GetStoryboardUsers(StoryboardID)
+
+	return users, nil
+}
+
+// storyboardLockClass is the postgres advisory lock key space of storyboard revision locks,
+// the storyboards ID is hashed into the second key
+const storyboardLockClass = 7_201_312
+
+// LockStoryboard takes the storyboards revision lock, held across every instance until the returned unlock func is called
+func (d *Database) LockStoryboard(StoryboardID string) (func(), error) {
+	ctx := context.Background()
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1, hashtext($2));`, storyboardLockClass, StoryboardID); err != nil {
+		log.Println(err)
+		conn.Close()
+		return nil, err
+	}
+
+	return func() {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1, hashtext($2));`, storyboardLockClass, StoryboardID); err != nil {
+			log.Println(err)
+			// the connection is discarded rather than pooled so its session, and the lock, ends
+			conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		conn.Close()
+	}, nil
+}
+
+// GetStoryboardRevision gets the storyboards current revision
+func (d *Database) GetStoryboardRevision(StoryboardID string) (int, error) {
+	var revision int
+	e := d.db.QueryRow("SELECT revision FROM storyboard WHERE id = $1", StoryboardID).Scan(&revision)
+	if e != nil {
+		log.Println(e)
+		return 0, errors.New("Storyboard Not found")
+	}
+
+	return revision, nil
+}
+
+// IncrementStoryboardRevision increments the storyboards revision after a mutation, returning the new revision
+func (d *Database) IncrementStoryboardRevision(StoryboardID string) (int, error) {
+	var revision int
+	e := d.db.QueryRow(
+		"UPDATE storyboard SET revision = revision + 1 WHERE id = $1 RETURNING revision",
+		StoryboardID,
+	).Scan(&revision)
+	if e != nil {
+		log.Println(e)
+		return 0, errors.New("Storyboard Not found")
+	}
+
+	return revision, nil
+}
+
 // GetStoryboardUser gets a user from db by ID and checks storyboard active status
 func (d *Database) GetStoryboardUser(StoryboardID string, UserID string) (*StoryboardUser, error) {
 	var active bool
@@ -137,10 +256,6 @@ func (d *Database) GetStoryboardUser(StoryboardID string, UserID string) (*Story
 		return nil, errors.New("User Not found")
 	}
 
-	if active {
-		return nil, errors.New("User Already Active in Storyboard")
-	}
-
 	return &w, nil
 }
 
@@ -155,7 +270,7 @@ func (d *Database) GetStoryboardUsers(StoryboardID string) []*StoryboardUser {
 		defer rows.Close()
 		for rows.Next() {
 			var w StoryboardUser
-			if err := rows.Scan(&w.UserID, &w.UserName, &w.Active); err != nil {
+			if err := rows.Scan(&w.UserID, &w.UserName, &w.Active, &w.Role, &w.Connections); err != nil {
 				log.Println(err)
 			} else {
 				users = append(users, &w)
@@ -188,32 +303,31 @@ func (d *Database) GetStoryboardPersonas(StoryboardID string) []*StoryboardPerso
 	return personas
 }
 
-// AddUserToStoryboard adds a user by ID to the storyboard by ID
-func (d *Database) AddUserToStoryboard(StoryboardID string, UserID string) ([]*StoryboardUser, error) {
-	if _, err := d.db.Exec(
-		`INSERT INTO storyboard_user (storyboard_id, user_id, active)
-		VALUES ($1, $2, true)
-		ON CONFLICT (storyboard_id, user_id) DO UPDATE SET active = true, abandoned = false`,
+// AddUserToStoryboard adds a session on the instance for the user to the storyboard, returning the session id
+// so each of the users connections can be tracked separately
+func (d *Database) AddUserToStoryboard(StoryboardID string, UserID string, NodeID string) ([]*StoryboardUser, string, error) {
+	var SessionID string
+
+	if err := d.db.QueryRow(
+		`SELECT storyboard_user_session_add($1, $2, $3);`,
 		StoryboardID,
 		UserID,
-	); err != nil {
+		NodeID,
+	).Scan(&SessionID); err != nil {
 		log.Println(err)
+		return nil, "", err
 	}
 
 	users := d.GetStoryboardUsers(StoryboardID)
 
-	return users, nil
+	return users, SessionID, nil
 }
 
-// RetreatUser removes a user from the current storyboard by ID
-func (d *Database) RetreatUser(StoryboardID string, UserID string) []*StoryboardUser {
-	if _, err := d.db.Exec(
-		`UPDATE storyboard_user SET active = false WHERE storyboard_id = $1 AND user_id = $2`, StoryboardID, UserID); err != nil {
-		log.Println(err)
-	}
-
+// RetreatUser removes a users session from the current storyboard,
+// the user remains active until their last session is removed
+func (d *Database) RetreatUser(StoryboardID string, UserID string, SessionID string) []*StoryboardUser {
 	if _, err := d.db.Exec(
-		`UPDATE users SET last_active = NOW() WHERE id = $1`, UserID); err != nil {
+		`call storyboard_user_session_remove($1, $2, $3, false);`, SessionID, StoryboardID, UserID); err != nil {
 		log.Println(err)
 	}
 
@@ -222,16 +336,10 @@ func (d *Database) RetreatUser(StoryboardID string, UserID string) []*Storyboard
 	return users
 }
 
-// AbandonStoryboard removes a user from the current storyboard by ID and sets abandoned true
-func (d *Database) AbandonStoryboard(StoryboardID string, UserID string) ([]*StoryboardUser, error) {
-	if _, err := d.db.Exec(
-		`UPDATE storyboard_user SET active = false, abandoned = true WHERE storyboard_id = $1 AND user_id = $2`, StoryboardID, UserID); err != nil {
-		log.Println(err)
-		return nil, err
-	}
-
+// AbandonStoryboard removes a users session from the current storyboard and sets abandoned true
+func (d *Database) AbandonStoryboard(StoryboardID string, UserID string, SessionID string) ([]*StoryboardUser, error) {
 	if _, err := d.db.Exec(
-		`UPDATE users SET last_active = NOW() WHERE id = $1`, UserID); err != nil {
+		`call storyboard_user_session_remove($1, $2, $3, true);`, SessionID, StoryboardID, UserID); err != nil {
 		log.Println(err)
 		return nil, err
 	}
@@ -251,6 +359,7 @@ func (d *Database) SetStoryboardOwner(StoryboardID string, userID string, OwnerI
 	if _, err := d.db.Exec(
 		`call set_storyboard_owner($1, $2);`, StoryboardID, OwnerID); err != nil {
 		log.Println(err)
+		return nil, err
 	}
 
 	storyboard, err := d.GetStoryboard(StoryboardID)
@@ -263,7 +372,7 @@ func (d *Database) SetStoryboardOwner(StoryboardID string, userID string, OwnerI
 
 // ReviseColorLegend revises the storyboard color legend by StoryboardID
 func (d *Database) ReviseColorLegend(StoryboardID string, UserID string, ColorLegend string) (*Storyboard, error) {
-	err := d.ConfirmOwner(StoryboardID, UserID)
+	err := d.ConfirmEditor(StoryboardID, UserID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
@@ -285,7 +394,26 @@ func (d *Database) ReviseColorLegend(StoryboardID string, UserID string, ColorLe
 	return storyboard, nil
 }
 
-// DeleteStoryboard removes all storyboard associations and the storyboard itself from DB by StoryboardID
+// ReviseCriteriaRule sets whether the storyboards stories require their acceptance criteria done to be closed
+func (d *Database) ReviseCriteriaRule(StoryboardID string, UserID string, RequireDone bool) (*Storyboard, error) {
+	err := d.ConfirmOwner(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.db.Exec(
+		`call revise_criteria_rule($1, $2);`,
+		StoryboardID,
+		RequireDone,
+	); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return d.GetStoryboard(StoryboardID)
+}
+
+// DeleteStoryboard moves the storyboard to its owners trash by StoryboardID
 func (d *Database) DeleteStoryboard(StoryboardID string, userID string) error {
 	err := d.ConfirmOwner(StoryboardID, userID)
 	if err != nil {
@@ -293,7 +421,7 @@ func (d *Database) DeleteStoryboard(StoryboardID string, userID string) error {
 	}
 
 	if _, err := d.db.Exec(
-		`call delete_storyboard($1);`, StoryboardID); err != nil {
+		`call delete_storyboard($1, $2);`, StoryboardID, userID); err != nil {
 		log.Println(err)
 		return err
 	}
@@ -303,7 +431,7 @@ func (d *Database) DeleteStoryboard(StoryboardID string, userID string) error {
 
 // AddPersona adds a persona to a storyboard
 func (d *Database) AddPersona(StoryboardID string, UserID string, Name string, Role string, Description string) ([]*StoryboardPersona, error) {
-	err := d.ConfirmOwner(StoryboardID, UserID)
+	err := d.ConfirmEditor(StoryboardID, UserID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
@@ -316,6 +444,7 @@ func (d *Database) AddPersona(StoryboardID string, UserID string, Name string, R
 		Description,
 	); err != nil {
 		log.Println(err)
+		return nil, err
 	}
 
 	personas := d.GetStoryboardPersonas(StoryboardID)
@@ -325,7 +454,7 @@ func (d *Database) AddPersona(StoryboardID string, UserID string, Name string, R
 
 // UpdatePersona updates a storyboard persona
 func (d *Database) UpdatePersona(StoryboardID string, UserID string, PersonaID string, Name string, Role string, Description string) ([]*StoryboardPersona, error) {
-	err := d.ConfirmOwner(StoryboardID, UserID)
+	err := d.ConfirmEditor(StoryboardID, UserID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
@@ -339,6 +468,7 @@ func (d *Database) UpdatePersona(StoryboardID string, UserID string, PersonaID s
 		Description,
 	); err != nil {
 		log.Println(err)
+		return nil, err
 	}
 
 	personas := d.GetStoryboardPersonas(StoryboardID)
@@ -348,7 +478,7 @@ func (d *Database) UpdatePersona(StoryboardID string, UserID string, PersonaID s
 
 // DeletePersona deletes a storyboard persona
 func (d *Database) DeletePersona(StoryboardID string, UserID string, PersonaID string) ([]*StoryboardPersona, error) {
-	err := d.ConfirmOwner(StoryboardID, UserID)
+	err := d.ConfirmEditor(StoryboardID, UserID)
 	if err != nil {
 		return nil, errors.New("Incorrect permissions")
 	}
@@ -359,6 +489,7 @@ func (d *Database) DeletePersona(StoryboardID string, UserID string, PersonaID s
 		PersonaID,
 	); err != nil {
 		log.Println(err)
+		return nil, err
 	}
 
 	personas := d.GetStoryboardPersonas(StoryboardID)
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+)
+
+// CloneStoryboard copies a storyboards goals, columns, personas, releases and color legend
+// (and optionally its stories) into a new storyboard owned by the user, optionally adding it to a team
+func (d *Database) CloneStoryboard(StoryboardID string, OwnerID string, TeamID string, StoryboardName string, IncludeStories bool) (*Storyboard, error) {
+	export, err := d.ExportStoryboard(StoryboardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if StoryboardName != "" {
+		export.StoryboardName = StoryboardName
+	}
+
+	if !IncludeStories {
+		for _, g := range export.Goals {
+			for _, c := range g.Columns {
+				c.Stories = make([]*StoryboardStory, 0)
+			}
+		}
+	}
+
+	return d.ImportStoryboard(OwnerID, TeamID, export)
+}
+
+// SetStoryboardTemplate marks the storyboard as a template at USER, TEAM or ORGANIZATION scope,
+// an empty scope unmarks it. The owner must be on the team or an admin of the organization.
+func (d *Database) SetStoryboardTemplate(StoryboardID string, UserID string, Scope string, ScopeID string) error {
+	err := d.ConfirmOwner(StoryboardID, UserID)
+	if err != nil {
+		return errors.New("Incorrect permissions")
+	}
+
+	switch Scope {
+	case "", "USER":
+		ScopeID = ""
+	case "TEAM":
+		if role, err := d.TeamUserRole(UserID, ScopeID); err != nil || role == "" {
+			return errors.New("Incorrect permissions")
+		}
+	case "ORGANIZATION":
+		if role, err := d.OrganizationUserRole(UserID, ScopeID); err != nil || role != "ADMIN" {
+			return errors.New("Incorrect permissions")
+		}
+	default:
+		return errors.New("Invalid template scope")
+	}
+
+	if _, err := d.db.Exec(
+		`call set_storyboard_template($1, $2, $3);`,
+		StoryboardID,
+		nullString(Scope),
+		nullString(ScopeID),
+	); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmTemplateAccess confirms the storyboard is a template available to the user
+func (d *Database) ConfirmTemplateAccess(StoryboardID string, UserID string) error {
+	for _, t := range d.GetStoryboardTemplatesByUser(UserID) {
+		if t.StoryboardID == StoryboardID {
+			return nil
+		}
+	}
+
+	return errors.New("Template Not found")
+}
+
+// GetStoryboardTemplatesByUser gets the user, team and organization templates available to the user
+func (d *Database) GetStoryboardTemplatesByUser(UserID string) []*StoryboardTemplate {
+	rows, err := d.db.Query(
+		`SELECT * FROM get_storyboard_templates_by_user($1);`,
+		UserID,
+	)
+
+	return scanStoryboardTemplates(rows, err)
+}
+
+// TeamStoryboardTemplateList gets a list of the teams storyboard templates
+func (d *Database) TeamStoryboardTemplateList(TeamID string) []*StoryboardTemplate {
+	rows, err := d.db.Query(
+		`SELECT * FROM get_storyboard_templates_by_scope('TEAM', $1);`,
+		TeamID,
+	)
+
+	return scanStoryboardTemplates(rows, err)
+}
+
+// OrganizationStoryboardTemplateList gets a list of the organizations storyboard templates
+func (d *Database) OrganizationStoryboardTemplateList(OrgID string) []*StoryboardTemplate {
+	rows, err := d.db.Query(
+		`SELECT * FROM get_storyboard_templates_by_scope('ORGANIZATION', $1);`,
+		OrgID,
+	)
+
+	return scanStoryboardTemplates(rows, err)
+}
+
+// scanStoryboardTemplates reads the rows of a storyboard templates query
+func scanStoryboardTemplates(rows *sql.Rows, err error) []*StoryboardTemplate {
+	var templates = make([]*StoryboardTemplate, 0)
+	if err != nil {
+		log.Println(err)
+		return templates
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		var t StoryboardTemplate
+		var ScopeID sql.NullString
+		if err := rows.Scan(
+			&t.StoryboardID,
+			&t.StoryboardName,
+			&t.OwnerID,
+			&t.Scope,
+			&ScopeID,
+		); err != nil {
+			log.Println(err)
+		} else {
+			t.ScopeID = ScopeID.String
+			templates = append(templates, &t)
+		}
+	}
+
+	return templates
+}
@@ -0,0 +1,172 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+)
+
+// TrashItem A goal, column or story in a storyboards trash, items deleted along with
+// their goal or column are restored with it and aren't listed separately
+type TrashItem struct {
+	ItemID      string `json:"id"`
+	Kind        string `json:"kind"`
+	Name        string `json:"name"`
+	GoalID      string `json:"goal_id"`
+	ColumnID    string `json:"column_id"`
+	DeletedBy   string `json:"deleted_by"`
+	DeletedDate string `json:"deleted_date"`
+}
+
+// DeletedStoryboard A storyboard in its owners trash
+type DeletedStoryboard struct {
+	StoryboardID   string `json:"id"`
+	StoryboardName string `json:"name"`
+	DeletedDate    string `json:"deleted_date"`
+}
+
+// trashKinds are the storyboard items that can be restored from the trash
+var trashKinds = map[string]bool{
+	"goal":   true,
+	"column": true,
+	"story":  true,
+}
+
+// GetStoryboardTrash gets the goals, columns and stories in the storyboards trash, newest first
+func (d *Database) GetStoryboardTrash(StoryboardID string) []*TrashItem {
+	var items = make([]*TrashItem, 0)
+
+	rows, err := d.db.Query(
+		`SELECT * FROM (
+			SELECT sg.id, 'goal' AS kind, coalesce(sg.name, '') AS name, '' AS goal_id, '' AS column_id,
+				coalesce(sg.deleted_by::TEXT, '') AS deleted_by, sg.deleted_date
+			FROM storyboard_goal sg
+			WHERE sg.storyboard_id = $1 AND sg.deleted_date IS NOT NULL
+			UNION ALL
+			SELECT sc.id, 'column', coalesce(sc.name, ''), sc.goal_id::TEXT, '',
+				coalesce(sc.deleted_by::TEXT, ''), sc.deleted_date
+			FROM storyboard_column sc
+			JOIN storyboard_goal sg ON sg.id = sc.goal_id
+			WHERE sc.storyboard_id = $1 AND sc.deleted_date IS NOT NULL
+			AND sg.deleted_date IS DISTINCT FROM sc.deleted_date
+			UNION ALL
+			SELECT ss.id, 'story', coalesce(ss.name, ''), ss.goal_id::TEXT, ss.column_id::TEXT,
+				coalesce(ss.deleted_by::TEXT, ''), ss.deleted_date
+			FROM storyboard_story ss
+			JOIN storyboard_column sc ON sc.id = ss.column_id
+			WHERE ss.storyboard_id = $1 AND ss.deleted_date IS NOT NULL
+			AND sc.deleted_date IS DISTINCT FROM ss.deleted_date
+		) t ORDER BY t.deleted_date DESC;`,
+		StoryboardID,
+	)
+	if err != nil {
+		log.Println(err)
+		return items
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var i TrashItem
+		if err := rows.Scan(
+			&i.ItemID, &i.Kind, &i.Name, &i.GoalID, &i.ColumnID, &i.DeletedBy, &i.DeletedDate,
+		); err != nil {
+			log.Println(err)
+			continue
+		}
+		items = append(items, &i)
+	}
+
+	return items
+}
+
+// RestoreStoryboardTrashItem restores a goal, column or story from the storyboards trash,
+// along with the items deleted with it
+func (d *Database) RestoreStoryboardTrashItem(StoryboardID string, UserID string, Kind string, ItemID string) (*Storyboard, error) {
+	err := d.ConfirmEditor(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if !trashKinds[Kind] {
+		return nil, errors.New("Invalid trash item kind")
+	}
+
+	var found bool
+	if err := d.db.QueryRow(
+		fmt.Sprintf(`SELECT EXISTS (
+			SELECT 1 FROM storyboard_%s WHERE id = $1 AND storyboard_id = $2 AND deleted_date IS NOT NULL
+		);`, Kind),
+		ItemID, StoryboardID,
+	).Scan(&found); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("Trash item not found")
+	}
+
+	if _, err := d.db.Exec(fmt.Sprintf(`call restore_storyboard_%s($1);`, Kind), ItemID); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	storyboard, err := d.GetStoryboard(StoryboardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := recordOperation(d.db, StoryboardID, UserID, "restore_"+Kind,
+		&OperationState{Kind: Kind, ID: ItemID}, itemState(storyboard.Goals, Kind, ItemID)); err != nil {
+		log.Println(err)
+	}
+
+	return storyboard, nil
+}
+
+// GetDeletedStoryboards gets the storyboards in the users trash, newest first
+func (d *Database) GetDeletedStoryboards(UserID string) []*DeletedStoryboard {
+	var storyboards = make([]*DeletedStoryboard, 0)
+
+	rows, err := d.db.Query(`SELECT * FROM get_deleted_storyboards_by_user($1);`, UserID)
+	if err != nil {
+		log.Println(err)
+		return storyboards
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b DeletedStoryboard
+		if err := rows.Scan(&b.StoryboardID, &b.StoryboardName, &b.DeletedDate); err != nil {
+			log.Println(err)
+			continue
+		}
+		storyboards = append(storyboards, &b)
+	}
+
+	return storyboards
+}
+
+// RestoreStoryboard restores a storyboard from its owners trash
+func (d *Database) RestoreStoryboard(StoryboardID string, UserID string) (*Storyboard, error) {
+	err := d.ConfirmOwner(StoryboardID, UserID)
+	if err != nil {
+		return nil, errors.New("Incorrect permissions")
+	}
+
+	if _, err := d.db.Exec(`call restore_storyboard($1);`, StoryboardID); err != nil {
+		log.Println(err)
+		return nil, errors.New("Storyboard not found in trash")
+	}
+
+	return d.GetStoryboard(StoryboardID)
+}
+
+// PurgeDeleted permanently deletes storyboards, goals, columns and stories in the trash for more than DaysOld
+func (d *Database) PurgeDeleted(DaysOld int) error {
+	if _, err := d.db.Exec(`call purge_deleted($1);`, DaysOld); err != nil {
+		log.Println(err)
+		return errors.New("error attempting to purge deleted storyboard items")
+	}
+
+	return nil
+}
@@ -29,9 +29,11 @@ type Color struct {
 
 // StoryboardUser aka user
 type StoryboardUser struct {
-	UserID   string `json:"id"`
-	UserName string `json:"name"`
-	Active   bool   `json:"active"`
+	UserID      string `json:"id"`
+	UserName    string `json:"name"`
+	Active      bool   `json:"active"`
+	Role        string `json:"role"`
+	Connections int    `json:"connections"`
 }
 
 // Storyboard A story mapping board
@@ -39,10 +41,24 @@ type Storyboard struct {
 	StoryboardID   string               `json:"id"`
 	OwnerID        string               `json:"owner_id"`
 	StoryboardName string               `json:"name"`
+	Revision       int                  `json:"revision"`
 	Users          []*StoryboardUser    `json:"users"`
 	Goals          []*StoryboardGoal    `json:"goals"`
 	ColorLegend    []*Color             `json:"color_legend"`
 	Personas       []*StoryboardPersona `json:"personas"`
+	Releases       []*StoryboardRelease `json:"releases"`
+	Labels         []*StoryboardLabel   `json:"labels"`
+	// RequireCriteriaDone refuses closing stories while any of their acceptance criteria are open
+	RequireCriteriaDone bool `json:"require_criteria_done"`
+}
+
+// StoryboardTemplate A storyboard marked as a template at user, team or organization scope
+type StoryboardTemplate struct {
+	StoryboardID   string `json:"id"`
+	StoryboardName string `json:"name"`
+	OwnerID        string `json:"owner_id"`
+	Scope          string `json:"scope"`
+	ScopeID        string `json:"scope_id"`
 }
 
 // StoryboardGoal A row in a story mapping board
@@ -63,22 +79,137 @@ type StoryboardColumn struct {
 
 // StoryboardStory A story in a storyboard goal column
 type StoryboardStory struct {
-	StoryID      string          `json:"id"`
-	StoryName    string          `json:"name"`
-	StoryContent string          `json:"content"`
-	StoryColor   string          `json:"color"`
-	StoryPoints  int             `json:"points"`
-	StoryClosed  bool            `json:"closed"`
-	SortOrder    int             `json:"sort_order"`
-	Comments     []*StoryComment `json:"comments"`
+	StoryID      string             `json:"id"`
+	StoryName    string             `json:"name"`
+	StoryContent string             `json:"content"`
+	StoryColor   string             `json:"color"`
+	StoryPoints  int                `json:"points"`
+	StoryClosed  bool               `json:"closed"`
+	SortOrder    int                `json:"sort_order"`
+	ReleaseID    string             `json:"release_id"`
+	Comments     []*StoryComment    `json:"comments"`
+	Assignees    []*StoryAssignee   `json:"assignees"`
+	Labels       []*StoryboardLabel `json:"labels"`
+	Criteria     []*StoryCriterion  `json:"criteria"`
+	// CriteriaRatio is the fraction of the acceptance criteria done, 0 without any criteria
+	CriteriaRatio float64      `json:"criteria_ratio"`
+	Links         []*StoryLink `json:"links"`
+	// Blocked is whether any story blocking this one is still open
+	Blocked      bool                `json:"blocked"`
+	ExternalRefs []*StoryExternalRef `json:"external_refs"`
+}
+
+// StoryboardRelease A horizontal release slice across the story map
+type StoryboardRelease struct {
+	ReleaseID   string `json:"id"`
+	ReleaseName string `json:"name"`
+	SortOrder   int    `json:"sort_order"`
+	TargetDate  string `json:"target_date"`
+	Points      int    `json:"points"`
+	StoryCount  int    `json:"story_count"`
 }
 
-// StoryComment A story comment by a user
+// StoryRevision The story fields to revise together, nil fields are left unchanged
+type StoryRevision struct {
+	Name    *string `json:"name"`
+	Content *string `json:"content"`
+	Color   *string `json:"color"`
+	Points  *int    `json:"points"`
+	Closed  *bool   `json:"closed"`
+}
+
+// StoryPosition A storys placement within a storyboard
+type StoryPosition struct {
+	GoalID    string `json:"goalId"`
+	ColumnID  string `json:"columnId"`
+	SortOrder int    `json:"sort_order"`
+}
+
+// StoryComment A story comment by a user, replies have the ParentID of the comment they reply to
 type StoryComment struct {
-	StoryID    string `json:"story_id"`
-	UserID     string `json:"user_id"`
-	Comment    string `json:"comment"`
-	CreateDate string `json:"created_date"`
+	CommentID   string `json:"id"`
+	StoryID     string `json:"story_id"`
+	ParentID    string `json:"parent_id"`
+	UserID      string `json:"user_id"`
+	UserName    string `json:"user_name"`
+	Comment     string `json:"comment"`
+	Edited      bool   `json:"edited"`
+	CreateDate  string `json:"created_date"`
+	UpdatedDate string `json:"updated_date"`
+}
+
+// StoryLink A typed link between a story and another story (on any storyboard), outward links are from the story
+// and inward links are to it, e.g. an inward blocks link is a story blocking it
+type StoryLink struct {
+	LinkID       string `json:"id"`
+	Type         string `json:"type"`
+	Direction    string `json:"direction"`
+	StoryID      string `json:"story_id"`
+	StoryName    string `json:"story_name"`
+	StoryboardID string `json:"storyboard_id"`
+	Closed       bool   `json:"closed"`
+}
+
+// StoryExternalRef A reference from a story to an issue in an external tracker such as Jira or GitHub,
+// Status is the issues status last cached from the tracker
+type StoryExternalRef struct {
+	RefID   string `json:"id"`
+	StoryID string `json:"story_id"`
+	System  string `json:"system"`
+	Key     string `json:"key"`
+	URL     string `json:"url"`
+	Status  string `json:"status"`
+}
+
+// ReferencedStory A story referencing an external key, with where it sits on its storyboard
+type ReferencedStory struct {
+	StoryID        string            `json:"id"`
+	StoryName      string            `json:"name"`
+	StoryClosed    bool              `json:"closed"`
+	StoryboardID   string            `json:"storyboard_id"`
+	StoryboardName string            `json:"storyboard_name"`
+	GoalID         string            `json:"goal_id"`
+	GoalName       string            `json:"goal_name"`
+	ColumnID       string            `json:"column_id"`
+	ColumnName     string            `json:"column_name"`
+	ExternalRef    *StoryExternalRef `json:"external_ref"`
+}
+
+// StoryCriterion An acceptance criterion in a storys checklist
+type StoryCriterion struct {
+	CriterionID string `json:"id"`
+	StoryID     string `json:"story_id"`
+	Text        string `json:"text"`
+	Done        bool   `json:"done"`
+	SortOrder   int    `json:"sort_order"`
+}
+
+// StoryboardLabel A label defined on a storyboard that can be added to its stories
+type StoryboardLabel struct {
+	LabelID string `json:"id"`
+	Name    string `json:"name"`
+	Color   string `json:"color"`
+}
+
+// StoryAssignee A user assigned to a story
+type StoryAssignee struct {
+	UserID   string `json:"id"`
+	UserName string `json:"name"`
+}
+
+// AssignedStory An open story assigned to a user, with where it sits on its storyboard
+type AssignedStory struct {
+	StoryID        string             `json:"id"`
+	StoryName      string             `json:"name"`
+	StoryColor     string             `json:"color"`
+	StoryPoints    int                `json:"points"`
+	StoryboardID   string             `json:"storyboard_id"`
+	StoryboardName string             `json:"storyboard_name"`
+	GoalID         string             `json:"goal_id"`
+	GoalName       string             `json:"goal_name"`
+	ColumnID       string             `json:"column_id"`
+	ColumnName     string             `json:"column_name"`
+	Labels         []*StoryboardLabel `json:"labels"`
 }
 
 // StoryboardPersona A storyboards personas
@@ -101,6 +232,8 @@ type User struct {
 	Locale     string `json:"locale"`
 	Company    string `json:"company"`
 	JobTitle   string `json:"jobTitle"`
+	// MentionEmails is whether the user is emailed when mentioned in a story comment they weren't around to see
+	MentionEmails bool `json:"mentionEmails"`
 }
 
 // APIKey structure
@@ -114,6 +114,7 @@ func (d *Database) GetUser(UserID string) (*User, error) {
 		&UserLocale,
 		&UserCompany,
 		&UserJobTitle,
+		&w.MentionEmails,
 	)
 	if e != nil {
 		log.Println(e)
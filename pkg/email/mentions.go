@@ -0,0 +1,53 @@
+package email
+
+import (
+	"log"
+
+	"github.com/matcornic/hermes/v2"
+)
+
+// SendMention Sends a story comment mention email to user, linking to the story on its storyboard
+func (m *Email) SendMention(UserName string, UserEmail string, AuthorName string, StoryboardID string, StoryboardName string, StoryID string, StoryName string, Comment string) error {
+	if StoryName == "" {
+		StoryName = "a story"
+	}
+
+	emailBody, err := m.generateBody(
+		hermes.Body{
+			Name: UserName,
+			Intros: []string{
+				AuthorName + " mentioned you on " + StoryName + " in the " + StoryboardName + " storyboard:",
+				Comment,
+			},
+			Actions: []hermes.Action{
+				{
+					Instructions: "Join the discussion on the story.",
+					Button: hermes.Button{
+						Text: "View Story",
+						Link: m.config.AppURL + "storyboard/" + StoryboardID + "/story/" + StoryID,
+					},
+				},
+			},
+			Outros: []string{
+				"You can turn mention emails off in your Exothermic profile.",
+			},
+		},
+	)
+	if err != nil {
+		log.Println("Error Generating Mention Email HTML: ", err)
+		return err
+	}
+
+	sendErr := m.Send(
+		UserName,
+		UserEmail,
+		AuthorName+" mentioned you in Exothermic",
+		emailBody,
+	)
+	if sendErr != nil {
+		log.Println("Error sending Mention Email: ", sendErr)
+		return sendErr
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// historySize is how many recent events are kept per arena for replay,
+	// kept below the connection send buffer so a replay can't overflow it
+	historySize = 200
+	// historyTTL is how long an arena without connections keeps its history
+	historyTTL = 10 * time.Minute
+)
+
+// sequencedEvent is a broadcast event kept for replay
+type sequencedEvent struct {
+	seq   int64
+	data  []byte
+	delta []byte
+}
+
+// arenaHistory is an arenas last sequence number and its ring buffer of recent events
+type arenaHistory struct {
+	seq     int64
+	events  []sequencedEvent
+	updated time.Time
+}
+
+// resume is a reconnecting clients request to replay the events it missed
+type resume struct {
+	// epoch identifies the hub the client last saw events from, sequences restart with each hub
+	epoch   string
+	lastSeq int64
+}
+
+// registration is the hubs reply to a subscription registering
+type registration struct {
+	// seq is the arenas current sequence number
+	seq int64
+	// replayed is set when the missed events were queued to the connection, no init is needed
+	replayed bool
+}
+
+// stampSeq sets the sequence number of an encoded SocketEvent
+func stampSeq(event []byte, Seq int64) []byte {
+	var e SocketEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return event
+	}
+	e.Seq = Seq
+
+	stamped, _ := json.Marshal(e)
+
+	return stamped
+}
+
+// record sequences a message broadcast to the whole arena and keeps it for replay
+func (h *hub) record(m *message) {
+	hist, ok := h.histories[m.arena]
+	if !ok {
+		hist = &arenaHistory{}
+		h.histories[m.arena] = hist
+	}
+
+	hist.seq++
+	hist.updated = time.Now()
+	m.data = stampSeq(m.data, hist.seq)
+	if m.delta != nil {
+		m.delta = stampSeq(m.delta, hist.seq)
+	}
+
+	if len(hist.events) == historySize {
+		hist.events = append(hist.events[:0:0], hist.events[1:]...)
+	}
+	hist.events = append(hist.events, sequencedEvent{seq: hist.seq, data: m.data, delta: m.delta})
+}
+
+// replay queues the events a resuming subscription missed onto its connection,
+// replayed is false when the gap is older than the arenas history
+func (h *hub) replay(s subscription) registration {
+	hist, ok := h.histories[s.arena]
+	if !ok {
+		return registration{}
+	}
+
+	reg := registration{seq: hist.seq}
+	if s.resume == nil || s.resume.epoch != clusterNodeID || s.resume.lastSeq > hist.seq {
+		return reg
+	}
+	if s.resume.lastSeq < hist.seq && (len(hist.events) == 0 || hist.events[0].seq > s.resume.lastSeq+1) {
+		return reg
+	}
+
+	for _, e := range hist.events {
+		if e.seq <= s.resume.lastSeq {
+			continue
+		}
+		data := e.data
+		if e.delta != nil && s.conn.protocol >= protocolDelta {
+			data = e.delta
+		}
+		s.conn.send <- data
+	}
+	reg.replayed = true
+
+	return reg
+}
+
+// pruneHistories drops the history of arenas that have had no connections or events for a while
+func (h *hub) pruneHistories() {
+	for arena, hist := range h.histories {
+		if _, active := h.arenas[arena]; !active && time.Since(hist.updated) > historyTTL {
+			delete(h.histories, arena)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// errInvalidEventValue is returned for socket events whose value can't be decoded
+var errInvalidEventValue = errors.New("invalid event value")
+
+// socketRequest is an inbound socket event, RequestID and Revision are optional
+type socketRequest struct {
+	Type      string `json:"type"`
+	Value     string `json:"value"`
+	RequestID string `json:"requestId"`
+	Revision  *int   `json:"revision"`
+}
+
+// socketReply is the value of the ack and error events sent back to the originating connection
+type socketReply struct {
+	RequestID string `json:"requestId,omitempty"`
+	Type      string `json:"type"`
+	Code      string `json:"code,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Revision  int    `json:"revision,omitempty"`
+}
+
+// eventErrorCode maps an event error to a machine readable code
+func eventErrorCode(err error) string {
+	if errors.Is(err, errInvalidEventValue) {
+		return "validation"
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return "not_found"
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23503": // foreign_key_violation, the referenced item doesn't exist
+			return "not_found"
+		case "22P02", "23502", "23505", "23514": // invalid_text_representation, not_null, unique, check
+			return "validation"
+		}
+		// raise_exception is a procedures own error, its message says what went wrong
+		if pqErr.Code != "P0001" {
+			return "internal"
+		}
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "permissions"), strings.Contains(msg, "not owner"):
+		return "forbidden"
+	case strings.Contains(msg, "locked"):
+		return "conflict"
+	case strings.Contains(msg, "not found"):
+		return "not_found"
+	case strings.HasPrefix(msg, "invalid"):
+		return "validation"
+	}
+
+	return "internal"
+}
+
+// replyMessage creates a message delivered only to the originating connection
+func replyMessage(StoryboardID string, c *connection, EventType string, reply *socketReply) message {
+	value, _ := json.Marshal(reply)
+	event := &SocketEvent{
+		EventType:  EventType,
+		EventValue: string(value),
+		Revision:   reply.Revision,
+	}
+	data, _ := json.Marshal(event)
+
+	return message{data: data, arena: StoryboardID, conn: c}
+}
+
+// ackReplyMessage creates the ack event for a successfully handled request
+func ackReplyMessage(StoryboardID string, c *connection, req *socketRequest, Revision int) message {
+	return replyMessage(StoryboardID, c, "ack", &socketReply{
+		RequestID: req.RequestID,
+		Type:      req.Type,
+		Revision:  Revision,
+	})
+}
+
+// errorReplyMessage creates the error event for a failed request
+func errorReplyMessage(StoryboardID string, c *connection, req *socketRequest, err error) message {
+	return replyMessage(StoryboardID, c, "error", &socketReply{
+		RequestID: req.RequestID,
+		Type:      req.Type,
+		Code:      eventErrorCode(err),
+		Message:   err.Error(),
+	})
+}
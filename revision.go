@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// revisionedEvents are the socket events that mutate a storyboard,
+// each is checked against and then increments the storyboards revision
+var revisionedEvents = map[string]bool{
+	"add_goal":                  true,
+	"revise_goal":               true,
+	"move_goal":                 true,
+	"delete_goal":               true,
+	"add_column":                true,
+	"revise_column":             true,
+	"move_column":               true,
+	"delete_column":             true,
+	"add_story":                 true,
+	"update_story_name":         true,
+	"update_story_content":      true,
+	"update_story_color":        true,
+	"update_story_points":       true,
+	"update_story_closed":       true,
+	"move_story":                true,
+	"delete_story":              true,
+	"add_story_comment":         true,
+	"update_story_comment":      true,
+	"delete_story_comment":      true,
+	"add_persona":               true,
+	"update_persona":            true,
+	"delete_persona":            true,
+	"add_release":               true,
+	"revise_release":            true,
+	"move_release":              true,
+	"delete_release":            true,
+	"update_story_release":      true,
+	"assign_story":              true,
+	"unassign_story":            true,
+	"add_label":                 true,
+	"revise_label":              true,
+	"delete_label":              true,
+	"add_story_label":           true,
+	"remove_story_label":        true,
+	"add_story_criterion":       true,
+	"update_story_criterion":    true,
+	"toggle_story_criterion":    true,
+	"move_story_criterion":      true,
+	"delete_story_criterion":    true,
+	"revise_criteria_rule":      true,
+	"add_story_link":            true,
+	"remove_story_link":         true,
+	"add_story_external_ref":    true,
+	"remove_story_external_ref": true,
+	"promote_owner":             true,
+	"revise_color_legend":       true,
+	"concede_storyboard":        true,
+	"undo":                      true,
+	"redo":                      true,
+}
+
+// storyboardLocks serializes revision checked mutations per storyboard within this instance,
+// so only one waits on the storyboards database lock at a time
+var storyboardLocks = struct {
+	sync.Mutex
+	boards map[string]*boardLock
+}{boards: make(map[string]*boardLock)}
+
+// boardLock is a storyboards lock with the count of mutations holding or waiting on it,
+// it's removed once none are
+type boardLock struct {
+	sync.Mutex
+	refs int
+}
+
+// lockStoryboard locks the storyboard for a revision checked mutation across every instance, returning the unlock func
+func (s *server) lockStoryboard(StoryboardID string) (func(), error) {
+	storyboardLocks.Lock()
+	l, ok := storyboardLocks.boards[StoryboardID]
+	if !ok {
+		l = &boardLock{}
+		storyboardLocks.boards[StoryboardID] = l
+	}
+	l.refs++
+	storyboardLocks.Unlock()
+
+	release := func() {
+		l.Unlock()
+		storyboardLocks.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(storyboardLocks.boards, StoryboardID)
+		}
+		storyboardLocks.Unlock()
+	}
+
+	l.Lock()
+	unlock, err := s.database.LockStoryboard(StoryboardID)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	return func() {
+		unlock()
+		release()
+	}, nil
+}
+
+// setRevision stamps the storyboard revision onto the messages events
+func (m *message) setRevision(Revision int) {
+	m.data = stampRevision(m.data, Revision)
+	if m.delta != nil {
+		m.delta = stampRevision(m.delta, Revision)
+	}
+}
+
+// stampRevision sets the revision of an encoded SocketEvent
+func stampRevision(event []byte, Revision int) []byte {
+	var e SocketEvent
+	if err := json.Unmarshal(event, &e); err != nil {
+		return event
+	}
+	e.Revision = Revision
+
+	stamped, _ := json.Marshal(e)
+
+	return stamped
+}
+
+// revisionConflictMessage creates the conflict error sent only to the connection with a stale revision
+func revisionConflictMessage(StoryboardID string, c *connection, req *socketRequest, Revision int) message {
+	return replyMessage(StoryboardID, c, "error", &socketReply{
+		RequestID: req.RequestID,
+		Type:      req.Type,
+		Code:      "conflict",
+		Message:   "storyboard revision is stale",
+		Revision:  Revision,
+	})
+}
+
+// lockRevision locks the storyboard and confirms the revision in the requests If-Match header (if any)
+// is current, responding 409 Conflict when stale. The unlock func must be called once the mutation is done.
+func (s *server) lockRevision(w http.ResponseWriter, r *http.Request, StoryboardID string) (func(), bool) {
+	unlock, err := s.lockStoryboard(StoryboardID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+
+	current, err := s.database.GetStoryboardRevision(StoryboardID)
+	if err != nil {
+		unlock()
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	if match := strings.Trim(r.Header.Get("If-Match"), `" `); match != "" {
+		revision, convErr := strconv.Atoi(match)
+		if convErr != nil {
+			unlock()
+			w.WriteHeader(http.StatusBadRequest)
+			return nil, false
+		}
+		if revision != current {
+			unlock()
+			w.Header().Set("ETag", fmt.Sprintf(`"%d"`, current))
+			s.respondWithJSON(w, http.StatusConflict, map[string]int{"revision": current})
+			return nil, false
+		}
+	}
+
+	return unlock, true
+}
+
+// commitRevision increments the storyboards revision after a successful mutation,
+// stamping it onto the message to broadcast and the responses ETag
+func (s *server) commitRevision(w http.ResponseWriter, StoryboardID string, m *message) {
+	revision, err := s.database.IncrementStoryboardRevision(StoryboardID)
+	if err != nil {
+		return
+	}
+
+	m.setRevision(revision)
+	w.Header().Set("ETag", fmt.Sprintf(`"%d"`, revision))
+}
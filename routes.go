@@ -58,12 +58,74 @@ func (s *server) routes() {
 	s.router.HandleFunc("/api/user/{id}/apikey/{keyID}", s.userOnly(s.handleUserAPIKeyDelete())).Methods("DELETE")
 	s.router.HandleFunc("/api/user/{id}/apikey", s.userOnly(s.handleAPIKeyGenerate())).Methods("POST")
 	s.router.HandleFunc("/api/user/{id}/apikeys", s.userOnly(s.handleUserAPIKeys())).Methods("GET")
+	s.router.HandleFunc("/api/user/{id}/stories/assigned", s.userOnly(s.handleUserAssignedStories())).Methods("GET")
+	s.router.HandleFunc("/api/user/{id}/stories/external", s.userOnly(s.handleUserExternalRefStories())).Methods("GET")
 	s.router.HandleFunc("/api/user/{id}", s.userOnly(s.handleUserProfile())).Methods("GET")
 	s.router.HandleFunc("/api/user/{id}", s.userOnly(s.handleUserProfileUpdate())).Methods("POST")
 	s.router.HandleFunc("/api/user/{id}", s.userOnly(s.handleUserDelete())).Methods("DELETE")
 	// storyboard(s)
+	s.router.HandleFunc("/api/storyboard/{id}/goals", s.userOnly(s.handleStoryboardGoalAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/goals/{goalId}/move", s.userOnly(s.handleStoryboardGoalMove())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/goals/{goalId}", s.userOnly(s.handleStoryboardGoalUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/goals/{goalId}", s.userOnly(s.handleStoryboardGoalDelete())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/columns", s.userOnly(s.handleStoryboardColumnAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/columns/{columnId}/move", s.userOnly(s.handleStoryboardColumnMove())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/columns/{columnId}", s.userOnly(s.handleStoryboardColumnUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/columns/{columnId}", s.userOnly(s.handleStoryboardColumnDelete())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/stories", s.userOnly(s.handleStoryboardStoriesGet())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/stories", s.userOnly(s.handleStoryboardStoryAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/move", s.userOnly(s.handleStoryboardStoryMove())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/release", s.userOnly(s.handleStoryboardStoryReleaseUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/assignees", s.userOnly(s.handleStoryboardStoryAssigneeAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/assignees/{userId}", s.userOnly(s.handleStoryboardStoryAssigneeRemove())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/labels", s.userOnly(s.handleStoryboardStoryLabelAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/labels/{labelId}", s.userOnly(s.handleStoryboardStoryLabelRemove())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/criteria", s.userOnly(s.handleStoryboardStoryCriterionAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/criteria/{criterionId}/move", s.userOnly(s.handleStoryboardStoryCriterionMove())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/criteria/{criterionId}", s.userOnly(s.handleStoryboardStoryCriterionUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/criteria/{criterionId}", s.userOnly(s.handleStoryboardStoryCriterionDelete())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/criteria-rule", s.userOnly(s.handleStoryboardCriteriaRuleUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/links", s.userOnly(s.handleStoryboardStoryLinkAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/links/{linkId}", s.userOnly(s.handleStoryboardStoryLinkRemove())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/external-refs", s.userOnly(s.handleStoryboardStoryExternalRefAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/external-refs/{refId}", s.userOnly(s.handleStoryboardStoryExternalRefRemove())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/dependencies", s.userOnly(s.handleStoryboardDependenciesGet())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}/comments", s.userOnly(s.handleStoryboardStoryCommentAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/comments/{commentId}", s.userOnly(s.handleStoryboardStoryCommentUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/comments/{commentId}", s.userOnly(s.handleStoryboardStoryCommentDelete())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}", s.userOnly(s.handleStoryboardStoryUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/stories/{storyId}", s.userOnly(s.handleStoryboardStoryDelete())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/personas", s.userOnly(s.handleStoryboardPersonaAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/personas/{personaId}", s.userOnly(s.handleStoryboardPersonaUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/personas/{personaId}", s.userOnly(s.handleStoryboardPersonaDelete())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/import", s.userOnly(s.handleStoryboardImport())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/clone", s.userOnly(s.handleStoryboardClone())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/template", s.userOnly(s.handleStoryboardTemplateUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/snapshots", s.userOnly(s.handleStoryboardSnapshotsGet())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/snapshots", s.userOnly(s.handleStoryboardSnapshotCreate())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/snapshots/diff", s.userOnly(s.handleStoryboardSnapshotsDiff())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/snapshots/{snapshotId}", s.userOnly(s.handleStoryboardSnapshotGet())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/snapshots/{snapshotId}/restore", s.userOnly(s.handleStoryboardSnapshotRestore())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/trash", s.userOnly(s.handleStoryboardTrashGet())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/trash/{kind}/{itemId}/restore", s.userOnly(s.handleStoryboardTrashRestore())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/restore", s.userOnly(s.handleStoryboardRestore())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/export", s.userOnly(s.handleStoryboardExport())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/releases", s.userOnly(s.handleStoryboardReleasesGet())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/releases", s.userOnly(s.handleStoryboardReleaseAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/releases/{releaseId}/move", s.userOnly(s.handleStoryboardReleaseMove())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/releases/{releaseId}", s.userOnly(s.handleStoryboardReleaseUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/releases/{releaseId}", s.userOnly(s.handleStoryboardReleaseDelete())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/labels", s.userOnly(s.handleStoryboardLabelsGet())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/labels", s.userOnly(s.handleStoryboardLabelAdd())).Methods("POST")
+	s.router.HandleFunc("/api/storyboard/{id}/labels/{labelId}", s.userOnly(s.handleStoryboardLabelUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/labels/{labelId}", s.userOnly(s.handleStoryboardLabelDelete())).Methods("DELETE")
+	s.router.HandleFunc("/api/storyboard/{id}/users", s.userOnly(s.handleStoryboardUsersGet())).Methods("GET")
+	s.router.HandleFunc("/api/storyboard/{id}/users/{userId}/role", s.userOnly(s.handleStoryboardUserRoleUpdate())).Methods("PUT")
+	s.router.HandleFunc("/api/storyboard/{id}/users/{userId}/role", s.userOnly(s.handleStoryboardUserRoleDelete())).Methods("DELETE")
 	s.router.HandleFunc("/api/storyboard/{id}", s.handleStoryboardGet())
 	s.router.HandleFunc("/api/storyboard", s.userOnly(s.handleStoryboardCreate())).Methods("POST")
+	s.router.HandleFunc("/api/storyboards/templates", s.userOnly(s.handleStoryboardTemplatesGet())).Methods("GET")
+	s.router.HandleFunc("/api/storyboards/trash", s.userOnly(s.handleStoryboardsTrashGet())).Methods("GET")
 	s.router.HandleFunc("/api/storyboards", s.userOnly(s.handleStoryboardsGet()))
 	// country(s)
 	if viper.GetBool("config.show_active_countries") {
@@ -80,8 +142,10 @@ func (s *server) routes() {
 	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/users/{limit}/{offset}", s.userOnly(s.departmentUserOnly(s.handleGetDepartmentUsers()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/users", s.userOnly(s.departmentAdminOnly(s.handleDepartmentAddUser()))).Methods("POST")
 	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/user", s.userOnly(s.departmentAdminOnly(s.handleDepartmentRemoveUser()))).Methods("DELETE")
+	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/team/{teamId}/storyboards/templates", s.userOnly(s.departmentTeamUserOnly(s.handleGetTeamStoryboardTemplates()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/team/{teamId}/storyboards/{limit}/{offset}", s.userOnly(s.departmentTeamUserOnly(s.handleGetTeamStoryboards()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/team/{teamId}/storyboard", s.userOnly(s.departmentTeamUserOnly(s.handleStoryboardCreate()))).Methods("POST")
+	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/team/{teamId}/storyboard/import", s.userOnly(s.departmentTeamUserOnly(s.handleStoryboardImport()))).Methods("POST")
 	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/team/{teamId}/storyboard", s.userOnly(s.departmentTeamAdminOnly(s.handleTeamRemoveStoryboard()))).Methods("DELETE")
 	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/team/{teamId}/users/{limit}/{offset}", s.userOnly(s.departmentTeamUserOnly(s.handleGetTeamUsers()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/department/{departmentId}/team/{teamId}/users", s.userOnly(s.departmentTeamAdminOnly(s.handleDepartmentTeamAddUser()))).Methods("POST")
@@ -92,8 +156,10 @@ func (s *server) routes() {
 	// org teams
 	s.router.HandleFunc("/api/organization/{orgId}/teams/{limit}/{offset}", s.userOnly(s.orgUserOnly(s.handleGetOrganizationTeams()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/teams", s.userOnly(s.orgAdminOnly(s.handleCreateOrganizationTeam()))).Methods("POST")
+	s.router.HandleFunc("/api/organization/{orgId}/team/{teamId}/storyboards/templates", s.userOnly(s.orgTeamOnly(s.handleGetTeamStoryboardTemplates()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/team/{teamId}/storyboards/{limit}/{offset}", s.userOnly(s.orgTeamOnly(s.handleGetTeamStoryboards()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/team/{teamId}/storyboard", s.userOnly(s.orgTeamOnly(s.handleStoryboardCreate()))).Methods("POST")
+	s.router.HandleFunc("/api/organization/{orgId}/team/{teamId}/storyboard/import", s.userOnly(s.orgTeamOnly(s.handleStoryboardImport()))).Methods("POST")
 	s.router.HandleFunc("/api/organization/{orgId}/team/{teamId}/storyboard", s.userOnly(s.orgTeamAdminOnly(s.handleTeamRemoveStoryboard()))).Methods("DELETE")
 	s.router.HandleFunc("/api/organization/{orgId}/team/{teamId}/users/{limit}/{offset}", s.userOnly(s.orgTeamOnly(s.handleGetTeamUsers()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/team/{teamId}/users", s.userOnly(s.orgTeamAdminOnly(s.handleOrganizationTeamAddUser()))).Methods("POST")
@@ -101,15 +167,19 @@ func (s *server) routes() {
 	s.router.HandleFunc("/api/organization/{orgId}/team/{teamId}", s.userOnly(s.orgTeamOnly(s.handleGetOrganizationTeamByUser()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/team", s.userOnly(s.orgAdminOnly(s.handleDeleteTeam()))).Methods("DELETE")
 	// org users
+	s.router.HandleFunc("/api/organization/{orgId}/storyboards/templates", s.userOnly(s.orgUserOnly(s.handleGetOrganizationStoryboardTemplates()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/users/{limit}/{offset}", s.userOnly(s.orgUserOnly(s.handleGetOrganizationUsers()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}/users", s.userOnly(s.orgAdminOnly(s.handleOrganizationAddUser()))).Methods("POST")
 	s.router.HandleFunc("/api/organization/{orgId}/user", s.userOnly(s.orgAdminOnly(s.handleOrganizationRemoveUser()))).Methods("DELETE")
+	s.router.HandleFunc("/api/organization/{orgId}/audit/{limit}/{offset}", s.userOnly(s.orgAdminOnly(s.handleGetOrganizationAuditLog()))).Methods("GET")
 	s.router.HandleFunc("/api/organization/{orgId}", s.userOnly(s.orgUserOnly(s.handleGetOrganizationByUser()))).Methods("GET")
 	// teams(s)
 	s.router.HandleFunc("/api/teams/{limit}/{offset}", s.userOnly(s.handleGetTeamsByUser())).Methods("GET")
 	s.router.HandleFunc("/api/teams", s.userOnly(s.handleCreateTeam())).Methods("POST")
+	s.router.HandleFunc("/api/team/{teamId}/storyboards/templates", s.userOnly(s.teamUserOnly(s.handleGetTeamStoryboardTemplates()))).Methods("GET")
 	s.router.HandleFunc("/api/team/{teamId}/storyboards/{limit}/{offset}", s.userOnly(s.teamUserOnly(s.handleGetTeamStoryboards()))).Methods("GET")
 	s.router.HandleFunc("/api/team/{teamId}/storyboard", s.userOnly(s.teamUserOnly(s.handleStoryboardCreate()))).Methods("POST")
+	s.router.HandleFunc("/api/team/{teamId}/storyboard/import", s.userOnly(s.teamUserOnly(s.handleStoryboardImport()))).Methods("POST")
 	s.router.HandleFunc("/api/team/{teamId}/storyboard", s.userOnly(s.teamAdminOnly(s.handleTeamRemoveStoryboard()))).Methods("DELETE")
 	s.router.HandleFunc("/api/team/{teamId}/users/{limit}/{offset}", s.userOnly(s.teamUserOnly(s.handleGetTeamUsers()))).Methods("GET")
 	s.router.HandleFunc("/api/team/{teamId}/users", s.userOnly(s.teamAdminOnly(s.handleTeamAddUser()))).Methods("POST")
@@ -127,6 +197,7 @@ func (s *server) routes() {
 	s.router.HandleFunc("/api/admin/organizations/{limit}/{offset}", s.adminOnly(s.handleGetOrganizations())).Methods("GET")
 	s.router.HandleFunc("/api/admin/teams/{limit}/{offset}", s.adminOnly(s.handleGetTeams())).Methods("GET")
 	s.router.HandleFunc("/api/admin/apikeys/{limit}/{offset}", s.adminOnly(s.handleGetAPIKeys())).Methods("GET")
+	s.router.HandleFunc("/api/admin/audit/{limit}/{offset}", s.adminOnly(s.handleGetAuditLog())).Methods("GET")
 	s.router.HandleFunc("/api/admin/alerts/{limit}/{offset}", s.adminOnly(s.handleGetAlerts())).Methods("GET")
 	s.router.HandleFunc("/api/admin/alert/{id}", s.adminOnly(s.handleAlertUpdate())).Methods("PUT")
 	s.router.HandleFunc("/api/admin/alert", s.adminOnly(s.handleAlertCreate())).Methods("POST")
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// takeAutomaticSnapshots snapshots the storyboards changed since their last automatic snapshot every interval
+func (s *server) takeAutomaticSnapshots(IntervalHours int, Retention int) {
+	ticker := time.NewTicker(time.Duration(IntervalHours) * time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if taken := s.database.TakeAutomaticSnapshots(IntervalHours, Retention); taken > 0 {
+			log.Printf("took %d automatic storyboard snapshots", taken)
+		}
+	}
+}
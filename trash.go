@@ -0,0 +1,19 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// purgeTrash permanently deletes storyboards, goals, columns and stories in the trash
+// for longer than the retention period, checking once an hour
+func (s *server) purgeTrash(RetentionDays int) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.database.PurgeDeleted(RetentionDays); err != nil {
+			log.Println(err)
+		}
+	}
+}